@@ -0,0 +1,215 @@
+// Command backup backs up the whole database and restores it. A backup is
+// a consistent copy that can be made while the server runs, optionally
+// encrypted with a passphrase.
+//
+//	go run ./cmd/backup create -o backup.db.enc -passphrase secret
+//	go run ./cmd/backup restore -file backup.db.enc -passphrase secret -apply
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"bell_scheduler/internal/config"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "create":
+		create(os.Args[2:])
+	case "list":
+		list(os.Args[2:])
+	case "verify":
+		verify(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: backup [command] [flags]")
+	fmt.Println("Commands:")
+	fmt.Println("  create   - Back up the database to the backup directory or a file")
+	fmt.Println("  list     - List the backups in the backup directory")
+	fmt.Println("  verify   - Check that a backup file can be restored")
+	fmt.Println("  restore  - Replace the data with a backup's")
+	fmt.Println("Run backup [command] -h for the flags of a command")
+	os.Exit(1)
+}
+
+// envOrDefault returns the value of an environment variable or a default value
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// dbFlag adds the -db flag, defaulting to DB_CONNECTION like the server
+func dbFlag(flags *flag.FlagSet) *string {
+	return flags.String("db", envOrDefault("DB_CONNECTION", "bell_scheduler.db"), "Path to the database file")
+}
+
+// dirFlag adds the -dir flag, defaulting to BACKUP_DIR like the server
+func dirFlag(flags *flag.FlagSet) *string {
+	return flags.String("dir", envOrDefault("BACKUP_DIR", "backups"), "Backup directory")
+}
+
+// passphraseFlag adds the -passphrase flag, defaulting to BACKUP_PASSPHRASE.
+// The environment variable keeps the passphrase out of the shell history.
+func passphraseFlag(flags *flag.FlagSet) *string {
+	return flags.String("passphrase", os.Getenv("BACKUP_PASSPHRASE"), "Passphrase of encrypted backups (default: BACKUP_PASSPHRASE)")
+}
+
+// newBackups opens the database and creates the backup service
+func newBackups(dbPath, dir, passphrase string) *services.BackupService {
+	db, err := config.OpenDB(dbPath, logger.Silent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	return services.NewBackupService(db, dir, passphrase, store.NewEmergencyRepository(db), nil, services.RealClock{})
+}
+
+func create(args []string) {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	dbPath := dbFlag(flags)
+	dir := dirFlag(flags)
+	passphrase := passphraseFlag(flags)
+	output := flags.String("o", "", "File to write instead of the backup directory")
+	flags.Parse(args)
+
+	backups := newBackups(*dbPath, *dir, *passphrase)
+	if *output == "" {
+		backup, err := backups.Create(services.BackupManual, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create backup: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s (%d bytes)\n", backup.Name, backup.Size)
+		return
+	}
+
+	data, err := backups.Snapshot(*passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create backup: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Backed up to %s (%d bytes)\n", *output, len(data))
+}
+
+func list(args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	dir := dirFlag(flags)
+	flags.Parse(args)
+
+	// Listing only reads the directory
+	backups, err := services.NewBackupService(nil, *dir, "", nil, nil, services.RealClock{}).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list backups: %v\n", err)
+		os.Exit(1)
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups in %s\n", *dir)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tREASON\tSIZE\tENCRYPTED")
+	for _, backup := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%t\n", backup.Name, backup.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			backup.Reason, backup.Size, backup.Encrypted)
+	}
+	w.Flush()
+}
+
+// readBackup reads the backup named by -file, from the backup directory
+// if it is not a path
+func readBackup(file, dir string) []byte {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(filepath.Join(dir, file))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", file, err)
+		os.Exit(1)
+	}
+	return data
+}
+
+func printContents(contents *services.BackupContents) {
+	fmt.Printf("  %d tables, %d users, %d schedules, %d bell log entries\n",
+		contents.Tables, contents.Users, contents.Schedules, contents.LogEntries)
+}
+
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	dir := dirFlag(flags)
+	passphrase := passphraseFlag(flags)
+	file := flags.String("file", "", "Backup file, or the name of a backup in the backup directory")
+	flags.Parse(args)
+
+	data := readBackup(*file, *dir)
+	contents, err := services.NewBackupService(nil, *dir, *passphrase, nil, nil, services.RealClock{}).Inspect(data, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot be restored: %v\n", *file, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s can be restored:\n", *file)
+	printContents(contents)
+}
+
+func restore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbPath := dbFlag(flags)
+	dir := dirFlag(flags)
+	passphrase := passphraseFlag(flags)
+	file := flags.String("file", "", "Backup file, or the name of a backup in the backup directory")
+	apply := flags.Bool("apply", false, "Restore the backup; without it the backup is only checked")
+	flags.Parse(args)
+
+	data := readBackup(*file, *dir)
+	backups := newBackups(*dbPath, *dir, *passphrase)
+	contents, err := backups.Inspect(data, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot be restored: %v\n", *file, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s holds:\n", *file)
+	printContents(contents)
+	if !*apply {
+		fmt.Println("\nDry run. Run again with -apply to replace the data in", *dbPath)
+		return
+	}
+
+	result, err := backups.Restore(data, "")
+	if result != nil {
+		fmt.Printf("\nThe replaced data was backed up to %s\n", result.Previous.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Restored. Everyone has to sign in again. A running server loads the data on SIGHUP or when it restarts.")
+}
@@ -0,0 +1,164 @@
+// Command import_calendar imports the events of an iCalendar file, such as a
+// school district's calendar, as calendar overrides. It shows what would
+// change unless -apply is given.
+//
+//	go run ./cmd/import_calendar -file district.ics \
+//		-map Holiday=no_bells -map "Early release=schedule:Half day" -apply
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"bell_scheduler/internal/config"
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"gorm.io/gorm/logger"
+)
+
+// mappingFlags collects the repeated -map flags
+type mappingFlags []string
+
+func (m *mappingFlags) String() string {
+	return strings.Join(*m, ", ")
+}
+
+func (m *mappingFlags) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+func main() {
+	defaultDB := os.Getenv("DB_CONNECTION")
+	if defaultDB == "" {
+		defaultDB = "bell_scheduler.db"
+	}
+
+	var mappings mappingFlags
+	dbPath := flag.String("db", defaultDB, "Path to the database file")
+	file := flag.String("file", "", "iCalendar file to import")
+	from := flag.String("from", "", "Skip events ending before this date (YYYY-MM-DD)")
+	to := flag.String("to", "", "Skip events starting after this date (YYYY-MM-DD)")
+	timezone := flag.String("timezone", "", "Timezone of event times without one (default: the configured timezone)")
+	apply := flag.Bool("apply", false, "Write the overrides instead of only showing them")
+	flag.Var(&mappings, "map", "Category mapping CATEGORY=ACTION, where ACTION is no_bells, use_default, skip or schedule:NAME|ID (repeatable; category * matches every event)")
+	flag.Parse()
+
+	if *file == "" || len(mappings) == 0 {
+		fmt.Println("Usage: import_calendar -file FILE -map CATEGORY=ACTION [-map ...] [-from DATE] [-to DATE] [-apply]")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Printf("Failed to read calendar: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := config.OpenDB(*dbPath, logger.Silent)
+	if err != nil {
+		fmt.Printf("Failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+
+	calendarRepo := store.NewCalendarRepository(db)
+	scheduleRepo := store.NewScheduleRepository(db)
+	settingsRepo := store.NewSettingsRepository(db)
+
+	if *timezone == "" {
+		if settings, err := settingsRepo.Get(); err == nil {
+			*timezone = settings.Timezone
+		}
+	}
+	location, err := services.LoadTimezone(*timezone)
+	if err != nil {
+		fmt.Printf("Failed to load timezone: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := services.CalendarImportOptions{From: *from, To: *to, Location: location}
+	for _, value := range mappings {
+		mapping, err := parseMapping(value, scheduleRepo)
+		if err != nil {
+			fmt.Printf("Invalid mapping %q: %v\n", value, err)
+			os.Exit(1)
+		}
+		opts.Mappings = append(opts.Mappings, mapping)
+	}
+
+	importer := services.NewCalendarImporter(calendarRepo, scheduleRepo)
+	plan, err := importer.Plan(data, opts)
+	if err != nil {
+		fmt.Printf("Failed to import calendar: %v\n", err)
+		os.Exit(1)
+	}
+
+	printPlan(plan)
+	if !*apply {
+		fmt.Println("\nNothing was changed. Run again with -apply to import.")
+		return
+	}
+
+	if err := importer.Apply(plan); err != nil {
+		fmt.Printf("Failed to import calendar: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nImported. A running server loads the overrides on SIGHUP or when it restarts.")
+}
+
+// parseMapping reads CATEGORY=ACTION, looking up schedules by name or ID
+func parseMapping(value string, scheduleRepo *store.ScheduleRepository) (models.CalendarImportMapping, error) {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return models.CalendarImportMapping{}, fmt.Errorf("expected CATEGORY=ACTION")
+	}
+	mapping := models.CalendarImportMapping{Category: strings.TrimSpace(kv[0]), Action: strings.TrimSpace(kv[1])}
+	if !strings.HasPrefix(mapping.Action, "schedule:") {
+		return mapping, nil
+	}
+
+	name := strings.TrimSpace(strings.TrimPrefix(mapping.Action, "schedule:"))
+	mapping.Action = models.CalendarActionUseSchedule
+	schedules, err := scheduleRepo.GetAll()
+	if err != nil {
+		return mapping, err
+	}
+	for _, schedule := range schedules {
+		if strings.EqualFold(schedule.Name, name) || strconv.FormatInt(schedule.ID, 10) == name {
+			id := schedule.ID
+			mapping.ScheduleID = &id
+			return mapping, nil
+		}
+	}
+	return mapping, fmt.Errorf("schedule %q not found", name)
+}
+
+// printPlan lists what happens to each event
+func printPlan(plan *services.CalendarImportPlan) {
+	if plan.CalendarName != "" {
+		fmt.Printf("Calendar: %s\n\n", plan.CalendarName)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESULT\tDATES\tEVENT\tACTION")
+	for _, item := range plan.Items {
+		dates := item.StartDate
+		if item.EndDate != item.StartDate {
+			dates += " to " + item.EndDate
+		}
+		action := item.Action
+		if item.Reason != "" {
+			action = item.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Result, dates, item.Summary, action)
+	}
+	w.Flush()
+	fmt.Printf("\n%d to create, %d to update, %d unchanged, %d to delete, %d skipped\n",
+		plan.Created, plan.Updated, plan.Unchanged, plan.Deleted, plan.Skipped)
+}
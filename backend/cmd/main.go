@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	_ "time/tzdata" // Embed the tz database so Settings.Timezone works on minimal images
 
 	"bell_scheduler/internal/config"
 	"bell_scheduler/internal/handlers"
@@ -42,6 +43,17 @@ func main() {
 	scheduleRepo := store.NewScheduleRepository(db)
 	settingsRepo := store.NewSettingsRepository(db)
 	logRepo := store.NewLogRepository(db)
+	calendarRepo := store.NewCalendarRepository(db)
+	activationRepo := store.NewActivationRepository(db)
+	feedRepo := store.NewFeedRepository(db)
+	zoneRepo := store.NewZoneRepository(db)
+	patternRepo := store.NewPatternRepository(db)
+	emergencyRepo := store.NewEmergencyRepository(db)
+	roleRepo := store.NewRoleRepository(db)
+	invitationRepo := store.NewInvitationRepository(db)
+	sessionRepo := store.NewSessionRepository(db)
+	auditRepo := store.NewAuditRepository(db)
+	rateLimitRepo := store.NewRateLimitRepository(db)
 
 	// Load settings
 	settings, err := settingsRepo.Get()
@@ -50,18 +62,34 @@ func main() {
 		settings = models.DefaultSettings()
 	}
 
-	// Initialize GPIO service
-	gpioService, err := services.NewGPIOService(settings.GPIOPin, settings.RingDuration)
+	clock := services.RealClock{}
+
+	// Open the output of every bell zone
+	zoneManager := services.NewZoneManager(settings.RingDuration, clock)
+	zoneManager.SetOverlapPolicy(settings.OverlapPolicy)
+	zones, err := zoneRepo.GetAll()
 	if err != nil {
-		log.Fatalf("Failed to initialize GPIO: %v", err)
+		log.Printf("Warning: Failed to load zones: %v", err)
+	}
+	if err := zoneManager.Load(zones); err != nil {
+		log.Printf("Warning: %v, affected zones will not ring", err)
 	}
-	defer gpioService.Close()
+	defer zoneManager.Close()
 
 	// Initialize scheduler service
-	scheduler := services.NewSchedulerService(gpioService, logRepo, scheduleRepo)
+	scheduler := services.NewSchedulerService(zoneManager, logRepo, scheduleRepo, clock)
+	if err := scheduler.SetTimezone(settings.Timezone); err != nil {
+		log.Printf("Warning: %v, using UTC", err)
+	}
+	scheduler.SetCatchUpPolicy(settings.CatchUpPolicy, settings.CatchUpGrace)
 	scheduler.Start()
 	defer scheduler.Stop()
 
+	// Schedules saved before revisions were kept start their history now
+	if err := scheduleRepo.RecordMissingRevisions(); err != nil {
+		log.Printf("Warning: Failed to record schedule revisions: %v", err)
+	}
+
 	// Load active schedules
 	schedules, err := scheduleRepo.GetAll()
 	if err != nil {
@@ -69,6 +97,50 @@ func main() {
 	}
 	scheduler.UpdateSchedules(schedules)
 
+	// Load calendar overrides
+	overrides, err := calendarRepo.GetAll()
+	if err != nil {
+		log.Printf("Warning: Failed to load calendar overrides: %v", err)
+	}
+	scheduler.UpdateCalendarOverrides(overrides)
+
+	// Load the scheduled activations that have not ended
+	activations, err := activationRepo.GetUnfinished(clock.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to load scheduled activations: %v", err)
+	}
+	scheduler.UpdateActivations(activations)
+
+	// Load the ring pattern library
+	patterns, err := patternRepo.GetAll()
+	if err != nil {
+		log.Printf("Warning: Failed to load ring patterns: %v", err)
+	}
+	scheduler.UpdatePatterns(patterns)
+
+	// Resume an emergency that was active before a restart
+	emergencyService := services.NewEmergencyService(emergencyRepo, scheduler, clock)
+	if err := emergencyService.Restore(); err != nil {
+		log.Printf("Warning: Failed to resume emergency: %v", err)
+	}
+
+	// The state loader reloads the scheduler when the database changes
+	// underneath it, after a restore or on SIGHUP
+	stateLoader := services.NewStateLoader(settingsRepo, zoneRepo, scheduleRepo, calendarRepo, activationRepo, patternRepo, scheduler)
+
+	// Back up the database on a schedule if BACKUP_INTERVAL is set
+	backupService := services.NewBackupService(db, cfg.BackupDir, cfg.BackupPassphrase, emergencyRepo, stateLoader, clock)
+	backupService.Start(cfg.BackupInterval, cfg.BackupKeep)
+	defer backupService.Stop()
+
+	sessionService := services.NewSessionService(sessionRepo, userRepo, clock)
+	twoFactorService := services.NewTwoFactorService(userRepo, clock)
+	auditService := services.NewAuditService(auditRepo, clock)
+	loginGuard := services.NewLoginGuard(userRepo, auditService, rateLimitRepo, clock)
+	if err := rateLimitRepo.DeleteOlderThan(clock.Now().Add(-models.LoginUsernameWindow)); err != nil {
+		log.Printf("Warning: Failed to prune login attempts: %v", err)
+	}
+
 	// Initialize email service
 	smtpPort, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
 	emailService := services.NewEmailService(
@@ -77,14 +149,50 @@ func main() {
 		os.Getenv("SMTP_USERNAME"),
 		os.Getenv("SMTP_PASSWORD"),
 		os.Getenv("SMTP_FROM"),
+		cfg.FrontendURL,
 	)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userRepo, emailService, cfg.JWTSecret)
-	userHandler := handlers.NewUserHandler(userRepo)
-	scheduleHandler := handlers.NewScheduleHandler(scheduleRepo, scheduler)
-	settingsHandler := handlers.NewSettingsHandler(settingsRepo, scheduler)
+	authHandler := handlers.NewAuthHandler(userRepo, roleRepo, settingsRepo, invitationRepo, sessionService, twoFactorService, loginGuard, emailService, cfg.JWTSecret)
+	userHandler := handlers.NewUserHandler(userRepo, roleRepo, sessionService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(userRepo, settingsRepo, twoFactorService)
+	lockoutHandler := handlers.NewLockoutHandler(userRepo, loginGuard)
+	roleHandler := handlers.NewRoleHandler(roleRepo)
+	invitationHandler := handlers.NewInvitationHandler(invitationRepo, userRepo, roleRepo, emailService)
+	scheduleTransfer := services.NewScheduleTransfer(scheduleRepo, zoneRepo, patternRepo, clock)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleRepo, zoneRepo, patternRepo, scheduleTransfer, scheduler)
+	settingsHandler := handlers.NewSettingsHandler(settingsRepo, zoneRepo, scheduler)
 	logHandler := handlers.NewLogHandler(logRepo)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	calendarImporter := services.NewCalendarImporter(calendarRepo, scheduleRepo)
+	calendarHandler := handlers.NewCalendarHandler(calendarRepo, scheduleRepo, calendarImporter, scheduler)
+	activationHandler := handlers.NewActivationHandler(activationRepo, scheduleRepo, scheduler)
+	feedHandler := handlers.NewFeedHandler(feedRepo, userRepo, scheduleRepo, zoneRepo, scheduler)
+	zoneHandler := handlers.NewZoneHandler(zoneRepo, settingsRepo, scheduler)
+	patternHandler := handlers.NewPatternHandler(patternRepo, scheduler)
+	emergencyHandler := handlers.NewEmergencyHandler(emergencyService)
+	eventsHandler := handlers.NewEventsHandler(scheduler)
+	backupHandler := handlers.NewBackupHandler(backupService, cfg.BackupMaxUpload)
+	setupHandler, err := handlers.NewSetupHandler(userRepo, rateLimitRepo, cfg.SetupCode)
+	if err != nil {
+		log.Fatalf("Failed to create setup code: %v", err)
+	}
+
+	// Older installations created admin/admin, which anyone could log in
+	// with until its password was changed
+	if disabled, err := setupHandler.DisableDefaultAdmin(); err != nil {
+		log.Printf("Warning: Failed to check the default admin account: %v", err)
+	} else if disabled {
+		log.Printf("Warning: Disabled the default admin account, whose password was never changed")
+	}
+
+	// A new installation has no accounts; the first administrator is
+	// created through the setup page with the code printed here
+	if required, err := setupHandler.Required(); err != nil {
+		log.Printf("Warning: Failed to check setup: %v", err)
+	} else if required {
+		log.Printf("No active administrator exists. Create one at %s/setup with setup code %s", cfg.FrontendURL, setupHandler.Code())
+	}
 
 	// Setup router
 	router := gin.Default()
@@ -92,6 +200,9 @@ func main() {
 	// Add middleware
 	router.Use(middleware.CORS())
 	router.Use(middleware.Logger())
+	// Every change is recorded in the audit log. Logins are recorded by
+	// their handlers, and token refreshes are too frequent to be useful.
+	router.Use(middleware.Audit(auditRepo, "/api/auth/login", "/api/auth/login/2fa", "/api/auth/refresh"))
 
 	// Serve frontend static files
 	router.Static("/js", "../frontend/dist/js")
@@ -112,40 +223,168 @@ func main() {
 	})
 
 	// Public routes
+	router.GET("/api/setup", setupHandler.Status)
+	router.POST("/api/setup", setupHandler.Setup)
 	router.POST("/api/auth/login", authHandler.Login)
+	router.POST("/api/auth/login/2fa", authHandler.LoginTwoFactor)
+	router.POST("/api/auth/refresh", authHandler.Refresh)
+	router.POST("/api/auth/logout", authHandler.Logout)
 	router.POST("/api/auth/register", authHandler.Register)
+	router.GET("/api/auth/registration", authHandler.Registration)
+	router.GET("/api/auth/invitations/:token", authHandler.Invitation)
 	router.POST("/api/auth/forgot-password", authHandler.ForgotPassword)
 	router.POST("/api/auth/reset-password", authHandler.ResetPassword)
 
-	// Protected routes
+	// Calendar feed, authenticated by the feed's own token since calendar
+	// clients cannot log in
+	router.GET(handlers.FeedPath, feedHandler.Feed)
+
+	// Event stream, which also accepts the token as a query parameter since
+	// browsers cannot set headers on an EventSource
+	router.GET("/api/events", middleware.TokenFromQuery(), middleware.Auth(cfg.JWTSecret, sessionService),
+		middleware.PasswordChangeRequired(), middleware.TwoFactorRequired(settingsRepo), eventsHandler.Stream)
+
+	// Protected routes. Reading is open to every authenticated user; changes
+	// need a permission granted by the user's role. Users who must change
+	// their password, or enrol in two-factor authentication, can do nothing
+	// else until they have.
 	protected := router.Group("/api")
-	protected.Use(middleware.Auth(cfg.JWTSecret))
+	protected.Use(
+		middleware.Auth(cfg.JWTSecret, sessionService),
+		middleware.PasswordChangeRequired("/api/auth/change-password"),
+		middleware.TwoFactorRequired(settingsRepo, "/api/auth/change-password",
+			"/api/auth/2fa", "/api/auth/2fa/setup", "/api/auth/2fa/enable"),
+	)
 	{
-		// User routes
-		protected.GET("/users", userHandler.GetUsers)
-		protected.POST("/users", userHandler.CreateUser)
-		protected.PUT("/users/:id", userHandler.UpdateUser)
-		protected.DELETE("/users/:id", userHandler.DeleteUser)
+		require := func(permission string) gin.HandlerFunc {
+			return middleware.RequirePermission(roleRepo, permission)
+		}
+		manageUsers := require(models.PermUsersManage)
+		writeSchedules := require(models.PermSchedulesWrite)
+		triggerBell := require(models.PermBellTrigger)
+		writeSettings := require(models.PermSettingsWrite)
+		readLogs := require(models.PermLogsRead)
+		signalEmergency := require(models.PermEmergency)
+		readAudit := require(models.PermAuditRead)
+		manageBackups := require(models.PermBackupsManage)
+
+		// Account routes
 		protected.POST("/auth/change-password", authHandler.ChangePassword)
+		protected.GET("/auth/permissions", authHandler.Permissions)
+		protected.GET("/auth/sessions", authHandler.Sessions)
+		protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+		protected.POST("/auth/sessions/revoke-all", authHandler.RevokeAllSessions)
+		protected.GET("/auth/2fa", twoFactorHandler.Status)
+		protected.POST("/auth/2fa/setup", twoFactorHandler.Setup)
+		protected.POST("/auth/2fa/enable", twoFactorHandler.Enable)
+		protected.POST("/auth/2fa/disable", twoFactorHandler.Disable)
+		protected.POST("/auth/2fa/recovery-codes", twoFactorHandler.RecoveryCodes)
+
+		// User routes
+		protected.GET("/users", manageUsers, userHandler.GetUsers)
+		protected.POST("/users", manageUsers, userHandler.CreateUser)
+		protected.GET("/users/pending", manageUsers, userHandler.GetPending)
+		protected.POST("/users/unblock-ip", manageUsers, lockoutHandler.UnblockIP)
+		protected.PUT("/users/:id", manageUsers, userHandler.UpdateUser)
+		protected.DELETE("/users/:id", manageUsers, userHandler.DeleteUser)
+		protected.POST("/users/:id/approve", manageUsers, userHandler.ApproveUser)
+		protected.POST("/users/:id/reject", manageUsers, userHandler.RejectUser)
+		protected.POST("/users/:id/revoke-sessions", manageUsers, userHandler.RevokeSessions)
+		protected.POST("/users/:id/reset-2fa", manageUsers, twoFactorHandler.Reset)
+		protected.POST("/users/:id/unlock", manageUsers, lockoutHandler.Unlock)
+
+		// Invitation routes
+		protected.GET("/invitations", manageUsers, invitationHandler.GetAll)
+		protected.POST("/invitations", manageUsers, invitationHandler.Create)
+		protected.DELETE("/invitations/:id", manageUsers, invitationHandler.Delete)
+
+		// Role routes
+		protected.GET("/roles", manageUsers, roleHandler.GetAll)
+		protected.GET("/roles/permissions", manageUsers, roleHandler.Permissions)
+		protected.POST("/roles", manageUsers, roleHandler.Create)
+		protected.GET("/roles/:id", manageUsers, roleHandler.Get)
+		protected.PUT("/roles/:id", manageUsers, roleHandler.Update)
+		protected.DELETE("/roles/:id", manageUsers, roleHandler.Delete)
 
 		// Schedule routes
 		protected.GET("/schedules", scheduleHandler.GetAll)
-		protected.POST("/schedules", scheduleHandler.Create)
+		protected.POST("/schedules", writeSchedules, scheduleHandler.Create)
+		protected.GET("/schedules/export", scheduleHandler.Export)
+		protected.POST("/schedules/import", writeSchedules, scheduleHandler.Import)
 		protected.GET("/schedules/:id", scheduleHandler.Get)
-		protected.PUT("/schedules/:id", scheduleHandler.Update)
-		protected.DELETE("/schedules/:id", scheduleHandler.Delete)
-		protected.POST("/schedules/:id/trigger", scheduleHandler.TriggerNow)
-		protected.PUT("/schedules/:id/default", scheduleHandler.SetDefault)
-		protected.PUT("/schedules/:id/temporary", scheduleHandler.SetTemporary)
-		protected.PUT("/schedules/:id/active", scheduleHandler.SetActive)
+		protected.PUT("/schedules/:id", writeSchedules, scheduleHandler.Update)
+		protected.DELETE("/schedules/:id", writeSchedules, scheduleHandler.Delete)
+		protected.POST("/schedules/:id/trigger", triggerBell, scheduleHandler.TriggerNow)
+		protected.PUT("/schedules/:id/default", writeSchedules, scheduleHandler.SetDefault)
+		protected.PUT("/schedules/:id/temporary", writeSchedules, scheduleHandler.SetTemporary)
+		protected.PUT("/schedules/:id/active", writeSchedules, scheduleHandler.SetActive)
+		protected.GET("/schedules/:id/revisions", scheduleHandler.Revisions)
+		protected.GET("/schedules/:id/revisions/:number", scheduleHandler.Revision)
+		protected.POST("/schedules/:id/revisions/:number/restore", writeSchedules, scheduleHandler.RestoreRevision)
+		protected.POST("/bell/stop", triggerBell, scheduleHandler.StopNow)
+		protected.GET("/schedule/upcoming", scheduleHandler.Upcoming)
+
+		// Calendar routes
+		protected.GET("/calendar", calendarHandler.GetAll)
+		protected.POST("/calendar", writeSchedules, calendarHandler.Create)
+		protected.POST("/calendar/import/preview", writeSchedules, calendarHandler.PreviewImport)
+		protected.POST("/calendar/import", writeSchedules, calendarHandler.Import)
+		protected.GET("/calendar/:id", calendarHandler.Get)
+		protected.PUT("/calendar/:id", writeSchedules, calendarHandler.Update)
+		protected.DELETE("/calendar/:id", writeSchedules, calendarHandler.Delete)
+
+		// Calendar feed routes
+		protected.GET("/calendar/feeds", feedHandler.GetAll)
+		protected.POST("/calendar/feeds", feedHandler.Create)
+		protected.DELETE("/calendar/feeds/:id", feedHandler.Delete)
+
+		// Scheduled activation routes
+		protected.GET("/activations", activationHandler.GetAll)
+		protected.POST("/activations", writeSchedules, activationHandler.Create)
+		protected.GET("/activations/:id", activationHandler.Get)
+		protected.POST("/activations/:id/cancel", writeSchedules, activationHandler.Cancel)
+
+		// Zone routes
+		protected.GET("/zones", zoneHandler.GetAll)
+		protected.POST("/zones", writeSettings, zoneHandler.Create)
+		protected.GET("/zones/:id", zoneHandler.Get)
+		protected.PUT("/zones/:id", writeSettings, zoneHandler.Update)
+		protected.DELETE("/zones/:id", writeSettings, zoneHandler.Delete)
+		protected.POST("/zones/:id/trigger", triggerBell, zoneHandler.Trigger)
+		protected.POST("/zones/:id/stop", triggerBell, zoneHandler.Stop)
+
+		// Ring pattern routes
+		protected.GET("/patterns", patternHandler.GetAll)
+		protected.POST("/patterns", writeSchedules, patternHandler.Create)
+		protected.GET("/patterns/:id", patternHandler.Get)
+		protected.PUT("/patterns/:id", writeSchedules, patternHandler.Update)
+		protected.DELETE("/patterns/:id", writeSchedules, patternHandler.Delete)
+
+		// Emergency routes
+		protected.GET("/emergency", emergencyHandler.Get)
+		protected.POST("/emergency/start", signalEmergency, emergencyHandler.Start)
+		protected.POST("/emergency/stop", signalEmergency, emergencyHandler.Stop)
+		protected.GET("/emergency/log", signalEmergency, emergencyHandler.GetLog)
 
 		// Settings routes
 		protected.GET("/settings", settingsHandler.Get)
-		protected.PUT("/settings", settingsHandler.Update)
+		protected.PUT("/settings", writeSettings, settingsHandler.Update)
 
 		// Log routes
-		protected.GET("/logs", logHandler.GetAll)
-		protected.GET("/logs/range", logHandler.GetByDateRange)
+		protected.GET("/logs", readLogs, logHandler.GetAll)
+		protected.GET("/logs/range", readLogs, logHandler.GetByDateRange)
+
+		// Audit routes
+		protected.GET("/audit", readAudit, auditHandler.GetAll)
+		protected.GET("/audit/export", readAudit, auditHandler.Export)
+
+		// Backup routes
+		protected.GET("/backups", manageBackups, backupHandler.GetAll)
+		protected.POST("/backups", manageBackups, backupHandler.Create)
+		protected.POST("/backups/restore", manageBackups, backupHandler.Upload)
+		protected.GET("/backups/:name", manageBackups, backupHandler.Download)
+		protected.DELETE("/backups/:name", manageBackups, backupHandler.Delete)
+		protected.POST("/backups/:name/restore", manageBackups, backupHandler.Restore)
 	}
 
 	// Handle graceful shutdown
@@ -155,11 +394,26 @@ func main() {
 	go func() {
 		<-sigChan
 		log.Println("Shutting down gracefully...")
+		backupService.Stop()
 		scheduler.Stop()
-		gpioService.Close()
+		zoneManager.Close()
 		os.Exit(0)
 	}()
 
+	// Reload schedules and settings on SIGHUP, e.g. after a command line
+	// tool imported schedules or restored a backup
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			if err := stateLoader.Reload(); err != nil {
+				log.Printf("Warning: Failed to reload: %v", err)
+				continue
+			}
+			log.Println("Reloaded schedules and settings")
+		}
+	}()
+
 	// Start server
 	if err := router.Run(cfg.Address); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
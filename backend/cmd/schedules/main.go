@@ -0,0 +1,185 @@
+// Command schedules exports schedules to a JSON, YAML or CSV file and
+// imports them again, for moving schedules between installations.
+//
+//	go run ./cmd/schedules export -format yaml -o schedules.yaml
+//	go run ./cmd/schedules import -file schedules.yaml -on-conflict rename -dry-run
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"bell_scheduler/internal/config"
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "import":
+		importFile(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: schedules [command] [flags]")
+	fmt.Println("Commands:")
+	fmt.Println("  export  - Write schedules to a file or standard output")
+	fmt.Println("  import  - Import schedules from a file")
+	fmt.Println("Run schedules [command] -h for the flags of a command")
+	os.Exit(1)
+}
+
+// dbFlag adds the -db flag, defaulting to DB_CONNECTION like the server
+func dbFlag(flags *flag.FlagSet) *string {
+	defaultDB := os.Getenv("DB_CONNECTION")
+	if defaultDB == "" {
+		defaultDB = "bell_scheduler.db"
+	}
+	return flags.String("db", defaultDB, "Path to the database file")
+}
+
+// newTransfer opens the database and creates the transfer service
+func newTransfer(dbPath string) *services.ScheduleTransfer {
+	db, err := config.OpenDB(dbPath, logger.Silent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	return services.NewScheduleTransfer(store.NewScheduleRepository(db), store.NewZoneRepository(db),
+		store.NewPatternRepository(db), services.RealClock{})
+}
+
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := dbFlag(flags)
+	format := flags.String("format", "", "json, yaml or csv (default: from the -o extension, else json)")
+	output := flags.String("o", "", "File to write (default: standard output)")
+	ids := flags.String("ids", "", "Comma-separated IDs of the schedules to export (default: all)")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = services.ScheduleFormatJSON
+		if *output != "" {
+			detected, err := services.ScheduleFormatFromName(*output)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			*format = detected
+		}
+	}
+
+	var scheduleIDs []int64
+	if *ids != "" {
+		for _, part := range strings.Split(*ids, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid schedule ID %q\n", part)
+				os.Exit(1)
+			}
+			scheduleIDs = append(scheduleIDs, id)
+		}
+	}
+
+	data, err := newTransfer(*dbPath).Export(scheduleIDs, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export schedules: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported schedules to %s\n", *output)
+}
+
+func importFile(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dbPath := dbFlag(flags)
+	file := flags.String("file", "", "File to import")
+	format := flags.String("format", "", "json, yaml or csv (default: from the file extension)")
+	onConflict := flags.String("on-conflict", services.ConflictSkip, "What to do when a schedule with the same name exists: skip, overwrite or rename")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be imported")
+	flags.Parse(args)
+
+	if *file == "" {
+		fmt.Println("Usage: schedules import -file FILE [-format FORMAT] [-on-conflict skip|overwrite|rename] [-dry-run]")
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format == "" {
+		detected, err := services.ScheduleFormatFromName(*file)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		*format = detected
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Printf("Failed to read %s: %v\n", *file, err)
+		os.Exit(1)
+	}
+
+	transfer := newTransfer(*dbPath)
+	plan, err := transfer.Plan(data, *format, *onConflict)
+	if err != nil {
+		fmt.Printf("Failed to import schedules: %v\n", err)
+		os.Exit(1)
+	}
+
+	printPlan(plan)
+	if !plan.Valid {
+		fmt.Println("\nNothing was imported. Fix the errors above and try again.")
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Println("\nDry run, nothing was changed.")
+		return
+	}
+
+	if err := transfer.Apply(plan, models.RevisionMeta{AuthorName: "schedules import", Note: "Imported from " + *file}); err != nil {
+		fmt.Printf("Failed to import schedules: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nImported. A running server loads the schedules on SIGHUP or when it restarts.")
+}
+
+// printPlan lists what happens to each schedule
+func printPlan(plan *services.ScheduleImportPlan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESULT\tSCHEDULE\tIMPORTED AS\tTIME SLOTS")
+	for _, item := range plan.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", item.Result, item.Name, item.ImportedAs, item.TimeSlots)
+	}
+	w.Flush()
+	for _, item := range plan.Items {
+		for _, message := range item.Errors {
+			fmt.Printf("  %s: %s\n", item.Name, message)
+		}
+	}
+	fmt.Printf("\n%d to create, %d to overwrite, %d to rename, %d skipped, %d invalid\n",
+		plan.Created, plan.Overwritten, plan.Renamed, plan.Skipped, plan.Invalid)
+}
@@ -52,7 +52,7 @@ func main() {
 	}
 
 	fmt.Println("Creating test schedule...")
-	if err := scheduleRepo.Create(testSchedule); err != nil {
+	if err := scheduleRepo.Create(testSchedule, models.RevisionMeta{}); err != nil {
 		log.Fatalf("Failed to create test schedule: %v", err)
 	}
 	fmt.Printf("Created schedule with ID: %d\n", testSchedule.ID)
@@ -89,7 +89,7 @@ func main() {
 	})
 
 	// Update the schedule
-	if err := scheduleRepo.Update(retrievedSchedule); err != nil {
+	if err := scheduleRepo.Update(retrievedSchedule, models.RevisionMeta{}); err != nil {
 		log.Fatalf("Failed to update schedule: %v", err)
 	}
 
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -17,6 +19,21 @@ type Config struct {
 	SMTPPass    string
 	SMTPFrom    string
 	FrontendURL string
+	// SetupCode authorises creating the first administrator. A random
+	// code is generated when it is empty.
+	SetupCode string
+	// BackupDir holds the backups made through the API and on a schedule
+	BackupDir string
+	// BackupInterval is how often a backup is made automatically, zero
+	// for never. BackupKeep is how many of those are kept.
+	BackupInterval time.Duration
+	BackupKeep     int
+	// BackupPassphrase encrypts the automatic backups, and API backups
+	// made without a passphrase of their own
+	BackupPassphrase string
+	// BackupMaxUpload is the largest backup file, in bytes, that can be
+	// uploaded to restore
+	BackupMaxUpload int64
 }
 
 // Load loads the configuration from environment variables
@@ -37,7 +54,29 @@ func Load() (*Config, error) {
 		SMTPPass:    getEnvOrDefault("SMTP_PASSWORD", ""),
 		SMTPFrom:    getEnvOrDefault("SMTP_FROM", ""),
 		FrontendURL: getEnvOrDefault("FRONTEND_URL", "http://localhost:8080"),
+		SetupCode:   getEnvOrDefault("SETUP_CODE", ""),
+
+		BackupDir:        getEnvOrDefault("BACKUP_DIR", "backups"),
+		BackupPassphrase: getEnvOrDefault("BACKUP_PASSPHRASE", ""),
+	}
+
+	if value := os.Getenv("BACKUP_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("BACKUP_INTERVAL must be a duration such as 24h")
+		}
+		cfg.BackupInterval = interval
+	}
+	keep, err := strconv.Atoi(getEnvOrDefault("BACKUP_KEEP", "7"))
+	if err != nil || keep < 1 {
+		return nil, fmt.Errorf("BACKUP_KEEP must be a positive number")
+	}
+	cfg.BackupKeep = keep
+	maxUpload, err := strconv.ParseInt(getEnvOrDefault("BACKUP_MAX_UPLOAD_MB", "100"), 10, 64)
+	if err != nil || maxUpload < 1 {
+		return nil, fmt.Errorf("BACKUP_MAX_UPLOAD_MB must be a positive number")
 	}
+	cfg.BackupMaxUpload = maxUpload << 20
 
 	// Validate required fields
 	if cfg.JWTSecret == "" {
@@ -5,7 +5,6 @@ import (
 
 	"bell_scheduler/internal/models"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,9 +12,15 @@ import (
 
 // NewDB creates a new database connection
 func NewDB(dbPath string) (*gorm.DB, error) {
+	return OpenDB(dbPath, logger.Info)
+}
+
+// OpenDB creates a new database connection that logs SQL at the given
+// level. Command line tools use logger.Silent to keep their output clean.
+func OpenDB(dbPath string, logLevel logger.LogLevel) (*gorm.DB, error) {
 	// Configure GORM logger
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(logLevel),
 	}
 
 	// Open database connection
@@ -27,45 +32,70 @@ func NewDB(dbPath string) (*gorm.DB, error) {
 	// Auto-migrate the schema
 	err = db.AutoMigrate(
 		&models.User{},
+		&models.Role{},
+		&models.Invitation{},
+		&models.Session{},
+		&models.AuditEntry{},
+		&models.RateLimitEntry{},
 		&models.Schedule{},
 		&models.TimeSlot{},
+		&models.ScheduleRevision{},
+		&models.ScheduleActivation{},
 		&models.Settings{},
 		&models.LogEntry{},
+		&models.CalendarOverride{},
+		&models.CalendarFeed{},
+		&models.Zone{},
+		&models.RingPattern{},
+		&models.EmergencyState{},
+		&models.EmergencyLogEntry{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 
-	// Create default admin user if it doesn't exist
-	var adminUser models.User
-	if err := db.Where("username = ?", "admin").First(&adminUser).Error; err != nil {
-		// Create default admin user
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, fmt.Errorf("failed to hash admin password: %v", err)
+	// Create the built-in roles that are missing
+	for _, role := range models.DefaultRoles() {
+		var existing models.Role
+		if err := db.Where("name = ?", role.Name).First(&existing).Error; err == nil {
+			continue
 		}
-
-		adminUser = models.User{
-			Username:            "admin",
-			Email:               "admin@example.com",
-			Password:            string(hashedPassword),
-			Role:                "admin",
-			ForcePasswordChange: true,
-		}
-
-		if err := db.Create(&adminUser).Error; err != nil {
-			return nil, fmt.Errorf("failed to create admin user: %v", err)
+		if err := db.Create(&role).Error; err != nil {
+			return nil, fmt.Errorf("failed to create %s role: %v", role.Name, err)
 		}
 	}
 
 	// Create default settings if they don't exist
 	var settings models.Settings
 	if err := db.First(&settings).Error; err != nil {
-		defaultSettings := models.DefaultSettings()
-		if err := db.Create(defaultSettings).Error; err != nil {
+		settings = *models.DefaultSettings()
+		if err := db.Create(&settings).Error; err != nil {
 			return nil, fmt.Errorf("failed to create default settings: %v", err)
 		}
 	}
 
+	// Create the default zone from the output settings if there are no zones
+	var zoneCount int64
+	if err := db.Model(&models.Zone{}).Count(&zoneCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count zones: %v", err)
+	}
+	if zoneCount == 0 {
+		if err := db.Create(models.DefaultZone(&settings)).Error; err != nil {
+			return nil, fmt.Errorf("failed to create default zone: %v", err)
+		}
+	}
+
+	// Seed the ring pattern library if it is empty
+	var patternCount int64
+	if err := db.Model(&models.RingPattern{}).Count(&patternCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count ring patterns: %v", err)
+	}
+	if patternCount == 0 {
+		patterns := models.DefaultRingPatterns()
+		if err := db.Create(&patterns).Error; err != nil {
+			return nil, fmt.Errorf("failed to create default ring patterns: %v", err)
+		}
+	}
+
 	return db, nil
 }
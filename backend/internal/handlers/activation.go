@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivationHandler handles HTTP requests for scheduled activations
+type ActivationHandler struct {
+	activationRepo *store.ActivationRepository
+	scheduleRepo   *store.ScheduleRepository
+	scheduler      *services.SchedulerService
+}
+
+// NewActivationHandler creates a new activation handler instance
+func NewActivationHandler(activationRepo *store.ActivationRepository, scheduleRepo *store.ScheduleRepository, scheduler *services.SchedulerService) *ActivationHandler {
+	return &ActivationHandler{
+		activationRepo: activationRepo,
+		scheduleRepo:   scheduleRepo,
+		scheduler:      scheduler,
+	}
+}
+
+// activationResponse is an activation with its current status
+type activationResponse struct {
+	models.ScheduleActivation
+	Status       string `json:"status"`
+	ScheduleName string `json:"scheduleName"`
+}
+
+// GetAll returns the activations ordered by start, optionally filtered by
+// status and schedule
+func (h *ActivationHandler) GetAll(c *gin.Context) {
+	var scheduleID int64
+	if value := c.Query("scheduleId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+			return
+		}
+		scheduleID = id
+	}
+	status := c.Query("status")
+
+	activations, err := h.activationRepo.GetAll(scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activations"})
+		return
+	}
+
+	response := make([]activationResponse, 0, len(activations))
+	for _, activation := range activations {
+		item := h.response(activation)
+		if status == "" || item.Status == status {
+			response = append(response, item)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get returns a specific activation
+func (h *ActivationHandler) Get(c *gin.Context) {
+	activation, ok := h.activation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.response(*activation))
+}
+
+// Create schedules a schedule to run for a window of time
+func (h *ActivationHandler) Create(c *gin.Context) {
+	var req models.ScheduleActivationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleRepo.Get(req.ScheduleID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "schedule not found"})
+		return
+	}
+
+	startsAt, err := h.parseTime(req.StartsAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "startsAt " + err.Error()})
+		return
+	}
+	endsAt, err := h.parseTime(req.EndsAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt " + err.Error()})
+		return
+	}
+	if !endsAt.After(startsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt must be after startsAt"})
+		return
+	}
+	if !endsAt.After(h.scheduler.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt must be in the future"})
+		return
+	}
+
+	activation := &models.ScheduleActivation{
+		ScheduleID:    schedule.ID,
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+		Note:          req.Note,
+		CreatedByID:   c.GetInt64("user_id"),
+		CreatedByName: c.GetString("username"),
+	}
+
+	// Only one activation can be in effect at a time
+	unfinished, err := h.activationRepo.GetUnfinished(h.scheduler.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activations"})
+		return
+	}
+	for i := range unfinished {
+		if activation.Overlaps(&unfinished[i]) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":        "The window overlaps another activation",
+				"activationId": unfinished[i].ID,
+			})
+			return
+		}
+	}
+
+	if err := h.activationRepo.Create(activation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create activation"})
+		return
+	}
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+	recordAudit(c, models.AuditActivationScheduled, "activation", activation.ID, schedule.Name,
+		nil, services.Snapshot(activation))
+
+	c.JSON(http.StatusCreated, h.response(*activation))
+}
+
+// Cancel cancels a pending activation, or ends a running one so the usual
+// schedule resumes straight away
+func (h *ActivationHandler) Cancel(c *gin.Context) {
+	activation, ok := h.activation(c)
+	if !ok {
+		return
+	}
+
+	now := h.scheduler.Now()
+	switch activation.Status(now) {
+	case models.ActivationCancelled:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Activation is already cancelled"})
+		return
+	case models.ActivationCompleted:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Activation has already ended"})
+		return
+	}
+
+	before := services.Snapshot(activation)
+	if err := h.activationRepo.Cancel(activation.ID, now, c.GetString("username")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel activation"})
+		return
+	}
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	activation, err := h.activationRepo.Get(activation.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activation"})
+		return
+	}
+	response := h.response(*activation)
+	recordAudit(c, models.AuditActivationCancelled, "activation", activation.ID, response.ScheduleName,
+		before, services.Snapshot(activation))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// activation loads the activation named by the :id parameter, writing the
+// error response if it cannot
+func (h *ActivationHandler) activation(c *gin.Context) (*models.ScheduleActivation, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activation ID"})
+		return nil, false
+	}
+
+	activation, err := h.activationRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Activation not found"})
+		return nil, false
+	}
+	return activation, true
+}
+
+// response adds the status and schedule name to an activation
+func (h *ActivationHandler) response(activation models.ScheduleActivation) activationResponse {
+	response := activationResponse{
+		ScheduleActivation: activation,
+		Status:             activation.Status(h.scheduler.Now()),
+	}
+	if schedule, err := h.scheduleRepo.Get(activation.ScheduleID); err == nil {
+		response.ScheduleName = schedule.Name
+	}
+	return response
+}
+
+// parseTime reads an RFC 3339 time, or a local YYYY-MM-DDTHH:MM time in the
+// scheduler's timezone
+func (h *ActivationHandler) parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.ParseInLocation(models.ActivationLocalFormat, value, h.scheduler.Location())
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 time or YYYY-MM-DDTHH:MM")
+	}
+	return t.UTC(), nil
+}
+
+// refreshScheduler reloads the unfinished activations into the scheduler
+func (h *ActivationHandler) refreshScheduler() error {
+	activations, err := h.activationRepo.GetUnfinished(h.scheduler.Now())
+	if err != nil {
+		return err
+	}
+	h.scheduler.UpdateActivations(activations)
+	return nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler serves the audit log
+type AuditHandler struct {
+	auditRepo *store.AuditRepository
+}
+
+// NewAuditHandler creates a new audit handler instance
+func NewAuditHandler(auditRepo *store.AuditRepository) *AuditHandler {
+	return &AuditHandler{auditRepo: auditRepo}
+}
+
+// GetAll returns a page of the audit entries matching the query filters,
+// newest first
+func (h *AuditHandler) GetAll(c *gin.Context) {
+	filter, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	entries, total, err := h.auditRepo.Find(filter, (page-1)*limit, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// Export downloads every audit entry matching the query filters as CSV
+func (h *AuditHandler) Export(c *gin.Context) {
+	filter, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, _, err := h.auditRepo.Find(filter, 0, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"timestamp", "action", "actor_id", "actor", "ip_address", "method", "path", "status",
+		"target_type", "target_id", "target_name", "details", "changes"})
+	for _, e := range entries {
+		w.Write([]string{
+			e.Timestamp.UTC().Format(time.RFC3339),
+			csvCell(e.Action),
+			strconv.FormatInt(e.ActorID, 10),
+			csvCell(e.ActorName),
+			csvCell(e.IPAddress),
+			e.Method,
+			csvCell(e.Path),
+			strconv.Itoa(e.Status),
+			csvCell(e.TargetType),
+			strconv.FormatInt(e.TargetID, 10),
+			csvCell(e.TargetName),
+			csvCell(e.Details),
+			csvCell(e.Changes),
+		})
+	}
+	w.Flush()
+}
+
+// csvCell stops spreadsheets from evaluating a value as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+// auditFilter reads the audit filter from the query. Dates are RFC 3339
+// times or whole days, and to includes the whole of its day.
+func auditFilter(c *gin.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Action:     c.Query("action"),
+		Actor:      c.Query("actor"),
+		TargetType: c.Query("targetType"),
+		IPAddress:  c.Query("ip"),
+	}
+	if id := c.Query("targetId"); id != "" {
+		targetID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid targetId")
+		}
+		filter.TargetID = targetID
+	}
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseAuditTime(from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date")
+		}
+		filter.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, day, err := parseAuditTime(to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date")
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = t
+	}
+	return filter, nil
+}
+
+// parseAuditTime parses an RFC 3339 time or a date, reporting which it was
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	return t, true, err
+}
+
+// recordAudit describes the change a request made, with snapshots of the
+// target before and after it. The Audit middleware stores the entry with
+// the actor and request once the handler has finished.
+func recordAudit(c *gin.Context, action, targetType string, targetID int64, targetName string, before, after map[string]interface{}) {
+	c.Set("audit_entry", &models.AuditEntry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		Changes:    services.AuditChanges(before, after),
+	})
+}
@@ -5,27 +5,47 @@ import (
 	"bell_scheduler/internal/services"
 	"bell_scheduler/internal/store"
 	"bell_scheduler/pkg/ratelimiter"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	userRepo     *store.GormUserRepository
-	emailService *services.EmailService
-	jwtSecret    []byte
-	resetLimiter *ratelimiter.RateLimiter
+	userRepo       *store.GormUserRepository
+	roleRepo       *store.RoleRepository
+	settingsRepo   *store.SettingsRepository
+	invitationRepo *store.InvitationRepository
+	sessions       *services.SessionService
+	twoFactor      *services.TwoFactorService
+	loginGuard     *services.LoginGuard
+	emailService   *services.EmailService
+	jwtSecret      []byte
+	resetLimiter   *ratelimiter.RateLimiter
+	codeLimiter    *ratelimiter.RateLimiter
 }
 
-func NewAuthHandler(userRepo *store.GormUserRepository, emailService *services.EmailService, jwtSecret string) *AuthHandler {
+func NewAuthHandler(userRepo *store.GormUserRepository, roleRepo *store.RoleRepository, settingsRepo *store.SettingsRepository,
+	invitationRepo *store.InvitationRepository, sessions *services.SessionService, twoFactor *services.TwoFactorService,
+	loginGuard *services.LoginGuard, emailService *services.EmailService, jwtSecret string) *AuthHandler {
 	return &AuthHandler{
-		userRepo:     userRepo,
-		emailService: emailService,
-		jwtSecret:    []byte(jwtSecret),
-		resetLimiter: ratelimiter.NewRateLimiter(5*time.Minute, 3), // 3 attempts per 5 minutes
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		settingsRepo:   settingsRepo,
+		invitationRepo: invitationRepo,
+		sessions:       sessions,
+		twoFactor:      twoFactor,
+		loginGuard:     loginGuard,
+		emailService:   emailService,
+		jwtSecret:      []byte(jwtSecret),
+		resetLimiter:   ratelimiter.NewRateLimiter(5*time.Minute, 3), // 3 attempts per 5 minutes
+		codeLimiter:    ratelimiter.NewRateLimiter(5*time.Minute, 5), // 5 two-factor codes per 5 minutes
 	}
 }
 
@@ -37,8 +57,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Blocked logins are refused before the password is looked at, so
+	// guessing cannot continue while an account or address is locked out
+	ip := c.ClientIP()
 	user, err := h.userRepo.GetByUsername(req.Username)
+	var account *models.User
+	if err == nil {
+		account = user
+	}
+	if wait := h.loginGuard.Check(account, req.Username, ip); wait > 0 {
+		auditLogin(c, account, req.Username, "too many failed logins")
+		tooManyLogins(c, wait)
+		return
+	}
+
 	if err != nil {
+		if err := h.loginGuard.Failed(nil, req.Username, ip); err != nil {
+			fmt.Printf("Failed to record failed login: %v\n", err)
+		}
+		auditLogin(c, nil, req.Username, "unknown username")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -64,6 +101,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})))
 
 	if !user.IsActive {
+		auditLogin(c, user, req.Username, "account is inactive")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is inactive"})
 		return
 	}
@@ -73,17 +111,225 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	gin.DefaultWriter.Write([]byte(fmt.Sprintf("Password check result: %v\n", passwordMatch)))
 
 	if !passwordMatch {
+		if err := h.loginGuard.Failed(user, req.Username, ip); err != nil {
+			fmt.Printf("Failed to record failed login: %v\n", err)
+		}
+		auditLogin(c, user, req.Username, "wrong password")
+		if wait := h.loginGuard.Check(user, req.Username, ip); wait > 0 {
+			tooManyLogins(c, wait)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
 		return
 	}
 
-	// Generate JWT token
+	if user.PendingApproval {
+		auditLogin(c, user, req.Username, "account is awaiting approval")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is awaiting approval"})
+		return
+	}
+
+	// With two-factor authentication the password only earns a short-lived
+	// challenge, which LoginTwoFactor exchanges for tokens
+	if user.TOTPEnabled {
+		challenge, err := h.twoFactorChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"twoFactorRequired": true,
+			"challengeToken":    challenge,
+			"expiresIn":         int(models.TwoFactorChallengeLifetime.Seconds()),
+		})
+		return
+	}
+
+	h.startSession(c, user)
+}
+
+// LoginTwoFactor completes a login with a code from the authenticator app
+// or a recovery code
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := h.parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login, please start again"})
+		return
+	}
+
+	// Six digits are guessable without a limit
+	key := strconv.FormatInt(userID, 10)
+	if allowed, waitTime := h.codeLimiter.IsAllowed(key); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(waitTime.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts. Please try again later."})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil || !user.IsActive || user.PendingApproval {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is inactive"})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	ip := c.ClientIP()
+	if wait := h.loginGuard.Check(user, user.Username, ip); wait > 0 {
+		auditLogin(c, user, user.Username, "too many failed logins")
+		tooManyLogins(c, wait)
+		return
+	}
+
+	if err := h.twoFactor.Verify(user, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) || errors.Is(err, services.ErrTwoFactorNotEnabled) {
+			if err := h.loginGuard.Failed(user, user.Username, ip); err != nil {
+				fmt.Printf("Failed to record failed login: %v\n", err)
+			}
+			auditLogin(c, user, user.Username, "wrong two-factor code")
+			if wait := h.loginGuard.Check(user, user.Username, ip); wait > 0 {
+				tooManyLogins(c, wait)
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	h.codeLimiter.Clear(key)
+
+	h.startSession(c, user)
+}
+
+// auditLogin describes a login for the audit log. A failed login names the
+// reason; the user is nil when no account has the username.
+func auditLogin(c *gin.Context, user *models.User, username, failure string) {
+	entry := &models.AuditEntry{
+		Action:     models.AuditLogin,
+		TargetType: "user",
+		TargetName: username,
+		Details:    failure,
+	}
+	if user != nil {
+		entry.TargetID = user.ID
+		entry.TargetName = user.Username
+	}
+	if failure != "" {
+		entry.Action = models.AuditLoginFailed
+	} else {
+		entry.ActorID = user.ID
+		entry.ActorName = user.Username
+	}
+	c.Set("audit_entry", entry)
+}
+
+// tooManyLogins refuses a login that is blocked by the login guard
+func tooManyLogins(c *gin.Context, wait time.Duration) {
+	retryAfter := int(wait.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "Too many failed login attempts. Please try again later.",
+		"retryAfter": retryAfter,
+	})
+}
+
+// twoFactorChallenge signs a token proving the password step of a login.
+// It has no session, so Auth does not accept it as an access token.
+func (h *AuthHandler) twoFactorChallenge(user *models.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": "2fa",
+		"exp":     time.Now().Add(models.TwoFactorChallengeLifetime).Unix(),
+	})
+	return token.SignedString(h.jwtSecret)
+}
+
+// parseTwoFactorChallenge returns the user a login challenge was issued to
+func (h *AuthHandler) parseTwoFactorChallenge(challenge string) (int64, error) {
+	token, err := h.ValidateToken(challenge)
+	if err != nil || !token.Valid {
+		return 0, errors.New("invalid challenge")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "2fa" {
+		return 0, errors.New("invalid challenge")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("invalid challenge")
+	}
+	return int64(userID), nil
+}
+
+// startSession creates a session for a user who has logged in
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User) {
+	if err := h.loginGuard.Succeeded(user); err != nil {
+		fmt.Printf("Failed to reset failed logins: %v\n", err)
+	}
+
+	refreshToken, session, err := h.sessions.Create(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	auditLogin(c, user, user.Username, "")
+
+	h.respondWithTokens(c, user, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken, session, user, err := h.sessions.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionInvalid) || errors.Is(err, services.ErrRefreshTokenReused) ||
+			errors.Is(err, services.ErrUserInactive) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	h.respondWithTokens(c, user, session.ID, refreshToken)
+}
+
+// Logout ends the session the refresh token belongs to
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessions.RevokeToken(req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// respondWithTokens writes a login response with a new access token for the
+// session
+func (h *AuthHandler) respondWithTokens(c *gin.Context, user *models.User, sessionID int64, refreshToken string) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":               user.ID,
+		"sid":                   sessionID,
 		"username":              user.Username,
 		"role":                  user.Role,
 		"force_password_change": user.ForcePasswordChange,
-		"exp":                   time.Now().Add(time.Hour * 24).Unix(),
+		"exp":                   time.Now().Add(models.AccessTokenLifetime).Unix(),
 	})
 
 	tokenString, err := token.SignedString(h.jwtSecret)
@@ -92,13 +338,106 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	permissions, err := h.permissionsFor(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	user.Password = ""
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Token: tokenString,
-		User:  *user,
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(models.AccessTokenLifetime.Seconds()),
+		User:         *user,
+		Permissions:  permissions,
+	})
+}
+
+// sessionResponse is a session as listed to its user
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// Sessions lists the signed-in devices of the authenticated user
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	sessions, err := h.sessions.List(c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := c.GetInt64("session_id")
+	response := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = sessionResponse{Session: session, Current: session.ID == current}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession signs the authenticated user out of one device
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.sessions.Revoke(c.GetInt64("user_id"), id); err != nil {
+		if errors.Is(err, services.ErrSessionInvalid) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessions signs the authenticated user out of every device,
+// including the one making the request
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	revoked, err := h.sessions.RevokeAll(c.GetInt64("user_id"), 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// Permissions returns the permissions of the authenticated user's role
+func (h *AuthHandler) Permissions(c *gin.Context) {
+	permissions, err := h.permissionsFor(c.GetString("role"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        c.GetString("role"),
+		"permissions": permissions,
 	})
 }
 
-// Register handles user registration
+// permissionsFor returns the permissions granted by the named role. An
+// unknown role grants nothing.
+func (h *AuthHandler) permissionsFor(roleName string) ([]string, error) {
+	role, err := h.roleRepo.GetByName(roleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role.PermissionList()
+}
+
+// Register handles user registration. Depending on the registration policy
+// it needs an invitation, or creates an account awaiting approval.
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -106,33 +445,114 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Check if username already exists
-	existingUser, _ := h.userRepo.GetByUsername(req.Username)
-	if existingUser != nil {
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	var invitation *models.Invitation
+	switch {
+	case settings.RegistrationPolicy == models.RegistrationDisabled:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is disabled"})
+		return
+	case req.InviteToken != "":
+		invitation, err = h.invitationRepo.GetByToken(req.InviteToken)
+		if err != nil || !invitation.IsUsable(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+		if !strings.EqualFold(invitation.Email, req.Email) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The invitation was sent to a different email address"})
+			return
+		}
+	case settings.RegistrationPolicy != models.RegistrationApproval:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration requires an invitation"})
+		return
+	}
+
+	// Check if username or email already exists
+	if existingUser, _ := h.userRepo.GetByUsername(req.Username); existingUser != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
 		return
 	}
+	if existingUser, _ := h.userRepo.GetByEmail(req.Email); existingUser != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+		return
+	}
 
 	user := &models.User{
 		Username: req.Username,
 		Email:    req.Email,
 		Password: req.Password,
-		Role:     req.Role,
+	}
+	if invitation != nil {
+		user.Role = invitation.Role
+		// Claim the invitation first so it cannot be used twice
+		claimed, err := h.invitationRepo.Accept(invitation.ID, time.Now())
+		if err != nil || !claimed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+	} else {
+		// Self-registered accounts start read-only and cannot log in until
+		// an administrator approves them
+		user.Role = models.RoleViewer
+		user.PendingApproval = true
 	}
 
 	// Note: Password will be hashed in the repository's Create method
 
 	if err := h.userRepo.Create(user); err != nil {
+		if invitation != nil {
+			h.invitationRepo.Release(invitation.ID)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
+	user.Password = ""
+	if user.PendingApproval {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Account created and awaiting approval",
+			"user":    user,
+		})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user":    user,
 	})
 }
 
+// Registration returns the registration policy, so the login page knows
+// whether to offer registration
+func (h *AuthHandler) Registration(c *gin.Context) {
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": settings.RegistrationPolicy})
+}
+
+// Invitation returns the email address and role of a usable invitation
+func (h *AuthHandler) Invitation(c *gin.Context) {
+	invitation, err := h.invitationRepo.GetByToken(c.Param("token"))
+	if err != nil || !invitation.IsUsable(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":     invitation.Email,
+		"role":      invitation.Role,
+		"expiresAt": invitation.ExpiresAt,
+	})
+}
+
 // ForgotPassword handles password reset requests
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req models.ForgotPasswordRequest
@@ -217,6 +637,11 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// Whoever knew the old password is signed out
+	if _, err := h.sessions.RevokeAll(user.ID, 0); err != nil {
+		fmt.Printf("Failed to revoke sessions of user %d: %v\n", user.ID, err)
+	}
+
 	// Clear rate limit for this email after successful password reset
 	h.resetLimiter.Clear(user.Email)
 
@@ -258,7 +683,16 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	// Other devices are signed out; this one stays signed in
+	if _, err := h.sessions.RevokeAll(user.ID, c.GetInt64("session_id")); err != nil {
+		fmt.Printf("Failed to revoke sessions of user %d: %v\n", user.ID, err)
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+		"user":    user,
+	})
 }
 
 // ValidateToken validates the JWT token
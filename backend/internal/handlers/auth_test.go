@@ -1,191 +1,152 @@
 package handlers
 
 import (
-	"bell_scheduler/internal/models"
-	"bell_scheduler/internal/services"
-	"bell_scheduler/internal/store"
 	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"bell_scheduler/internal/config"
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-// MockUserRepository is a mock implementation of UserRepository
-type MockUserRepository struct {
-	mock.Mock
-}
-
-var _ store.UserRepository = (*MockUserRepository)(nil) // Type assertion to ensure interface implementation
-
-func (m *MockUserRepository) GetByUsername(username string) (*models.User, error) {
-	args := m.Called(username)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) GetByID(id int64) (*models.User, error) {
-	args := m.Called(id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
-	args := m.Called(email)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) GetByResetToken(token string) (*models.User, error) {
-	args := m.Called(token)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) Create(user *models.User) error {
-	args := m.Called(user)
-	return args.Error(0)
+// newTestDB opens a fully migrated database, so handlers run against the
+// same repositories as the server
+func newTestDB(t *testing.T) *gorm.DB {
+	gin.SetMode(gin.TestMode)
+	db, err := config.OpenDB(filepath.Join(t.TempDir(), "bell.db"), logger.Silent)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
-	args := m.Called(user)
-	return args.Error(0)
+type authFixture struct {
+	db      *gorm.DB
+	users   *store.GormUserRepository
+	clock   *services.FakeClock
+	handler *AuthHandler
+	user    *models.User
 }
 
-func (m *MockUserRepository) Delete(id int64) error {
-	args := m.Called(id)
-	return args.Error(0)
+func newAuthFixture(t *testing.T) *authFixture {
+	db := newTestDB(t)
+	users := store.NewUserRepository(db)
+	clock := services.NewFakeClock(time.Now())
+	audit := services.NewAuditService(store.NewAuditRepository(db), clock)
+	handler := NewAuthHandler(users, store.NewRoleRepository(db), store.NewSettingsRepository(db),
+		store.NewInvitationRepository(db),
+		services.NewSessionService(store.NewSessionRepository(db), users, clock),
+		services.NewTwoFactorService(users, clock),
+		services.NewLoginGuard(users, audit, store.NewRateLimitRepository(db), clock),
+		services.NewEmailService("localhost", 25, "test", "test", "test@test.com", "http://localhost"),
+		"test_secret")
+
+	user := &models.User{Username: "teacher", Email: "teacher@example.com", Password: "password123", Role: models.RoleUser, IsActive: true}
+	require.NoError(t, users.Create(user))
+	return &authFixture{db: db, users: users, clock: clock, handler: handler, user: user}
 }
 
-func (m *MockUserRepository) GetAll() ([]models.User, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]models.User), args.Error(1)
+// call runs a handler on a JSON request and returns the recorded response
+func call(handler gin.HandlerFunc, method, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return w
 }
 
 func TestLogin(t *testing.T) {
-	gin.SetMode(gin.TestMode)
+	f := newAuthFixture(t)
 
-	mockRepo := &MockUserRepository{}
-	mockEmailService := services.NewEmailService("localhost", 25, "test", "test", "test@test.com")
-
-	// Test successful login
 	t.Run("Successful login", func(t *testing.T) {
-		user := &models.User{
-			BaseModel: models.BaseModel{
-				ID: 1,
-			},
-			Username: "testuser",
-			Password: "hashedpassword",
-		}
-
-		mockRepo.On("GetByUsername", "testuser").Return(user, nil)
-
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-
-		loginData := map[string]string{
-			"username": "testuser",
-			"password": "password",
-		}
-		body, _ := json.Marshal(loginData)
-		c.Request = httptest.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(body))
-
-		handler := NewAuthHandler(mockRepo, mockEmailService, "test_secret")
-		handler.Login(c)
-
+		w := call(f.handler.Login, "POST", "/api/auth/login", gin.H{"username": "teacher", "password": "password123"})
 		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.NotEmpty(t, resp["token"])
 	})
 
-	// Test invalid credentials
 	t.Run("Invalid credentials", func(t *testing.T) {
-		user := &models.User{
-			BaseModel: models.BaseModel{
-				ID: 1,
-			},
-			Username: "testuser",
-			Password: "wrongpassword",
-		}
-
-		mockRepo.On("GetByUsername", "testuser").Return(user, nil)
-
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-
-		loginData := map[string]string{
-			"username": "testuser",
-			"password": "wrongpassword",
-		}
-		body, _ := json.Marshal(loginData)
-		c.Request = httptest.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(body))
-
-		handler := NewAuthHandler(mockRepo, mockEmailService, "test_secret")
-		handler.Login(c)
+		w := call(f.handler.Login, "POST", "/api/auth/login", gin.H{"username": "teacher", "password": "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
 
+		w = call(f.handler.Login, "POST", "/api/auth/login", gin.H{"username": "nobody", "password": "password123"})
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
 
 func TestRegister(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	mockRepo := &MockUserRepository{}
-	mockEmailService := services.NewEmailService("localhost", 25, "test", "test", "test@test.com")
+	f := newAuthFixture(t)
+	body := gin.H{"username": "newuser", "password": "password", "email": "test@test.com"}
 
-	// Test successful registration
-	t.Run("Successful registration", func(t *testing.T) {
-		mockRepo.On("GetByUsername", "newuser").Return(nil, nil)
-		mockRepo.On("GetByEmail", "test@test.com").Return(nil, nil)
-		mockRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
-
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
+	t.Run("Registration disabled", func(t *testing.T) {
+		w := call(f.handler.Register, "POST", "/api/auth/register", body)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
 
-		registerData := map[string]string{
-			"username": "newuser",
-			"password": "password",
-			"email":    "test@test.com",
-		}
-		body, _ := json.Marshal(registerData)
-		c.Request = httptest.NewRequest("POST", "/api/auth/register", bytes.NewBuffer(body))
+	t.Run("Registration awaiting approval", func(t *testing.T) {
+		require.NoError(t, f.db.Model(&models.Settings{}).Where("1 = 1").
+			Update("registration_policy", models.RegistrationApproval).Error)
 
-		handler := NewAuthHandler(mockRepo, mockEmailService, "test_secret")
-		handler.Register(c)
+		w := call(f.handler.Register, "POST", "/api/auth/register", body)
+		assert.Equal(t, http.StatusAccepted, w.Code)
 
-		assert.Equal(t, http.StatusCreated, w.Code)
+		user, err := f.users.GetByUsername("newuser")
+		require.NoError(t, err)
+		assert.True(t, user.PendingApproval)
+		assert.Equal(t, models.RoleViewer, user.Role)
 	})
 }
 
 func TestValidateToken(t *testing.T) {
-	gin.SetMode(gin.TestMode)
+	f := newAuthFixture(t)
 
-	mockRepo := &MockUserRepository{}
-	mockEmailService := services.NewEmailService("localhost", 25, "test", "test", "test@test.com")
+	_, err := f.handler.ValidateToken("invalid_token")
+	assert.Error(t, err)
+}
 
-	// Test invalid token
-	t.Run("Invalid token", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest("GET", "/", nil)
-		c.Request.Header.Set("Authorization", "Bearer invalid_token")
+func TestLoginTwoFactor_WrongCodesLockAccount(t *testing.T) {
+	f := newAuthFixture(t)
+	require.NoError(t, f.users.UpdateTwoFactor(f.user.ID, "JBSWY3DPEHPK3PXP", true, ""))
 
-		handler := NewAuthHandler(mockRepo, mockEmailService, "test_secret")
-		handler.ValidateToken("invalid_token")
+	w := call(f.handler.Login, "POST", "/api/auth/login", gin.H{"username": "teacher", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	challenge := resp["challengeToken"]
+	require.NotEmpty(t, challenge)
 
+	wrongCode := gin.H{"challengeToken": challenge, "code": "not-a-code"}
+	for i := 1; i < models.MaxConsecutiveFailedLogins; i++ {
+		w = call(f.handler.LoginTwoFactor, "POST", "/api/auth/login/2fa", wrongCode)
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
-	})
+	}
+	w = call(f.handler.LoginTwoFactor, "POST", "/api/auth/login/2fa", wrongCode)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	user, err := f.users.GetByID(f.user.ID)
+	require.NoError(t, err)
+	assert.True(t, user.IsLocked(f.clock.Now()))
+
+	// The lockout also holds back the password step
+	w = call(f.handler.Login, "POST", "/api/auth/login", gin.H{"username": "teacher", "password": "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
 }
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BackupHandler handles HTTP requests for backups of all data
+type BackupHandler struct {
+	backups   *services.BackupService
+	maxUpload int64 // Largest request body of an uploaded restore, in bytes
+}
+
+// NewBackupHandler creates a new backup handler instance
+func NewBackupHandler(backups *services.BackupService, maxUpload int64) *BackupHandler {
+	return &BackupHandler{
+		backups:   backups,
+		maxUpload: maxUpload,
+	}
+}
+
+// GetAll returns the stored backups, newest first, and how backups are made
+func (h *BackupHandler) GetAll(c *gin.Context) {
+	backups, err := h.backups.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list backups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+		"status":  h.backups.Status(),
+	})
+}
+
+// Create makes a backup now, encrypted with the request's passphrase or
+// the configured one
+func (h *BackupHandler) Create(c *gin.Context) {
+	req, ok := bindBackupRequest(c)
+	if !ok {
+		return
+	}
+
+	backup, err := h.backups.Create(services.BackupManual, req.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+	recordAudit(c, models.AuditBackupCreated, "backup", 0, backup.Name, nil, services.Snapshot(backup))
+
+	c.JSON(http.StatusCreated, backup)
+}
+
+// Download sends a stored backup as a file
+func (h *BackupHandler) Download(c *gin.Context) {
+	name := c.Param("name")
+	path, err := h.backups.Path(name)
+	if err != nil {
+		backupError(c, err, "Failed to get backup")
+		return
+	}
+	c.FileAttachment(path, name)
+}
+
+// Delete removes a stored backup
+func (h *BackupHandler) Delete(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.backups.Delete(name); err != nil {
+		backupError(c, err, "Failed to delete backup")
+		return
+	}
+	recordAudit(c, models.AuditBackupDeleted, "backup", 0, name, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+}
+
+// Restore restores a stored backup
+func (h *BackupHandler) Restore(c *gin.Context) {
+	req, ok := bindBackupRequest(c)
+	if !ok {
+		return
+	}
+
+	name := c.Param("name")
+	result, err := h.backups.RestoreFile(name, req.Passphrase)
+	h.restored(c, name, result, err)
+}
+
+// Upload restores a backup file sent as the multipart field "file", with
+// the passphrase in the field "passphrase". The request body is limited to
+// the configured size before anything is read.
+func (h *BackupHandler) Upload(c *gin.Context) {
+	limit := h.maxUpload >> 20
+	if c.Request.ContentLength > h.maxUpload {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("The backup file must be at most %d MB", limit)})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUpload)
+
+	header, err := c.FormFile("file")
+	if err != nil && bodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("The backup file must be at most %d MB", limit)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A backup file of at most %d MB is required", limit)})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the backup file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the backup file"})
+		return
+	}
+
+	result, err := h.backups.Restore(data, c.PostForm("passphrase"))
+	h.restored(c, header.Filename, result, err)
+}
+
+// bodyTooLarge reports whether reading a request failed at the limit of
+// http.MaxBytesReader, which only tells by its message in this Go version
+func bodyTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "request body too large")
+}
+
+// restored writes the response of a restore. Every session ends with a
+// restore, so the caller has to sign in again.
+func (h *BackupHandler) restored(c *gin.Context, name string, result *services.BackupRestore, err error) {
+	if err != nil && result != nil {
+		// The data was restored but the scheduler kept its old state
+		recordAudit(c, models.AuditBackupRestored, "backup", 0, name, nil, services.Snapshot(result))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "previous": result.Previous})
+		return
+	}
+	if err != nil {
+		backupError(c, err, "Failed to restore backup")
+		return
+	}
+	recordAudit(c, models.AuditBackupRestored, "backup", 0, name, nil, services.Snapshot(result))
+
+	c.JSON(http.StatusOK, result)
+}
+
+// bindBackupRequest reads the optional JSON body of a backup request,
+// writing the error response if it cannot
+func bindBackupRequest(c *gin.Context) (models.BackupRequest, bool) {
+	var req models.BackupRequest
+	if c.Request.ContentLength == 0 {
+		return req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// backupError writes the response for a failed backup operation
+func backupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrBackupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+	case errors.Is(err, services.ErrInvalidBackup),
+		errors.Is(err, services.ErrPassphraseRequired),
+		errors.Is(err, services.ErrWrongPassphrase):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRestoreDuringEmergency):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
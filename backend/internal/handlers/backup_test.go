@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadRequest builds a restore upload holding size bytes of backup file
+func uploadRequest(t *testing.T, size int) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("file", "bell.db")
+	require.NoError(t, err)
+	_, err = part.Write(make([]byte, size))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	return body, form.FormDataContentType()
+}
+
+func TestBackupUpload_TooLarge(t *testing.T) {
+	db := newTestDB(t)
+	clock := services.NewFakeClock(time.Now())
+	backups := services.NewBackupService(db, t.TempDir(), "", store.NewEmergencyRepository(db), nil, clock)
+	handler := NewBackupHandler(backups, 1<<20)
+
+	t.Run("Declared length", func(t *testing.T) {
+		body, contentType := uploadRequest(t, 2<<20)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/backups/upload", body)
+		c.Request.Header.Set("Content-Type", contentType)
+		handler.Upload(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Streamed body", func(t *testing.T) {
+		body, contentType := uploadRequest(t, 2<<20)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/backups/upload", io.NopCloser(body))
+		c.Request.ContentLength = -1
+		c.Request.Header.Set("Content-Type", contentType)
+		handler.Upload(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarHandler handles HTTP requests for calendar overrides
+type CalendarHandler struct {
+	calendarRepo *store.CalendarRepository
+	scheduleRepo *store.ScheduleRepository
+	importer     *services.CalendarImporter
+	scheduler    *services.SchedulerService
+}
+
+// NewCalendarHandler creates a new calendar handler instance
+func NewCalendarHandler(calendarRepo *store.CalendarRepository, scheduleRepo *store.ScheduleRepository, importer *services.CalendarImporter, scheduler *services.SchedulerService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarRepo: calendarRepo,
+		scheduleRepo: scheduleRepo,
+		importer:     importer,
+		scheduler:    scheduler,
+	}
+}
+
+// GetAll returns all calendar overrides, optionally limited to a date range
+func (h *CalendarHandler) GetAll(c *gin.Context) {
+	startStr := c.Query("start")
+	endStr := c.Query("end")
+
+	if startStr == "" && endStr == "" {
+		overrides, err := h.calendarRepo.GetAll()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar overrides"})
+			return
+		}
+		c.JSON(http.StatusOK, overrides)
+		return
+	}
+
+	start, err := time.Parse(models.CalendarDateFormat, startStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+		return
+	}
+	end, err := time.Parse(models.CalendarDateFormat, endStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+		return
+	}
+
+	overrides, err := h.calendarRepo.GetByDateRange(start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar overrides"})
+		return
+	}
+	c.JSON(http.StatusOK, overrides)
+}
+
+// Get returns a specific calendar override
+func (h *CalendarHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar override ID"})
+		return
+	}
+
+	override, err := h.calendarRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar override not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+// Create creates a new calendar override
+func (h *CalendarHandler) Create(c *gin.Context) {
+	var req models.CalendarOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override := &models.CalendarOverride{}
+	if err := h.applyRequest(override, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.calendarRepo.Create(override); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar override"})
+		return
+	}
+	recordAudit(c, models.AuditCalendarCreated, "calendar_override", override.ID, override.Name, nil, services.Snapshot(override))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+// Update updates an existing calendar override
+func (h *CalendarHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar override ID"})
+		return
+	}
+
+	var req models.CalendarOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override, err := h.calendarRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar override not found"})
+		return
+	}
+	before := services.Snapshot(override)
+
+	if err := h.applyRequest(override, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.calendarRepo.Update(override); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calendar override"})
+		return
+	}
+	recordAudit(c, models.AuditCalendarUpdated, "calendar_override", override.ID, override.Name, before, services.Snapshot(override))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+// Delete deletes a calendar override
+func (h *CalendarHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar override ID"})
+		return
+	}
+
+	override, err := h.calendarRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar override not found"})
+		return
+	}
+
+	if err := h.calendarRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar override"})
+		return
+	}
+	recordAudit(c, models.AuditCalendarDeleted, "calendar_override", override.ID, override.Name, services.Snapshot(override), nil)
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar override deleted successfully"})
+}
+
+// applyRequest validates the request and copies it onto the override
+func (h *CalendarHandler) applyRequest(override *models.CalendarOverride, req *models.CalendarOverrideRequest) error {
+	start, err := time.Parse(models.CalendarDateFormat, req.StartDate)
+	if err != nil {
+		return errors.New("startDate must be in YYYY-MM-DD format")
+	}
+
+	// A single-day override only needs a start date
+	endDate := req.EndDate
+	if endDate == "" {
+		endDate = req.StartDate
+	}
+	end, err := time.Parse(models.CalendarDateFormat, endDate)
+	if err != nil {
+		return errors.New("endDate must be in YYYY-MM-DD format")
+	}
+	if end.Before(start) {
+		return errors.New("endDate must not be before startDate")
+	}
+
+	var scheduleID *int64
+	if req.Action == models.CalendarActionUseSchedule {
+		if req.ScheduleID == nil {
+			return errors.New("scheduleId is required for the use_schedule action")
+		}
+		if _, err := h.scheduleRepo.Get(*req.ScheduleID); err != nil {
+			return errors.New("schedule not found")
+		}
+		scheduleID = req.ScheduleID
+	}
+
+	override.Name = req.Name
+	override.StartDate = start.Format(models.CalendarDateFormat)
+	override.EndDate = end.Format(models.CalendarDateFormat)
+	override.Action = req.Action
+	override.ScheduleID = scheduleID
+	override.Description = req.Description
+	return nil
+}
+
+// refreshScheduler reloads the calendar overrides into the scheduler
+func (h *CalendarHandler) refreshScheduler() error {
+	overrides, err := h.calendarRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	h.scheduler.UpdateCalendarOverrides(overrides)
+	return nil
+}
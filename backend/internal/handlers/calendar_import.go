@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewImport shows what importing an iCalendar document would do
+// without changing anything
+func (h *CalendarHandler) PreviewImport(c *gin.Context) {
+	plan, ok := h.planImport(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// Import writes the events of an iCalendar document as calendar overrides
+func (h *CalendarHandler) Import(c *gin.Context) {
+	plan, ok := h.planImport(c)
+	if !ok {
+		return
+	}
+
+	if err := h.importer.Apply(plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import calendar: " + err.Error()})
+		return
+	}
+	before, after := plan.Changes()
+	recordAudit(c, models.AuditCalendarImported, "calendar", 0, plan.CalendarName, before, after)
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// planImport binds the request and plans the import, writing the error
+// response if it cannot
+func (h *CalendarHandler) planImport(c *gin.Context) (*services.CalendarImportPlan, bool) {
+	var req models.CalendarImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	plan, err := h.importer.Plan([]byte(req.Calendar), services.CalendarImportOptions{
+		Mappings: req.Mappings,
+		From:     req.From,
+		To:       req.To,
+		Location: h.scheduler.Location(),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return plan, true
+}
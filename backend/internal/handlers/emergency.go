@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmergencyHandler handles HTTP requests for lockdown and evacuation signalling
+type EmergencyHandler struct {
+	emergency *services.EmergencyService
+}
+
+// NewEmergencyHandler creates a new emergency handler instance
+func NewEmergencyHandler(emergency *services.EmergencyService) *EmergencyHandler {
+	return &EmergencyHandler{
+		emergency: emergency,
+	}
+}
+
+// Get returns the current emergency state
+func (h *EmergencyHandler) Get(c *gin.Context) {
+	state, err := h.emergency.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get emergency state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// Start begins emergency signalling on every zone
+func (h *EmergencyHandler) Start(c *gin.Context) {
+	var req models.StartEmergencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+
+	before, _ := h.emergency.Status()
+	state, err := h.emergency.Start(req.Mode, req.Reason, userID, username)
+	if errors.Is(err, services.ErrEmergencyAlreadyActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An emergency is already active"})
+		return
+	}
+	if state != nil {
+		recordAudit(c, models.AuditEmergencyStarted, "emergency", state.ID, state.Mode, services.Snapshot(before), services.Snapshot(state))
+	}
+	if err != nil {
+		if state != nil {
+			// Recorded and suppressing bells, but some outputs failed
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Emergency started but signalling failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start emergency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// Stop ends emergency signalling and resumes scheduled bells
+func (h *EmergencyHandler) Stop(c *gin.Context) {
+	var req models.StopEmergencyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+
+	before, _ := h.emergency.Status()
+	state, err := h.emergency.Stop(req.Reason, userID, username)
+	if errors.Is(err, services.ErrNoEmergency) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No emergency is active"})
+		return
+	}
+	if state != nil {
+		mode := ""
+		if before != nil {
+			mode = before.Mode
+		}
+		recordAudit(c, models.AuditEmergencyStopped, "emergency", state.ID, mode, services.Snapshot(before), services.Snapshot(state))
+	}
+	if err != nil {
+		if state != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Emergency stopped but switching outputs off failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop emergency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// GetLog returns the emergency log together with the result of verifying
+// its hash chain
+func (h *EmergencyHandler) GetLog(c *gin.Context) {
+	entries, intact, brokenID, err := h.emergency.Log()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get emergency log"})
+		return
+	}
+
+	response := gin.H{
+		"entries":  entries,
+		"verified": intact,
+	}
+	if !intact {
+		response["brokenAt"] = brokenID
+	}
+	c.JSON(http.StatusOK, response)
+}
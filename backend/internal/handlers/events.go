@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"time"
+
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventsKeepAlive is how often an idle stream sends a comment so proxies
+// do not close the connection
+const eventsKeepAlive = 30 * time.Second
+
+// EventsHandler streams scheduler state changes as Server-Sent Events
+type EventsHandler struct {
+	scheduler *services.SchedulerService
+}
+
+// NewEventsHandler creates a new events handler instance
+func NewEventsHandler(scheduler *services.SchedulerService) *EventsHandler {
+	return &EventsHandler{
+		scheduler: scheduler,
+	}
+}
+
+// Stream sends a status snapshot followed by every event published by the
+// scheduler until the client disconnects
+func (h *EventsHandler) Stream(c *gin.Context) {
+	events, unsubscribe := h.scheduler.Events().Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("status", gin.H{
+		"bellActive": h.scheduler.IsActive(),
+		"schedule":   h.scheduler.CurrentSchedule(),
+		"emergency":  h.scheduler.EmergencyMode(),
+	})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent(event.Type, event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
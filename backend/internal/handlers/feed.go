@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeedPath is where calendar clients fetch feeds
+const FeedPath = "/api/calendar.ics"
+
+// FeedHandler handles HTTP requests for iCalendar feeds
+type FeedHandler struct {
+	feedRepo     *store.FeedRepository
+	userRepo     *store.GormUserRepository
+	scheduleRepo *store.ScheduleRepository
+	zoneRepo     *store.ZoneRepository
+	scheduler    *services.SchedulerService
+}
+
+// NewFeedHandler creates a new feed handler instance
+func NewFeedHandler(feedRepo *store.FeedRepository, userRepo *store.GormUserRepository, scheduleRepo *store.ScheduleRepository, zoneRepo *store.ZoneRepository, scheduler *services.SchedulerService) *FeedHandler {
+	return &FeedHandler{
+		feedRepo:     feedRepo,
+		userRepo:     userRepo,
+		scheduleRepo: scheduleRepo,
+		zoneRepo:     zoneRepo,
+		scheduler:    scheduler,
+	}
+}
+
+// createdFeedResponse is a new feed with its token, which is only shown once
+type createdFeedResponse struct {
+	models.CalendarFeed
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// GetAll returns the current user's feeds
+func (h *FeedHandler) GetAll(c *gin.Context) {
+	feeds, err := h.feedRepo.GetByUser(c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar feeds"})
+		return
+	}
+	c.JSON(http.StatusOK, feeds)
+}
+
+// Create adds a feed for the current user and returns its subscription URL
+func (h *FeedHandler) Create(c *gin.Context) {
+	var req models.CalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ScheduleID != nil && req.ZoneID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A feed can be limited to a schedule or a zone, not both"})
+		return
+	}
+	if req.ScheduleID != nil {
+		if _, err := h.scheduleRepo.Get(*req.ScheduleID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "schedule not found"})
+			return
+		}
+	}
+	if req.ZoneID != nil {
+		if _, err := h.zoneRepo.Get(*req.ZoneID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "zone not found"})
+			return
+		}
+	}
+	days := req.Days
+	if days == 0 {
+		days = models.DefaultFeedDays
+	}
+
+	token, hash, err := models.NewFeedToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+	feed := &models.CalendarFeed{
+		Name:       req.Name,
+		UserID:     c.GetInt64("user_id"),
+		TokenHash:  hash,
+		ScheduleID: req.ScheduleID,
+		ZoneID:     req.ZoneID,
+		Days:       days,
+	}
+	if err := h.feedRepo.Create(feed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdFeedResponse{
+		CalendarFeed: *feed,
+		Token:        token,
+		URL:          feedURL(c, token),
+	})
+}
+
+// Delete removes one of the current user's feeds, which stops its URL
+// from working
+func (h *FeedHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed ID"})
+		return
+	}
+
+	feed, err := h.feedRepo.Get(id)
+	if err != nil || feed.UserID != c.GetInt64("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+	if err := h.feedRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted"})
+}
+
+// Feed serves the iCalendar document of the feed named by the token query
+// parameter. Feeds stop working when their owner is deactivated or deleted.
+func (h *FeedHandler) Feed(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Feed token required"})
+		return
+	}
+
+	feed, err := h.feedRepo.GetByTokenHash(models.HashFeedToken(token))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid feed token"})
+		return
+	}
+	user, err := h.userRepo.GetByID(feed.UserID)
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid feed token"})
+		return
+	}
+
+	if err := h.feedRepo.Touch(feed.ID, h.scheduler.Now()); err != nil {
+		fmt.Printf("Failed to record feed use: %v\n", err)
+	}
+
+	c.Header("Content-Disposition", `inline; filename="bells.ics"`)
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", h.scheduler.CalendarFeed(feed))
+}
+
+// feedURL returns the subscription URL of a feed as seen by the client
+// that created it
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s%s?token=%s", scheme, c.Request.Host, FeedPath, token)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler handles HTTP requests for account invitations
+type InvitationHandler struct {
+	invitationRepo *store.InvitationRepository
+	userRepo       store.UserRepository
+	roleRepo       *store.RoleRepository
+	emailService   *services.EmailService
+}
+
+// NewInvitationHandler creates a new invitation handler instance
+func NewInvitationHandler(invitationRepo *store.InvitationRepository, userRepo store.UserRepository, roleRepo *store.RoleRepository, emailService *services.EmailService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		emailService:   emailService,
+	}
+}
+
+// GetAll returns all invitations
+func (h *InvitationHandler) GetAll(c *gin.Context) {
+	invitations, err := h.invitationRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// Create invites an email address to register with a role and emails the
+// invitation token
+func (h *InvitationHandler) Create(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+	if !requireAssignable(c, h.roleRepo, req.Role) {
+		return
+	}
+	if existingUser, _ := h.userRepo.GetByEmail(req.Email); existingUser != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	token, hash, err := models.NewInvitationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+
+	invitation := &models.Invitation{
+		Email:         req.Email,
+		Role:          req.Role,
+		TokenHash:     hash,
+		InvitedByID:   c.GetInt64("user_id"),
+		InvitedByName: c.GetString("username"),
+		ExpiresAt:     time.Now().Add(models.InvitationLifetime),
+	}
+	if err := h.invitationRepo.Create(invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	if err := h.emailService.SendInvitationEmail(invitation.Email, token, invitation.InvitedByName, invitation.ExpiresAt); err != nil {
+		// Without the email nobody can use the token
+		h.invitationRepo.Delete(invitation.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// Delete revokes an invitation
+func (h *InvitationHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if _, err := h.invitationRepo.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if err := h.invitationRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutHandler lets administrators lift login lockouts
+type LockoutHandler struct {
+	userRepo   *store.GormUserRepository
+	loginGuard *services.LoginGuard
+}
+
+// NewLockoutHandler creates a new lockout handler instance
+func NewLockoutHandler(userRepo *store.GormUserRepository, loginGuard *services.LoginGuard) *LockoutHandler {
+	return &LockoutHandler{
+		userRepo:   userRepo,
+		loginGuard: loginGuard,
+	}
+}
+
+// Unlock clears the failed logins and lockout of a user
+func (h *LockoutHandler) Unlock(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.loginGuard.Unlock(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	recordAudit(c, models.AuditAccountUnlocked, "user", user.ID, user.Username, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// UnblockIP clears the failed logins of an IP address
+func (h *LockoutHandler) UnblockIP(c *gin.Context) {
+	var req models.UnlockIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ip := net.ParseIP(req.IPAddress)
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	h.loginGuard.UnblockIP(ip.String())
+	recordAudit(c, models.AuditIPUnblocked, "ip", 0, ip.String(), nil, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "IP address unblocked"})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PatternHandler handles HTTP requests for the ring pattern library
+type PatternHandler struct {
+	patternRepo *store.PatternRepository
+	scheduler   *services.SchedulerService
+}
+
+// NewPatternHandler creates a new ring pattern handler instance
+func NewPatternHandler(patternRepo *store.PatternRepository, scheduler *services.SchedulerService) *PatternHandler {
+	return &PatternHandler{
+		patternRepo: patternRepo,
+		scheduler:   scheduler,
+	}
+}
+
+// GetAll returns all ring patterns
+func (h *PatternHandler) GetAll(c *gin.Context) {
+	patterns, err := h.patternRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ring patterns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, patterns)
+}
+
+// Get returns a specific ring pattern
+func (h *PatternHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ring pattern ID"})
+		return
+	}
+
+	pattern, err := h.patternRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ring pattern not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pattern)
+}
+
+// Create creates a new ring pattern
+func (h *PatternHandler) Create(c *gin.Context) {
+	var req models.RingPatternRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pattern := &models.RingPattern{}
+	if err := applyPatternRequest(pattern, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.patternRepo.Create(pattern); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ring pattern"})
+		return
+	}
+	recordAudit(c, models.AuditPatternCreated, "pattern", pattern.ID, pattern.Name, nil, services.Snapshot(pattern))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, pattern)
+}
+
+// Update updates an existing ring pattern
+func (h *PatternHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ring pattern ID"})
+		return
+	}
+
+	var req models.RingPatternRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pattern, err := h.patternRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ring pattern not found"})
+		return
+	}
+	before := services.Snapshot(pattern)
+
+	if err := applyPatternRequest(pattern, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.patternRepo.Update(pattern); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ring pattern"})
+		return
+	}
+	recordAudit(c, models.AuditPatternUpdated, "pattern", pattern.ID, pattern.Name, before, services.Snapshot(pattern))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pattern)
+}
+
+// Delete deletes a ring pattern that no time slot uses
+func (h *PatternHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ring pattern ID"})
+		return
+	}
+
+	pattern, err := h.patternRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ring pattern not found"})
+		return
+	}
+
+	count, err := h.patternRepo.CountUsage(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete ring pattern"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Ring pattern is used by %d time slots", count)})
+		return
+	}
+
+	if err := h.patternRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete ring pattern"})
+		return
+	}
+	recordAudit(c, models.AuditPatternDeleted, "pattern", pattern.ID, pattern.Name, services.Snapshot(pattern), nil)
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ring pattern deleted successfully"})
+}
+
+// applyPatternRequest validates the request and copies it onto the pattern
+func applyPatternRequest(pattern *models.RingPattern, req *models.RingPatternRequest) error {
+	if _, err := models.PatternDurations(req.Steps); err != nil {
+		return err
+	}
+
+	steps, err := json.Marshal(req.Steps)
+	if err != nil {
+		return err
+	}
+
+	pattern.Name = req.Name
+	pattern.Description = req.Description
+	pattern.Steps = string(steps)
+	return nil
+}
+
+// refreshScheduler reloads the ring pattern library into the scheduler
+func (h *PatternHandler) refreshScheduler() error {
+	patterns, err := h.patternRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	h.scheduler.UpdatePatterns(patterns)
+	return nil
+}
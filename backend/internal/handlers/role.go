@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler handles HTTP requests for roles and their permissions
+type RoleHandler struct {
+	roleRepo *store.RoleRepository
+}
+
+// NewRoleHandler creates a new role handler instance
+func NewRoleHandler(roleRepo *store.RoleRepository) *RoleHandler {
+	return &RoleHandler{
+		roleRepo: roleRepo,
+	}
+}
+
+// GetAll returns all roles
+func (h *RoleHandler) GetAll(c *gin.Context) {
+	roles, err := h.roleRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// Get returns a specific role
+func (h *RoleHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	role, err := h.roleRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// Permissions returns every permission a role can be granted
+func (h *RoleHandler) Permissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AllPermissions)
+}
+
+// Create creates a new custom role
+func (h *RoleHandler) Create(c *gin.Context) {
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if existing, _ := h.roleRepo.GetByName(req.Name); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	role := &models.Role{Name: req.Name, Description: req.Description}
+	if err := role.SetPermissions(req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireGrantable(c, h.roleRepo, req.Permissions) {
+		return
+	}
+
+	if err := h.roleRepo.Create(role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	recordAudit(c, models.AuditRoleCreated, "role", role.ID, role.Name, nil, services.Snapshot(role))
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// Update updates a role. Built-in roles keep their name, and the admin role
+// always keeps every permission.
+func (h *RoleHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.roleRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	// Only roles within the editor's own permissions can be changed, so a
+	// role cannot be stripped of what the editor could not give back
+	if !requireAssignable(c, h.roleRepo, role.Name) {
+		return
+	}
+	before := services.Snapshot(role)
+	previousName := role.Name
+	if req.Name != previousName {
+		if role.IsBuiltin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be renamed"})
+			return
+		}
+		if existing, _ := h.roleRepo.GetByName(req.Name); existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+			return
+		}
+	}
+
+	role.Name = req.Name
+	role.Description = req.Description
+	if role.Name != models.RoleAdmin {
+		if err := role.SetPermissions(req.Permissions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !requireGrantable(c, h.roleRepo, req.Permissions) {
+			return
+		}
+	}
+
+	if err := h.roleRepo.Update(role, previousName); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	recordAudit(c, models.AuditRoleUpdated, "role", role.ID, role.Name, before, services.Snapshot(role))
+
+	c.JSON(http.StatusOK, role)
+}
+
+// Delete deletes a custom role that no user holds
+func (h *RoleHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	role, err := h.roleRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if role.IsBuiltin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	count, err := h.roleRepo.CountUsers(role.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role usage"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Role is assigned to %d users", count)})
+		return
+	}
+
+	if err := h.roleRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	recordAudit(c, models.AuditRoleDeleted, "role", role.ID, role.Name, services.Snapshot(role), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// requireGrantable writes a 403 response and returns false unless the
+// signed-in user holds every one of the permissions, so managing users and
+// roles never hands out more than the manager has
+func requireGrantable(c *gin.Context, roles *store.RoleRepository, permissions []string) bool {
+	actor, err := roles.GetByName(c.GetString("role"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return false
+	}
+	for _, p := range permissions {
+		if !actor.HasPermission(p) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("You cannot grant the %s permission, which you do not hold", p)})
+			return false
+		}
+	}
+	return true
+}
+
+// requireAssignable writes the error response and returns false unless the
+// role exists and the signed-in user may give it to someone. The admin role
+// is reserved to administrators.
+func requireAssignable(c *gin.Context, roles *store.RoleRepository, roleName string) bool {
+	role, err := roles.GetByName(roleName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return false
+	}
+	if role.Name == models.RoleAdmin && c.GetString("role") != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can manage administrators"})
+		return false
+	}
+	permissions, err := role.PermissionList()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role"})
+		return false
+	}
+	return requireGrantable(c, roles, permissions)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callAs runs a handler on a JSON request signed in with the role
+func callAs(role string, handler gin.HandlerFunc, method, path string, params gin.Params, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("user_id", int64(100))
+	c.Set("role", role)
+	handler(c)
+	return w
+}
+
+func TestUserManagers_CannotGrantMoreThanTheyHold(t *testing.T) {
+	db := newTestDB(t)
+	roles := store.NewRoleRepository(db)
+	users := store.NewUserRepository(db)
+	manager := &models.Role{Name: "office"}
+	require.NoError(t, manager.SetPermissions([]string{models.PermUsersManage, models.PermLogsRead}))
+	require.NoError(t, roles.Create(manager))
+	admin := &models.User{Username: "head", Email: "head@example.com", Password: "password123", Role: models.RoleAdmin, IsActive: true}
+	require.NoError(t, users.Create(admin))
+
+	userHandler := NewUserHandler(users, roles, nil)
+	roleHandler := NewRoleHandler(roles)
+	newUser := func(role string) gin.H {
+		return gin.H{"username": "new-" + role, "email": role + "@example.com", "password": "password123", "role": role}
+	}
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		params  gin.Params
+		body    interface{}
+		want    int
+	}{
+		{"create administrator", userHandler.CreateUser, "POST", nil, newUser(models.RoleAdmin), http.StatusForbidden},
+		{"create user with more permissions", userHandler.CreateUser, "POST", nil, newUser(models.RoleUser), http.StatusForbidden},
+		{"create viewer", userHandler.CreateUser, "POST", nil, newUser(models.RoleViewer), http.StatusCreated},
+		{"reset administrator password", userHandler.UpdateUser, "PUT", gin.Params{{Key: "id", Value: "1"}},
+			gin.H{"username": "head", "email": "head@example.com", "password": "taken-over", "role": models.RoleAdmin, "isActive": true}, http.StatusForbidden},
+		{"delete administrator", userHandler.DeleteUser, "DELETE", gin.Params{{Key: "id", Value: "1"}}, nil, http.StatusForbidden},
+		{"create role with more permissions", roleHandler.Create, "POST", nil,
+			gin.H{"name": "power", "permissions": []string{models.PermUsersManage, models.PermSettingsWrite}}, http.StatusForbidden},
+		{"create role within permissions", roleHandler.Create, "POST", nil,
+			gin.H{"name": "clerk", "permissions": []string{models.PermLogsRead}}, http.StatusCreated},
+		{"grant more permissions to own role", roleHandler.Update, "PUT", gin.Params{{Key: "id", Value: "4"}},
+			gin.H{"name": "office", "permissions": models.AllPermissions}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := callAs("office", tt.handler, tt.method, "/api", tt.params, tt.body)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+
+	stored, err := users.GetByID(admin.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.CheckPassword("password123"))
+
+	// Administrators can hand out any role
+	w := callAs(models.RoleAdmin, userHandler.CreateUser, "POST", "/api", nil, newUser(models.RoleAdmin))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
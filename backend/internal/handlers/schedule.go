@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"bell_scheduler/internal/models"
 	"bell_scheduler/internal/services"
@@ -13,12 +16,19 @@ import (
 
 type ScheduleHandler struct {
 	scheduleRepo *store.ScheduleRepository
+	zoneRepo     *store.ZoneRepository
+	patternRepo  *store.PatternRepository
+	transfer     *services.ScheduleTransfer
 	scheduler    *services.SchedulerService
 }
 
-func NewScheduleHandler(scheduleRepo *store.ScheduleRepository, scheduler *services.SchedulerService) *ScheduleHandler {
+func NewScheduleHandler(scheduleRepo *store.ScheduleRepository, zoneRepo *store.ZoneRepository, patternRepo *store.PatternRepository,
+	transfer *services.ScheduleTransfer, scheduler *services.SchedulerService) *ScheduleHandler {
 	return &ScheduleHandler{
 		scheduleRepo: scheduleRepo,
+		zoneRepo:     zoneRepo,
+		patternRepo:  patternRepo,
+		transfer:     transfer,
 		scheduler:    scheduler,
 	}
 }
@@ -62,6 +72,10 @@ func (h *ScheduleHandler) Create(c *gin.Context) {
 	for _, slot := range req.TimeSlots {
 		println("Creating time slot with triggerTime:", slot.TriggerTime)
 	}
+	if err := h.validateTimeSlots(req.TimeSlots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	schedule := &models.Schedule{
 		Name:        req.Name,
@@ -71,7 +85,7 @@ func (h *ScheduleHandler) Create(c *gin.Context) {
 		TimeSlots:   req.TimeSlots,
 	}
 
-	if err := h.scheduleRepo.Create(schedule); err != nil {
+	if err := h.scheduleRepo.Create(schedule, revisionMeta(c, "")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
 		return
 	}
@@ -89,6 +103,7 @@ func (h *ScheduleHandler) Create(c *gin.Context) {
 		return
 	}
 	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleCreated, "schedule", schedule.ID, schedule.Name, nil, services.Snapshot(schedule))
 
 	c.JSON(http.StatusCreated, schedule)
 }
@@ -111,12 +126,17 @@ func (h *ScheduleHandler) Update(c *gin.Context) {
 	for _, slot := range req.TimeSlots {
 		println("Updating time slot with triggerTime:", slot.TriggerTime)
 	}
+	if err := h.validateTimeSlots(req.TimeSlots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	schedule, err := h.scheduleRepo.Get(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
 		return
 	}
+	before := services.Snapshot(schedule)
 
 	// Update basic schedule fields
 	schedule.Name = req.Name
@@ -168,7 +188,7 @@ func (h *ScheduleHandler) Update(c *gin.Context) {
 	}
 	schedule.TimeSlots = updatedTimeSlots
 
-	if err := h.scheduleRepo.Update(schedule); err != nil {
+	if err := h.scheduleRepo.Update(schedule, revisionMeta(c, "")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
 		return
 	}
@@ -186,6 +206,7 @@ func (h *ScheduleHandler) Update(c *gin.Context) {
 		return
 	}
 	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleUpdated, "schedule", schedule.ID, schedule.Name, before, services.Snapshot(findSchedule(schedules, schedule.ID)))
 
 	c.JSON(http.StatusOK, schedule)
 }
@@ -198,6 +219,12 @@ func (h *ScheduleHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	schedule, err := h.scheduleRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
 	if err := h.scheduleRepo.Delete(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
 		return
@@ -210,19 +237,158 @@ func (h *ScheduleHandler) Delete(c *gin.Context) {
 		return
 	}
 	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleDeleted, "schedule", id, schedule.Name, services.Snapshot(schedule), nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
 }
 
-// TriggerNow manually triggers the bell
+// TriggerNow manually triggers the bell, in every zone unless the optional
+// request body limits it to some zones or selects a ring pattern
 func (h *ScheduleHandler) TriggerNow(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 	username := c.GetString("username")
 
-	if err := h.scheduler.TriggerNow(userID, username); err != nil {
+	var req models.TriggerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.scheduler.TriggerNow(userID, username, req.ZoneIDs, req.PatternID); err != nil {
+		if errors.Is(err, services.ErrEmergencyActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An emergency signal is active"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to trigger bell"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Bell triggered successfully"})
 }
+
+// StopNow stops the bell, in every zone unless the optional request body
+// limits it to some zones
+func (h *ScheduleHandler) StopNow(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+
+	var req models.StopRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.scheduler.StopNow(userID, username, req.ZoneIDs); err != nil {
+		if errors.Is(err, services.ErrEmergencyActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An emergency signal is active"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop bell"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bell stopped successfully"})
+}
+
+// Upcoming returns the bells due in the next `hours` (default 24) or `days`,
+// expanded from the schedules, temporary schedule and calendar overrides
+func (h *ScheduleHandler) Upcoming(c *gin.Context) {
+	window := 24 * time.Hour
+	if hours := c.Query("hours"); hours != "" {
+		n, err := strconv.Atoi(hours)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hours"})
+			return
+		}
+		window = time.Duration(n) * time.Hour
+	} else if days := c.Query("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+			return
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	}
+	if window > services.MaxUpcomingWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The timeline is limited to 14 days"})
+		return
+	}
+
+	limit := 0
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	from := h.scheduler.Now()
+	bells := h.scheduler.Upcoming(from, window)
+	if limit > 0 && len(bells) > limit {
+		bells = bells[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone": h.scheduler.Location().String(),
+		"from":     from,
+		"to":       from.Add(window),
+		"bells":    bells,
+	})
+}
+
+// findSchedule returns the schedule with the ID, or nil
+func findSchedule(schedules []models.Schedule, id int64) *models.Schedule {
+	for i := range schedules {
+		if schedules[i].ID == id {
+			return &schedules[i]
+		}
+	}
+	return nil
+}
+
+// validateTimeSlots checks the recurrence settings of each time slot, and
+// that the zones and ring pattern it names exist
+func (h *ScheduleHandler) validateTimeSlots(timeSlots []models.TimeSlot) error {
+	zones, err := h.zoneRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to get zones: %w", err)
+	}
+	patterns, err := h.patternRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to get ring patterns: %w", err)
+	}
+	zoneExists := make(map[int64]bool, len(zones))
+	for _, zone := range zones {
+		zoneExists[zone.ID] = true
+	}
+	patternExists := make(map[int64]bool, len(patterns))
+	for _, pattern := range patterns {
+		patternExists[pattern.ID] = true
+	}
+
+	for i := range timeSlots {
+		slot := &timeSlots[i]
+		if err := slot.ValidateRecurrence(); err != nil {
+			return fmt.Errorf("time slot %s: %w", slot.TriggerTime, err)
+		}
+		zoneIDs, err := slot.ZoneIDs()
+		if err != nil {
+			return fmt.Errorf("time slot %s: invalid zones: %w", slot.TriggerTime, err)
+		}
+		for _, id := range zoneIDs {
+			if !zoneExists[id] {
+				return fmt.Errorf("time slot %s: zone %d does not exist", slot.TriggerTime, id)
+			}
+		}
+		if slot.PatternID != nil && !patternExists[*slot.PatternID] {
+			return fmt.Errorf("time slot %s: ring pattern %d does not exist", slot.TriggerTime, *slot.PatternID)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,6 +24,7 @@ func (h *ScheduleHandler) SetDefault(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
 		return
 	}
+	before := services.Snapshot(schedule)
 
 	// Set as default
 	if err := h.scheduleRepo.SetDefault(id); err != nil {
@@ -35,6 +39,7 @@ func (h *ScheduleHandler) SetDefault(c *gin.Context) {
 		return
 	}
 	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleDefault, "schedule", id, schedule.Name, before, services.Snapshot(findSchedule(schedules, id)))
 
 	c.JSON(http.StatusOK, gin.H{"message": "Schedule set as default successfully", "schedule": schedule})
 }
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// revisionResponse is a revision with how it differs from an earlier one
+type revisionResponse struct {
+	models.ScheduleRevision
+	TimeSlots []models.RevisionSlot `json:"timeSlots,omitempty"`
+	Changes   services.RevisionDiff `json:"changes"`
+}
+
+// Revisions lists the revisions of a schedule, newest first, each with the
+// changes from the one before it
+func (h *ScheduleHandler) Revisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+	// A deleted schedule keeps its revisions
+	revisions, err := h.scheduleRepo.GetRevisions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+	if len(revisions) == 0 {
+		if _, err := h.scheduleRepo.Get(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+	}
+
+	response := make([]revisionResponse, 0, len(revisions))
+	for i := range revisions {
+		var previous *models.ScheduleRevision
+		if i+1 < len(revisions) {
+			previous = &revisions[i+1]
+		}
+		changes, err := services.DiffRevisions(previous, &revisions[i])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare revisions"})
+			return
+		}
+		response = append(response, revisionResponse{ScheduleRevision: revisions[i], Changes: changes})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Revision returns a revision with its time slots and the changes from the
+// one before it, or from the revision given as compare
+func (h *ScheduleHandler) Revision(c *gin.Context) {
+	revision, ok := h.revision(c)
+	if !ok {
+		return
+	}
+
+	compareTo := revision.Number - 1
+	if compare := c.Query("compare"); compare != "" {
+		n, err := strconv.Atoi(compare)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number to compare"})
+			return
+		}
+		compareTo = n
+	}
+	var previous *models.ScheduleRevision
+	if compareTo > 0 {
+		found, err := h.scheduleRepo.GetRevision(revision.ScheduleID, compareTo)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision to compare not found"})
+			return
+		}
+		previous = found
+	}
+
+	slots, err := revision.Slots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read revision"})
+		return
+	}
+	changes, err := services.DiffRevisions(previous, revision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare revisions"})
+		return
+	}
+	c.JSON(http.StatusOK, revisionResponse{ScheduleRevision: *revision, TimeSlots: slots, Changes: changes})
+}
+
+// RestoreRevision puts the name, description and time slots of a revision
+// back into its schedule, saved as a new revision. A deleted schedule is
+// recreated with its ID, inactive until it is switched on again.
+func (h *ScheduleHandler) RestoreRevision(c *gin.Context) {
+	revision, ok := h.revision(c)
+	if !ok {
+		return
+	}
+	timeSlots, err := revision.ScheduleTimeSlots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read revision"})
+		return
+	}
+	// Zones and ring patterns may have been deleted since the revision
+	if err := h.validateTimeSlots(timeSlots); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	meta := revisionMeta(c, fmt.Sprintf("Restored revision %d", revision.Number))
+
+	schedule, err := h.scheduleRepo.Get(revision.ScheduleID)
+	var before map[string]interface{}
+	switch {
+	case err == nil:
+		before = services.Snapshot(schedule)
+		schedule.Name = revision.Name
+		schedule.Description = revision.Description
+		schedule.TimeSlots = timeSlots
+		err = h.scheduleRepo.Update(schedule, meta)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		schedule = &models.Schedule{
+			BaseModel:   models.BaseModel{ID: revision.ScheduleID},
+			Name:        revision.Name,
+			Description: revision.Description,
+			TimeSlots:   timeSlots,
+		}
+		err = h.scheduleRepo.Create(schedule, meta)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	// Update scheduler with the restored schedule
+	schedules, err := h.scheduleRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleRestored, "schedule", schedule.ID, schedule.Name, before, services.Snapshot(schedule))
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// revision loads the revision named in the path, writing the error
+// response when there is none
+func (h *ScheduleHandler) revision(c *gin.Context) (*models.ScheduleRevision, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return nil, false
+	}
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return nil, false
+	}
+
+	revision, err := h.scheduleRepo.GetRevision(id, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return nil, false
+	}
+	return revision, true
+}
+
+// revisionMeta names the user saving a schedule in the revision it creates
+func revisionMeta(c *gin.Context, note string) models.RevisionMeta {
+	return models.RevisionMeta{
+		AuthorID:   c.GetInt64("user_id"),
+		AuthorName: c.GetString("username"),
+		Note:       note,
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,6 +24,7 @@ func (h *ScheduleHandler) SetTemporary(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
 		return
 	}
+	before := services.Snapshot(schedule)
 
 	// Parse request body to get temporary flag
 	var request struct {
@@ -37,7 +41,7 @@ func (h *ScheduleHandler) SetTemporary(c *gin.Context) {
 	schedule.IsActive = true // Also set as active
 
 	// Save the temporary flag
-	if err := h.scheduleRepo.Update(schedule); err != nil {
+	if err := h.scheduleRepo.Update(schedule, revisionMeta(c, "")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
 		return
 	}
@@ -55,6 +59,7 @@ func (h *ScheduleHandler) SetTemporary(c *gin.Context) {
 		return
 	}
 	h.scheduler.UpdateSchedules(schedules)
+	recordAudit(c, models.AuditScheduleTemporary, "schedule", id, schedule.Name, before, services.Snapshot(findSchedule(schedules, id)))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Schedule set as active successfully",
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleContentTypes are the content types of the schedule file formats
+var scheduleContentTypes = map[string]string{
+	services.ScheduleFormatJSON: "application/json; charset=utf-8",
+	services.ScheduleFormatYAML: "application/yaml; charset=utf-8",
+	services.ScheduleFormatCSV:  "text/csv; charset=utf-8",
+}
+
+// Export downloads schedules as a JSON, YAML or CSV file. The optional ids
+// query parameter limits the export to a comma-separated list of schedules.
+func (h *ScheduleHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", services.ScheduleFormatJSON)
+	contentType, ok := scheduleContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, yaml or csv"})
+		return
+	}
+
+	var ids []int64
+	if value := c.Query("ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	data, err := h.transfer.Export(ids, format)
+	if err != nil {
+		if errors.Is(err, services.ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export schedules"})
+		return
+	}
+
+	filename := fmt.Sprintf("schedules-%s.%s", h.scheduler.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Import validates a schedule file and imports its schedules, handling
+// schedules whose name is taken as the request asks. A dry run, or a file
+// with invalid schedules, changes nothing and returns the plan.
+func (h *ScheduleHandler) Import(c *gin.Context) {
+	var req models.ScheduleImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	onConflict := req.OnConflict
+	if onConflict == "" {
+		onConflict = services.ConflictSkip
+	}
+
+	plan, err := h.transfer.Plan([]byte(req.Data), req.Format, onConflict)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+	if !plan.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidImport.Error(), "plan": plan})
+		return
+	}
+
+	if err := h.transfer.Apply(plan, revisionMeta(c, "Imported from a file")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import schedules: " + err.Error()})
+		return
+	}
+
+	schedules, err := h.scheduleRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+	h.scheduler.UpdateSchedules(schedules)
+	for _, schedule := range plan.ImportedSchedules() {
+		recordAudit(c, models.AuditScheduleImported, "schedule", schedule.ID, schedule.Name, nil, services.Snapshot(schedule))
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"bell_scheduler/internal/models"
@@ -11,14 +12,48 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// settingsResponse is the settings representation sent to the frontend,
+// with durations converted to seconds
+type settingsResponse struct {
+	RingDuration  int    `json:"ringDuration"`
+	GPIOPin       int    `json:"gpioPin"`
+	OutputDriver  string `json:"outputDriver"`
+	GPIOChip      string `json:"gpioChip"`
+	Timezone      string `json:"timezone"`
+	CatchUpPolicy string `json:"catchUpPolicy"`
+	CatchUpGrace  int    `json:"catchUpGrace"`
+	OverlapPolicy string `json:"overlapPolicy"`
+
+	RegistrationPolicy    string `json:"registrationPolicy"`
+	RequireAdminTwoFactor bool   `json:"requireAdminTwoFactor"`
+}
+
+func newSettingsResponse(settings *models.Settings) settingsResponse {
+	return settingsResponse{
+		RingDuration:  int(settings.RingDuration.Seconds()),
+		GPIOPin:       settings.GPIOPin,
+		OutputDriver:  settings.OutputDriver,
+		GPIOChip:      settings.GPIOChip,
+		Timezone:      settings.Timezone,
+		CatchUpPolicy: settings.CatchUpPolicy,
+		CatchUpGrace:  int(settings.CatchUpGrace.Seconds()),
+		OverlapPolicy: settings.OverlapPolicy,
+
+		RegistrationPolicy:    settings.RegistrationPolicy,
+		RequireAdminTwoFactor: settings.RequireAdminTwoFactor,
+	}
+}
+
 type SettingsHandler struct {
 	settingsRepo *store.SettingsRepository
+	zoneRepo     *store.ZoneRepository
 	scheduler    *services.SchedulerService
 }
 
-func NewSettingsHandler(settingsRepo *store.SettingsRepository, scheduler *services.SchedulerService) *SettingsHandler {
+func NewSettingsHandler(settingsRepo *store.SettingsRepository, zoneRepo *store.ZoneRepository, scheduler *services.SchedulerService) *SettingsHandler {
 	return &SettingsHandler{
 		settingsRepo: settingsRepo,
+		zoneRepo:     zoneRepo,
 		scheduler:    scheduler,
 	}
 }
@@ -31,18 +66,7 @@ func (h *SettingsHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// Convert duration to seconds for frontend
-	response := struct {
-		RingDuration int    `json:"ringDuration"`
-		GPIOPin      int    `json:"gpioPin"`
-		Timezone     string `json:"timezone"`
-	}{
-		RingDuration: int(settings.RingDuration.Seconds()),
-		GPIOPin:      settings.GPIOPin,
-		Timezone:     settings.Timezone,
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, newSettingsResponse(settings))
 }
 
 // Update updates the settings
@@ -53,35 +77,96 @@ func (h *SettingsHandler) Update(c *gin.Context) {
 		return
 	}
 
+	// Reject unknown timezones before anything is persisted
+	if _, err := services.LoadTimezone(req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
 	settings, err := h.settingsRepo.Get()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
 		return
 	}
 
+	before := services.Snapshot(newSettingsResponse(settings))
+
+	// Remember the output configuration to detect changes
+	previousOutput := [3]string{settings.OutputDriver, settings.GPIOChip, strconv.Itoa(settings.GPIOPin)}
+
 	// Update settings
 	settings.RingDuration = time.Duration(req.RingDuration) * time.Second
 	settings.GPIOPin = req.GPIOPin
 	settings.Timezone = req.Timezone
+	if req.OutputDriver != "" {
+		settings.OutputDriver = req.OutputDriver
+	}
+	if req.GPIOChip != "" {
+		settings.GPIOChip = req.GPIOChip
+	}
+	if req.CatchUpPolicy != "" {
+		settings.CatchUpPolicy = req.CatchUpPolicy
+	}
+	if req.CatchUpGrace != nil {
+		settings.CatchUpGrace = time.Duration(*req.CatchUpGrace) * time.Second
+	}
+	if req.OverlapPolicy != "" {
+		settings.OverlapPolicy = req.OverlapPolicy
+	}
+	if req.RegistrationPolicy != "" {
+		settings.RegistrationPolicy = req.RegistrationPolicy
+	}
+	if req.RequireAdminTwoFactor != nil {
+		settings.RequireAdminTwoFactor = *req.RequireAdminTwoFactor
+	}
 
 	if err := h.settingsRepo.Update(settings); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 		return
 	}
+	recordAudit(c, models.AuditSettingsUpdated, "settings", settings.ID, "", before, services.Snapshot(newSettingsResponse(settings)))
 
 	// Update scheduler with new settings
 	h.scheduler.SetDuration(settings.RingDuration)
+	if err := h.scheduler.SetTimezone(settings.Timezone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply timezone"})
+		return
+	}
+	h.scheduler.SetCatchUpPolicy(settings.CatchUpPolicy, settings.CatchUpGrace)
+	h.scheduler.SetOverlapPolicy(settings.OverlapPolicy)
 
-	// Convert duration to seconds for frontend
-	response := struct {
-		RingDuration int    `json:"ringDuration"`
-		GPIOPin      int    `json:"gpioPin"`
-		Timezone     string `json:"timezone"`
-	}{
-		RingDuration: int(settings.RingDuration.Seconds()),
-		GPIOPin:      settings.GPIOPin,
-		Timezone:     settings.Timezone,
+	// The output settings belong to the default zone; switch its driver or
+	// pin without a restart
+	if previousOutput != [3]string{settings.OutputDriver, settings.GPIOChip, strconv.Itoa(settings.GPIOPin)} {
+		if err := h.updateDefaultZone(settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Settings saved but the output driver failed to open: " + err.Error()})
+			return
+		}
 	}
 
+	response := newSettingsResponse(settings)
+	h.scheduler.Events().Publish(services.EventSettingsChanged, response)
 	c.JSON(http.StatusOK, response)
 }
+
+// updateDefaultZone copies the output settings onto the default zone and
+// reloads the zones
+func (h *SettingsHandler) updateDefaultZone(settings *models.Settings) error {
+	zone, err := h.zoneRepo.GetDefault()
+	if err != nil {
+		return err
+	}
+
+	zone.OutputDriver = settings.OutputDriver
+	zone.GPIOChip = settings.GPIOChip
+	zone.GPIOPin = settings.GPIOPin
+	if err := h.zoneRepo.Update(zone); err != nil {
+		return err
+	}
+
+	zones, err := h.zoneRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	return h.scheduler.UpdateZones(zones)
+}
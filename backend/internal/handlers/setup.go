@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+	"bell_scheduler/pkg/ratelimiter"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Wrong setup codes allowed per IP address within setupCodeWindow
+const (
+	setupCodeWindow   = 15 * time.Minute
+	maxSetupCodeTries = 10
+)
+
+// SetupHandler creates the first administrator of a new installation.
+// There are no default credentials; until an active administrator exists,
+// whoever presents the setup code printed in the server log may create one.
+type SetupHandler struct {
+	userRepo *store.GormUserRepository
+	code     string
+	limiter  *ratelimiter.RateLimiter // Wrong setup codes per IP address
+	mu       sync.Mutex
+}
+
+// NewSetupHandler creates a new setup handler instance, keeping the count
+// of wrong setup codes in limits. An empty code is replaced by a random one.
+func NewSetupHandler(userRepo *store.GormUserRepository, limits ratelimiter.Store, code string) (*SetupHandler, error) {
+	if code == "" {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code = hex.EncodeToString(b)
+	}
+	return &SetupHandler{
+		userRepo: userRepo,
+		code:     code,
+		limiter:  ratelimiter.NewPersistentRateLimiter(setupCodeWindow, maxSetupCodeTries, limits),
+	}, nil
+}
+
+// Code returns the setup code
+func (h *SetupHandler) Code() string {
+	return h.code
+}
+
+// Required reports whether setup has yet to be completed, which is the
+// case while no active administrator exists
+func (h *SetupHandler) Required() (bool, error) {
+	count, err := h.userRepo.CountActiveAdmins()
+	return count == 0, err
+}
+
+// DisableDefaultAdmin deactivates the admin account with the password
+// "admin" that versions before setup codes created, if its password was
+// never changed. It reports whether the account was disabled.
+func (h *SetupHandler) DisableDefaultAdmin() (bool, error) {
+	user, err := h.userRepo.GetByUsername("admin")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !user.IsActive || !user.ForcePasswordChange || !user.CheckPassword("admin") {
+		return false, nil
+	}
+
+	user.IsActive = false
+	if err := h.userRepo.Update(user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Status reports whether setup has yet to be completed
+func (h *SetupHandler) Status(c *gin.Context) {
+	required, err := h.Required()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"required": required})
+}
+
+// Setup creates the first administrator
+func (h *SetupHandler) Setup(c *gin.Context) {
+	var req models.SetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The setup code is all that protects setup, so it cannot be guessed
+	key := "setup:ip:" + c.ClientIP()
+	if allowed, wait := h.limiter.Check(key); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts. Please try again later."})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	required, err := h.Required()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check setup"})
+		return
+	}
+	if !required {
+		c.JSON(http.StatusConflict, gin.H{"error": "Setup has already been completed"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.SetupCode), []byte(h.code)) != 1 {
+		h.limiter.Add(key)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid setup code"})
+		return
+	}
+	h.limiter.Clear(key)
+
+	// A disabled default admin keeps its username
+	if existingUser, _ := h.userRepo.GetByUsername(req.Username); existingUser != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+		return
+	}
+	if existingUser, _ := h.userRepo.GetByEmail(req.Email); existingUser != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	user := &models.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		Role:     models.RoleAdmin,
+		IsActive: true,
+	}
+	if err := h.userRepo.Create(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Setup completed",
+		"user":    user,
+	})
+}
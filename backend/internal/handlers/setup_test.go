@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup_DisablesDefaultAdmin(t *testing.T) {
+	db := newTestDB(t)
+	users := store.NewUserRepository(db)
+	require.NoError(t, users.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "admin", Role: models.RoleAdmin, IsActive: true, ForcePasswordChange: true}))
+	require.NoError(t, users.Create(&models.User{Username: "teacher", Email: "teacher@example.com", Password: "password123", Role: models.RoleUser, IsActive: true}))
+
+	handler, err := NewSetupHandler(users, store.NewRateLimitRepository(db), "abc123")
+	require.NoError(t, err)
+	required, err := handler.Required()
+	require.NoError(t, err)
+	assert.False(t, required)
+
+	disabled, err := handler.DisableDefaultAdmin()
+	require.NoError(t, err)
+	assert.True(t, disabled)
+	admin, err := users.GetByUsername("admin")
+	require.NoError(t, err)
+	assert.False(t, admin.IsActive)
+	required, err = handler.Required()
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	// The username stays taken by the disabled account
+	w := call(handler.Setup, "POST", "/api/setup", gin.H{"setupCode": "abc123", "username": "admin", "email": "head@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = call(handler.Setup, "POST", "/api/setup", gin.H{"setupCode": "abc123", "username": "head", "email": "head@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	required, err = handler.Required()
+	require.NoError(t, err)
+	assert.False(t, required)
+}
+
+func TestSetup_KeepsChangedAdmin(t *testing.T) {
+	db := newTestDB(t)
+	users := store.NewUserRepository(db)
+	require.NoError(t, users.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "changed123", Role: models.RoleAdmin, IsActive: true}))
+
+	handler, err := NewSetupHandler(users, store.NewRateLimitRepository(db), "")
+	require.NoError(t, err)
+	disabled, err := handler.DisableDefaultAdmin()
+	require.NoError(t, err)
+	assert.False(t, disabled)
+}
+
+func TestSetup_LimitsWrongCodes(t *testing.T) {
+	db := newTestDB(t)
+	handler, err := NewSetupHandler(store.NewUserRepository(db), store.NewRateLimitRepository(db), "abc123")
+	require.NoError(t, err)
+
+	guess := gin.H{"setupCode": "wrong", "username": "head", "email": "head@example.com", "password": "password123"}
+	for i := 0; i < maxSetupCodeTries; i++ {
+		w := call(handler.Setup, "POST", "/api/setup", guess)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	guess["setupCode"] = "abc123"
+	w := call(handler.Setup, "POST", "/api/setup", guess)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler handles enrolment in two-factor authentication
+type TwoFactorHandler struct {
+	userRepo     *store.GormUserRepository
+	settingsRepo *store.SettingsRepository
+	twoFactor    *services.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler instance
+func NewTwoFactorHandler(userRepo *store.GormUserRepository, settingsRepo *store.SettingsRepository, twoFactor *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		userRepo:     userRepo,
+		settingsRepo: settingsRepo,
+		twoFactor:    twoFactor,
+	}
+}
+
+// Status returns whether the authenticated user has two-factor
+// authentication enabled, and whether their role requires it
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	required, err := h.settingsRepo.RequiresTwoFactor(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                user.TOTPEnabled,
+		"required":               required,
+		"recoveryCodesRemaining": len(user.RecoveryCodeHashes()),
+	})
+}
+
+// Setup starts enrolment and returns the secret and provisioning URI for
+// the authenticator app
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := h.twoFactor.Setup(user)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+// Enable completes enrolment with a code from the authenticator app and
+// returns the recovery codes
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.Enable(user, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		case errors.Is(err, services.ErrTwoFactorNotSetUp):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start the setup first"})
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// Disable turns two-factor authentication off after checking the password
+// and a code. Users whose role requires it cannot turn it off.
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	required, err := h.settingsRepo.RequiresTwoFactor(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+	if !user.CheckPassword(req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if !h.verify(c, user, req.Code) {
+		return
+	}
+
+	if err := h.twoFactor.Disable(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RecoveryCodes replaces the recovery codes after checking a code
+func (h *TwoFactorHandler) RecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !h.verify(c, user, req.Code) {
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// Reset turns two-factor authentication off for another user who lost
+// their authenticator and recovery codes
+func (h *TwoFactorHandler) Reset(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.twoFactor.Disable(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+	recordAudit(c, models.AuditUserTwoFactorReset, "user", user.ID, user.Username, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// currentUser loads the authenticated user, writing the error response
+// when that fails
+func (h *TwoFactorHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	user, err := h.userRepo.GetByID(c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+// verify checks a code, writing the error response when it is wrong
+func (h *TwoFactorHandler) verify(c *gin.Context, user *models.User, code string) bool {
+	err := h.twoFactor.Verify(user, code)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
 	"bell_scheduler/internal/store"
 	"bytes"
 	"encoding/json"
@@ -17,11 +18,15 @@ import (
 
 type UserHandler struct {
 	userRepo store.UserRepository
+	roleRepo *store.RoleRepository
+	sessions *services.SessionService
 }
 
-func NewUserHandler(userRepo store.UserRepository) *UserHandler {
+func NewUserHandler(userRepo store.UserRepository, roleRepo *store.RoleRepository, sessions *services.SessionService) *UserHandler {
 	return &UserHandler{
 		userRepo: userRepo,
+		roleRepo: roleRepo,
+		sessions: sessions,
 	}
 }
 
@@ -100,7 +105,10 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 	// Set default role if not provided
 	if user.Role == "" {
-		user.Role = "user"
+		user.Role = models.RoleUser
+	}
+	if !requireAssignable(c, h.roleRepo, user.Role) {
+		return
 	}
 
 	// Create user
@@ -108,6 +116,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
+	recordAudit(c, models.AuditUserCreated, "user", user.ID, user.Username, nil, services.Snapshot(&user))
 
 	// Clear password before sending response
 	user.Password = ""
@@ -153,6 +162,12 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 	fmt.Printf("[UpdateUser] Found existing user: %s (ID: %d)\n", existingUser.Username, existingUser.ID)
 	fmt.Printf("[UpdateUser] Existing password hash length: %d\n", len(existingUser.Password))
+	// Users with permissions the editor lacks, whose password could be reset
+	// to take over their account, are out of reach
+	if !requireAssignable(c, h.roleRepo, existingUser.Role) {
+		return
+	}
+	before := services.Snapshot(existingUser)
 
 	var updateData models.User
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -171,6 +186,20 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if updateData.Role == "" {
+		updateData.Role = existingUser.Role
+	}
+	if updateData.Role != existingUser.Role {
+		// Keep administrators from locking themselves out
+		if existingUser.ID == c.GetInt64("user_id") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+			return
+		}
+		if !requireAssignable(c, h.roleRepo, updateData.Role) {
+			return
+		}
+	}
+
 	// Check username uniqueness if changed
 	if updateData.Username != existingUser.Username {
 		if existingUser, _ := h.userRepo.GetByUsername(updateData.Username); existingUser != nil {
@@ -187,6 +216,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		}
 	}
 
+	// A deactivated user or one whose password was reset is signed out everywhere
+	signOut := (existingUser.IsActive && !updateData.IsActive) || updateData.Password != ""
+
 	// Update fields
 	existingUser.Username = updateData.Username
 	existingUser.Email = updateData.Email
@@ -217,6 +249,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	fmt.Printf("[UpdateUser] User updated successfully\n")
+	recordAudit(c, models.AuditUserUpdated, "user", existingUser.ID, existingUser.Username, before, services.Snapshot(existingUser))
+
+	if signOut {
+		// Administrators editing themselves keep the session they are using
+		var keep int64
+		if existingUser.ID == c.GetInt64("user_id") {
+			keep = c.GetInt64("session_id")
+		}
+		if _, err := h.sessions.RevokeAll(existingUser.ID, keep); err != nil {
+			fmt.Printf("[UpdateUser] Error revoking sessions: %v\n", err)
+		}
+	}
 
 	// Clear password before sending response
 	existingUser.Password = ""
@@ -239,20 +283,141 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	}
 
 	// Check if user exists
-	if _, err := h.userRepo.GetByID(idInt); err != nil {
+	user, err := h.userRepo.GetByID(idInt)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	if idInt == c.GetInt64("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+	if !requireAssignable(c, h.roleRepo, user.Role) {
+		return
+	}
 
 	// Delete user
 	if err := h.userRepo.Delete(idInt); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	if err := h.sessions.DeleteAll(idInt); err != nil {
+		fmt.Printf("[DeleteUser] Error deleting sessions: %v\n", err)
+	}
+	recordAudit(c, models.AuditUserDeleted, "user", user.ID, user.Username, services.Snapshot(user), nil)
 
 	c.Status(http.StatusNoContent)
 }
 
+// RevokeSessions signs a user out of every device
+func (h *UserHandler) RevokeSessions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	revoked, err := h.sessions.RevokeAll(id, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	recordAudit(c, models.AuditUserSessionsRevoked, "user", user.ID, user.Username, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// GetPending returns the self-registered users awaiting approval
+func (h *UserHandler) GetPending(c *gin.Context) {
+	users, err := h.userRepo.GetPending()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Clear passwords before sending response
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// ApproveUser lets a pending user log in, optionally with a different role
+func (h *UserHandler) ApproveUser(c *gin.Context) {
+	user, ok := h.pendingUser(c)
+	if !ok {
+		return
+	}
+	before := services.Snapshot(user)
+
+	var req models.ApproveUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Role != "" {
+		if !requireAssignable(c, h.roleRepo, req.Role) {
+			return
+		}
+		user.Role = req.Role
+	}
+
+	user.PendingApproval = false
+	if err := h.userRepo.Update(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve user"})
+		return
+	}
+	recordAudit(c, models.AuditUserApproved, "user", user.ID, user.Username, before, services.Snapshot(user))
+
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// RejectUser deletes a pending user
+func (h *UserHandler) RejectUser(c *gin.Context) {
+	user, ok := h.pendingUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.Delete(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject user"})
+		return
+	}
+	recordAudit(c, models.AuditUserRejected, "user", user.ID, user.Username, services.Snapshot(user), nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// pendingUser loads the user named in the path, writing the error response
+// when there is none or the user is not awaiting approval
+func (h *UserHandler) pendingUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if !user.PendingApproval {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not awaiting approval"})
+		return nil, false
+	}
+	return user, true
+}
+
 // validateUserInput validates user input data
 func validateUserInput(user *models.User) error {
 	if user.Username == "" {
@@ -277,8 +442,5 @@ func validateUserInput(user *models.User) error {
 	if user.Password != "" && len(user.Password) < 8 {
 		return errors.New("password must be at least 8 characters long")
 	}
-	if user.Role != "" && user.Role != "admin" && user.Role != "user" {
-		return errors.New("invalid role")
-	}
 	return nil
 }
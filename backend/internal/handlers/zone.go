@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/services"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ZoneHandler handles HTTP requests for bell zones
+type ZoneHandler struct {
+	zoneRepo     *store.ZoneRepository
+	settingsRepo *store.SettingsRepository
+	scheduler    *services.SchedulerService
+}
+
+// NewZoneHandler creates a new zone handler instance
+func NewZoneHandler(zoneRepo *store.ZoneRepository, settingsRepo *store.SettingsRepository, scheduler *services.SchedulerService) *ZoneHandler {
+	return &ZoneHandler{
+		zoneRepo:     zoneRepo,
+		settingsRepo: settingsRepo,
+		scheduler:    scheduler,
+	}
+}
+
+// GetAll returns all zones
+func (h *ZoneHandler) GetAll(c *gin.Context) {
+	zones, err := h.zoneRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get zones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
+
+// Get returns a specific zone
+func (h *ZoneHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	zone, err := h.zoneRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// Create creates a new zone
+func (h *ZoneHandler) Create(c *gin.Context) {
+	var req models.ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone := &models.Zone{}
+	applyZoneRequest(zone, &req)
+	// The default flag is set through SetDefault so only one zone holds it
+	zone.IsDefault = false
+
+	if err := h.zoneRepo.Create(zone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zone"})
+		return
+	}
+
+	if req.IsDefault {
+		if err := h.makeDefault(zone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default zone"})
+			return
+		}
+	}
+	recordAudit(c, models.AuditZoneCreated, "zone", zone.ID, zone.Name, nil, services.Snapshot(zone))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Zone saved but its output failed to open: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// Update updates an existing zone
+func (h *ZoneHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req models.ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.zoneRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return
+	}
+
+	before := services.Snapshot(zone)
+
+	// A zone stops being the default only when another zone takes over
+	wasDefault := zone.IsDefault
+	applyZoneRequest(zone, &req)
+	zone.IsDefault = wasDefault
+
+	if err := h.zoneRepo.Update(zone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update zone"})
+		return
+	}
+
+	if req.IsDefault || wasDefault {
+		if err := h.makeDefault(zone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default zone"})
+			return
+		}
+	}
+	recordAudit(c, models.AuditZoneUpdated, "zone", zone.ID, zone.Name, before, services.Snapshot(zone))
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Zone saved but its output failed to open: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// Delete deletes a zone that no time slot rings
+func (h *ZoneHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	zone, err := h.zoneRepo.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return
+	}
+	if zone.IsDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete the default zone"})
+		return
+	}
+
+	count, err := h.zoneRepo.CountUsage(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete zone"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Zone is used by %d time slots", count)})
+		return
+	}
+
+	if err := h.zoneRepo.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete zone"})
+		return
+	}
+	recordAudit(c, models.AuditZoneDeleted, "zone", zone.ID, zone.Name, services.Snapshot(zone), nil)
+
+	if err := h.refreshScheduler(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Zone deleted successfully"})
+}
+
+// Trigger manually rings the bell of a single zone, optionally playing the
+// ring pattern given in the request body
+func (h *ZoneHandler) Trigger(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req models.TriggerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if _, err := h.zoneRepo.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+
+	if err := h.scheduler.TriggerNow(userID, username, []int64{id}, req.PatternID); err != nil {
+		if errors.Is(err, services.ErrEmergencyActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An emergency signal is active"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to trigger bell"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bell triggered successfully"})
+}
+
+// Stop stops the bell of a single zone and drops its queued rings
+func (h *ZoneHandler) Stop(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	if _, err := h.zoneRepo.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+
+	if err := h.scheduler.StopNow(userID, username, []int64{id}); err != nil {
+		if errors.Is(err, services.ErrEmergencyActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An emergency signal is active"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop bell"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bell stopped successfully"})
+}
+
+// applyZoneRequest copies the request onto the zone
+func applyZoneRequest(zone *models.Zone, req *models.ZoneRequest) {
+	zone.Name = req.Name
+	zone.Description = req.Description
+	zone.OutputDriver = req.OutputDriver
+	zone.GPIOChip = req.GPIOChip
+	zone.GPIOPin = req.GPIOPin
+	zone.RingDuration = req.RingDuration
+}
+
+// makeDefault marks the zone as the default and mirrors its output into the
+// settings, which always describe the default zone
+func (h *ZoneHandler) makeDefault(zone *models.Zone) error {
+	if err := h.zoneRepo.SetDefault(zone.ID); err != nil {
+		return err
+	}
+	zone.IsDefault = true
+
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		return err
+	}
+	settings.OutputDriver = zone.OutputDriver
+	settings.GPIOChip = zone.GPIOChip
+	settings.GPIOPin = zone.GPIOPin
+	return h.settingsRepo.Update(settings)
+}
+
+// refreshScheduler reloads the zones into the scheduler
+func (h *ZoneHandler) refreshScheduler() error {
+	zones, err := h.zoneRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	return h.scheduler.UpdateZones(zones)
+}
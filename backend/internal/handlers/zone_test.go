@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneDelete_InUse(t *testing.T) {
+	db := newTestDB(t)
+	zoneRepo := store.NewZoneRepository(db)
+	scheduleRepo := store.NewScheduleRepository(db)
+	gym := &models.Zone{Name: "Gym", OutputDriver: models.OutputDriverMock}
+	require.NoError(t, zoneRepo.Create(gym))
+	require.NoError(t, scheduleRepo.Create(&models.Schedule{Name: "Regular", TimeSlots: []models.TimeSlot{
+		{TriggerTime: "08:00", Days: `["monday"]`, Zones: "[1,2]"},
+		{TriggerTime: "09:00", Days: `["monday"]`, Zones: "[2]"},
+	}}, models.RevisionMeta{}))
+
+	handler := NewZoneHandler(zoneRepo, store.NewSettingsRepository(db), nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("DELETE", "/api/zones/2", nil)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+	handler.Delete(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "used by 2 time slots")
+	_, err := zoneRepo.Get(gym.ID)
+	assert.NoError(t, err)
+}
+
+func TestScheduleCreate_UnknownReferences(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewScheduleHandler(store.NewScheduleRepository(db), store.NewZoneRepository(db), store.NewPatternRepository(db), nil, nil)
+
+	w := call(handler.Create, "POST", "/api/schedules", gin.H{"name": "Regular", "timeSlots": []gin.H{
+		{"triggerTime": "08:00", "days": `["monday"]`, "zones": "[99]"},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "zone 99 does not exist")
+
+	w = call(handler.Create, "POST", "/api/schedules", gin.H{"name": "Regular", "timeSlots": []gin.H{
+		{"triggerTime": "08:00", "days": `["monday"]`, "patternId": 99},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ring pattern 99 does not exist")
+}
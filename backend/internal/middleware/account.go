@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordChangeRequired creates a middleware that blocks users who must
+// change their password from every route but the allowed ones. It runs
+// after Auth, which reads the flag from the stored user.
+func PasswordChangeRequired(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("force_password_change") {
+			c.Next()
+			return
+		}
+		for _, path := range allowed {
+			if c.FullPath() == path {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You must change your password before continuing",
+			"code":  "password_change_required",
+		})
+		c.Abort()
+	}
+}
+
+// TwoFactorPolicy reports whether users of a role must use two-factor
+// authentication
+type TwoFactorPolicy interface {
+	RequiresTwoFactor(role string) (bool, error)
+}
+
+// TwoFactorRequired creates a middleware that blocks users who must use
+// two-factor authentication but have not enrolled from every route but the
+// allowed ones. It runs after Auth.
+func TwoFactorRequired(policy TwoFactorPolicy, allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("two_factor_enabled") {
+			c.Next()
+			return
+		}
+		required, err := policy.RequiresTwoFactor(c.GetString("role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor policy"})
+			c.Abort()
+			return
+		}
+		if !required {
+			c.Next()
+			return
+		}
+		for _, path := range allowed {
+			if c.FullPath() == path {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You must set up two-factor authentication before continuing",
+			"code":  "two_factor_setup_required",
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordChangeRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := func(force bool, method, path string) int {
+		router := gin.New()
+		group := router.Group("/api")
+		group.Use(func(c *gin.Context) {
+			c.Set("force_password_change", force)
+		}, PasswordChangeRequired("/api/auth/change-password"))
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		group.POST("/auth/change-password", ok)
+		group.GET("/schedules/:id", ok)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(false, http.MethodGet, "/api/schedules/1"))
+	assert.Equal(t, http.StatusForbidden, request(true, http.MethodGet, "/api/schedules/1"))
+	assert.Equal(t, http.StatusOK, request(true, http.MethodPost, "/api/auth/change-password"))
+	assert.Equal(t, http.StatusOK, request(false, http.MethodPost, "/api/auth/change-password"))
+}
+
+type adminTwoFactorPolicy bool
+
+func (p adminTwoFactorPolicy) RequiresTwoFactor(role string) (bool, error) {
+	return bool(p) && role == "admin", nil
+}
+
+func TestTwoFactorRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := func(required bool, role string, enrolled bool, path string) int {
+		router := gin.New()
+		group := router.Group("/api")
+		group.Use(func(c *gin.Context) {
+			c.Set("role", role)
+			c.Set("two_factor_enabled", enrolled)
+		}, TwoFactorRequired(adminTwoFactorPolicy(required), "/api/auth/2fa/setup"))
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		group.POST("/auth/2fa/setup", ok)
+		group.POST("/schedules", ok)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(false, "admin", false, "/api/schedules"))
+	assert.Equal(t, http.StatusForbidden, request(true, "admin", false, "/api/schedules"))
+	assert.Equal(t, http.StatusOK, request(true, "admin", false, "/api/auth/2fa/setup"))
+	assert.Equal(t, http.StatusOK, request(true, "admin", true, "/api/schedules"))
+	assert.Equal(t, http.StatusOK, request(true, "user", false, "/api/schedules"))
+}
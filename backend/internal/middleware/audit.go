@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditStore stores audit entries
+type AuditStore interface {
+	Create(entry *models.AuditEntry) error
+}
+
+// Audit creates a middleware that records every request that changes
+// something in the audit log, once its handler has finished. Handlers can
+// describe the change by setting an *models.AuditEntry under "audit_entry",
+// which is recorded whatever the response. Other requests are recorded by
+// method and route if they succeed, except reads and the skipped routes.
+func Audit(store AuditStore, skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+
+		var entry models.AuditEntry
+		if detailed, ok := c.Get("audit_entry"); ok {
+			entry = *detailed.(*models.AuditEntry)
+		} else {
+			if skipped[route] || c.Writer.Status() >= http.StatusBadRequest {
+				return
+			}
+			entry.Action = c.Request.Method + " " + route
+			if id, err := strconv.ParseInt(c.Param("id"), 10, 64); err == nil {
+				entry.TargetID = id
+			}
+		}
+
+		entry.Timestamp = time.Now()
+		entry.Method = c.Request.Method
+		entry.Path = c.Request.URL.Path
+		entry.Status = c.Writer.Status()
+		entry.IPAddress = c.ClientIP()
+		if entry.ActorID == 0 {
+			entry.ActorID = c.GetInt64("user_id")
+		}
+		if entry.ActorName == "" {
+			entry.ActorName = c.GetString("username")
+		}
+
+		if err := store.Create(&entry); err != nil {
+			fmt.Printf("Failed to record audit entry %s: %v\n", entry.Action, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAuditStore []models.AuditEntry
+
+func (s *memoryAuditStore) Create(entry *models.AuditEntry) error {
+	*s = append(*s, *entry)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &memoryAuditStore{}
+	router := gin.New()
+	router.Use(Audit(store, "/api/auth/refresh"), func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("username", "head")
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/zones/:id", ok)
+	router.POST("/api/zones/:id/trigger", ok)
+	router.POST("/api/auth/refresh", ok)
+	router.DELETE("/api/zones/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.POST("/api/auth/login", func(c *gin.Context) {
+		c.Set("audit_entry", &models.AuditEntry{Action: models.AuditLoginFailed, TargetName: "head"})
+		c.Status(http.StatusUnauthorized)
+	})
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/api/zones/3"},
+		{http.MethodPost, "/api/zones/3/trigger"},
+		{http.MethodPost, "/api/auth/refresh"},
+		{http.MethodDelete, "/api/zones/3"},
+		{http.MethodPost, "/api/auth/login"},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	// Reads, skipped routes and failures without a handler's entry are left out
+	require.Len(t, *store, 2)
+	trigger := (*store)[0]
+	assert.Equal(t, "POST /api/zones/:id/trigger", trigger.Action)
+	assert.Equal(t, "/api/zones/3/trigger", trigger.Path)
+	assert.Equal(t, int64(3), trigger.TargetID)
+	assert.Equal(t, int64(7), trigger.ActorID)
+	assert.Equal(t, "head", trigger.ActorName)
+	assert.Equal(t, http.StatusOK, trigger.Status)
+	assert.False(t, trigger.Timestamp.IsZero())
+
+	login := (*store)[1]
+	assert.Equal(t, models.AuditLoginFailed, login.Action)
+	assert.Equal(t, http.StatusUnauthorized, login.Status)
+	assert.Equal(t, "head", login.TargetName)
+}
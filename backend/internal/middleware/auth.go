@@ -4,12 +4,22 @@ import (
 	"net/http"
 	"strings"
 
+	"bell_scheduler/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Auth creates a middleware that validates JWT tokens
-func Auth(jwtSecret string) gin.HandlerFunc {
+// SessionValidator looks up the session an access token was issued for
+type SessionValidator interface {
+	Validate(sessionID, userID int64) (*models.User, error)
+}
+
+// Auth creates a middleware that validates JWT tokens. The token's session
+// must still be active and its user enabled, so revoking a session or
+// deactivating a user takes effect immediately. Role and password change
+// state are read from the stored user rather than the token.
+func Auth(jwtSecret string, sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -48,12 +58,39 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Convert user_id from float64 to int64
-		userID := int64(claims["user_id"].(float64))
-		c.Set("user_id", userID)
-		c.Set("username", claims["username"].(string))
-		c.Set("role", claims["role"].(string))
-		c.Set("force_password_change", claims["force_password_change"].(bool))
+		// Numeric claims are decoded as float64
+		userID, okUser := claims["user_id"].(float64)
+		sessionID, okSession := claims["sid"].(float64)
+		if !okUser || !okSession {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.Abort()
+			return
+		}
+
+		user, err := sessions.Validate(int64(sessionID), int64(userID))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("session_id", int64(sessionID))
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Set("force_password_change", user.ForcePasswordChange)
+		c.Set("two_factor_enabled", user.TOTPEnabled)
+		c.Next()
+	}
+}
+
+// TokenFromQuery copies a token passed as the token query parameter into the
+// Authorization header, for clients such as EventSource that cannot set headers
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionChecker reports whether a role grants a permission
+type PermissionChecker interface {
+	HasPermission(role, permission string) (bool, error)
+}
+
+// RequirePermission creates a middleware that requires the role of the
+// authenticated user to grant the permission. Roles are looked up on every
+// request, so permission changes apply without logging in again.
+func RequirePermission(roles PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, err := roles.HasPermission(c.GetString("role"), permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestRoles(t *testing.T) (*store.RoleRepository, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Role{}))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	roles := store.NewRoleRepository(db)
+	for _, role := range models.DefaultRoles() {
+		role := role
+		require.NoError(t, roles.Create(&role))
+	}
+	return roles, db
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	roles, _ := newTestRoles(t)
+
+	ringer := &models.Role{Name: "ringer"}
+	require.NoError(t, ringer.SetPermissions([]string{models.PermBellTrigger}))
+	require.NoError(t, roles.Create(ringer))
+
+	request := func(role, permission string) int {
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Set("role", role)
+		}, RequirePermission(roles, permission), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	tests := []struct {
+		role       string
+		permission string
+		want       int
+	}{
+		{models.RoleAdmin, models.PermUsersManage, http.StatusOK},
+		{models.RoleAdmin, models.PermEmergency, http.StatusOK},
+		{models.RoleUser, models.PermBellTrigger, http.StatusOK},
+		{models.RoleUser, models.PermSettingsWrite, http.StatusForbidden},
+		{models.RoleUser, models.PermUsersManage, http.StatusForbidden},
+		{models.RoleUser, models.PermEmergency, http.StatusForbidden}, // Emergency log stays admin-only
+		{models.RoleViewer, models.PermLogsRead, http.StatusForbidden},
+		{"ringer", models.PermBellTrigger, http.StatusOK},
+		{"ringer", models.PermSchedulesWrite, http.StatusForbidden},
+		{"deleted", models.PermBellTrigger, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, request(tt.role, tt.permission), "%s %s", tt.role, tt.permission)
+	}
+
+	// Changing a role applies to the next request
+	require.NoError(t, ringer.SetPermissions(nil))
+	require.NoError(t, roles.Update(ringer, ringer.Name))
+	assert.Equal(t, http.StatusForbidden, request("ringer", models.PermBellTrigger))
+}
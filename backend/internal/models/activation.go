@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Activation statuses, derived from the window and the current time
+const (
+	ActivationPending   = "pending"
+	ActivationRunning   = "running"
+	ActivationCompleted = "completed"
+	ActivationCancelled = "cancelled"
+)
+
+// ActivationLocalFormat is the layout of activation times given without a
+// UTC offset, which are read in the scheduler's timezone
+const ActivationLocalFormat = "2006-01-02T15:04"
+
+// ScheduleActivation runs a schedule in place of the usual one for a window
+// of time, e.g. an assembly schedule one morning. When the window ends the
+// usual schedule resumes without any further change.
+type ScheduleActivation struct {
+	BaseModel
+	ScheduleID    int64      `json:"scheduleId" gorm:"index"`
+	StartsAt      time.Time  `json:"startsAt" gorm:"index"`
+	EndsAt        time.Time  `json:"endsAt" gorm:"index"`
+	Note          string     `json:"note"`
+	CreatedByID   int64      `json:"createdById"`
+	CreatedByName string     `json:"createdByName"`
+	CancelledAt   *time.Time `json:"cancelledAt"`
+	CancelledBy   string     `json:"cancelledBy,omitempty"`
+}
+
+// TableName specifies the table name for ScheduleActivation
+func (ScheduleActivation) TableName() string {
+	return "schedule_activations"
+}
+
+// Covers reports whether the activation is in effect at the given instant
+func (a *ScheduleActivation) Covers(at time.Time) bool {
+	return a.CancelledAt == nil && !at.Before(a.StartsAt) && at.Before(a.EndsAt)
+}
+
+// Overlaps reports whether the windows of two activations share any time
+func (a *ScheduleActivation) Overlaps(other *ScheduleActivation) bool {
+	return a.StartsAt.Before(other.EndsAt) && other.StartsAt.Before(a.EndsAt)
+}
+
+// Status describes where the activation stands at the given time
+func (a *ScheduleActivation) Status(now time.Time) string {
+	switch {
+	case a.CancelledAt != nil:
+		return ActivationCancelled
+	case now.Before(a.StartsAt):
+		return ActivationPending
+	case now.Before(a.EndsAt):
+		return ActivationRunning
+	}
+	return ActivationCompleted
+}
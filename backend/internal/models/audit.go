@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Audit actions. Requests without a more specific action are recorded as
+// their method and route, such as "POST /api/zones/:id/trigger".
+const (
+	AuditAccountLocked   = "account.locked"
+	AuditAccountUnlocked = "account.unlocked"
+	AuditIPBlocked       = "ip.blocked"
+	AuditIPUnblocked     = "ip.unblocked"
+
+	AuditLogin       = "auth.login"
+	AuditLoginFailed = "auth.login_failed"
+
+	AuditScheduleCreated   = "schedule.created"
+	AuditScheduleUpdated   = "schedule.updated"
+	AuditScheduleDeleted   = "schedule.deleted"
+	AuditScheduleActive    = "schedule.active"
+	AuditScheduleDefault   = "schedule.default"
+	AuditScheduleTemporary = "schedule.temporary"
+	AuditScheduleRestored  = "schedule.restored"
+	AuditScheduleImported  = "schedule.imported"
+
+	AuditActivationScheduled = "schedule.activation_scheduled"
+	AuditActivationCancelled = "schedule.activation_cancelled"
+
+	AuditSettingsUpdated = "settings.updated"
+
+	AuditZoneCreated = "zone.created"
+	AuditZoneUpdated = "zone.updated"
+	AuditZoneDeleted = "zone.deleted"
+
+	AuditPatternCreated = "pattern.created"
+	AuditPatternUpdated = "pattern.updated"
+	AuditPatternDeleted = "pattern.deleted"
+
+	AuditCalendarCreated  = "calendar.created"
+	AuditCalendarUpdated  = "calendar.updated"
+	AuditCalendarDeleted  = "calendar.deleted"
+	AuditCalendarImported = "calendar.imported"
+
+	AuditRoleCreated = "role.created"
+	AuditRoleUpdated = "role.updated"
+	AuditRoleDeleted = "role.deleted"
+
+	AuditEmergencyStarted = "emergency.started"
+	AuditEmergencyStopped = "emergency.stopped"
+
+	AuditBackupCreated  = "backup.created"
+	AuditBackupDeleted  = "backup.deleted"
+	AuditBackupRestored = "backup.restored"
+
+	AuditUserCreated         = "user.created"
+	AuditUserUpdated         = "user.updated"
+	AuditUserDeleted         = "user.deleted"
+	AuditUserApproved        = "user.approved"
+	AuditUserRejected        = "user.rejected"
+	AuditUserSessionsRevoked = "user.sessions_revoked"
+	AuditUserTwoFactorReset  = "user.two_factor_reset"
+)
+
+// AuditEntry records a change or security-relevant event, who caused it and
+// from where. ActorID is zero for events raised by anonymous requests, such
+// as a lockout after failed logins.
+type AuditEntry struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	Timestamp  time.Time `json:"timestamp" gorm:"index"`
+	Action     string    `json:"action" gorm:"type:varchar(128);index;not null"`
+	ActorID    int64     `json:"actorId,omitempty" gorm:"index"`
+	ActorName  string    `json:"actorName,omitempty"`
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	Method     string    `json:"method,omitempty" gorm:"type:varchar(8)"`
+	Path       string    `json:"path,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	TargetType string    `json:"targetType,omitempty" gorm:"type:varchar(32);index"`
+	TargetID   int64     `json:"targetId,omitempty"`
+	TargetName string    `json:"targetName,omitempty"`
+	Details    string    `json:"details,omitempty" gorm:"type:text"`
+	Changes    string    `json:"changes,omitempty" gorm:"type:text"` // JSON object of changed fields, each with its before and after value
+}
+
+// TableName specifies the table name for AuditEntry
+func (AuditEntry) TableName() string {
+	return "audit_entries"
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Action     string // Exact action, or a prefix ending in "." such as "schedule."
+	Actor      string // Actor username
+	TargetType string
+	TargetID   int64
+	IPAddress  string
+	From       time.Time
+	To         time.Time
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Calendar override actions
+const (
+	CalendarActionNoBells     = "no_bells"     // Suppress all bells for the covered dates
+	CalendarActionUseSchedule = "use_schedule" // Run the referenced schedule instead of the active one
+	CalendarActionUseDefault  = "use_default"  // Run the default schedule even if another one is active
+)
+
+// CalendarImportSkip maps iCalendar events to no override at all
+const CalendarImportSkip = "skip"
+
+// CalendarImportAny is the mapping category that matches every event
+const CalendarImportAny = "*"
+
+// CalendarDateFormat is the layout used for calendar override dates
+const CalendarDateFormat = "2006-01-02"
+
+// CalendarOverride maps a specific date or date range to a scheduling decision,
+// e.g. a holiday with no bells or an exam week that uses a special schedule
+type CalendarOverride struct {
+	BaseModel
+	Name        string `json:"name"`
+	StartDate   string `json:"startDate" gorm:"index"` // YYYY-MM-DD format
+	EndDate     string `json:"endDate" gorm:"index"`   // YYYY-MM-DD format, inclusive
+	Action      string `json:"action"`                 // "no_bells", "use_schedule" or "use_default"
+	ScheduleID  *int64 `json:"scheduleId,omitempty"`   // Only used with the "use_schedule" action
+	Description string `json:"description"`
+	// SourceUID is the UID of the iCalendar event the override was imported
+	// from, followed by its RECURRENCE-ID for a changed instance of a
+	// recurring event, so importing the calendar again updates it
+	SourceUID string `json:"sourceUid,omitempty" gorm:"index"`
+}
+
+// TableName specifies the table name for CalendarOverride
+func (CalendarOverride) TableName() string {
+	return "calendar_overrides"
+}
+
+// Covers reports whether the override applies to the given date
+func (o *CalendarOverride) Covers(date time.Time) bool {
+	day := date.Format(CalendarDateFormat)
+	return o.StartDate <= day && day <= o.EndDate
+}
+
+// Span returns the number of days covered by the override
+func (o *CalendarOverride) Span() int {
+	start, err := time.Parse(CalendarDateFormat, o.StartDate)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(CalendarDateFormat, o.EndDate)
+	if err != nil {
+		return 0
+	}
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// IsValidCalendarAction checks whether the action is a known calendar override action
+func IsValidCalendarAction(action string) bool {
+	switch action {
+	case CalendarActionNoBells, CalendarActionUseSchedule, CalendarActionUseDefault:
+		return true
+	}
+	return false
+}
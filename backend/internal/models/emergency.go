@@ -0,0 +1,101 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Emergency modes, each signalled with its own pattern
+const (
+	EmergencyLockdown   = "lockdown"   // Repeating short pulses
+	EmergencyEvacuation = "evacuation" // Continuous ring
+)
+
+// Emergency log actions
+const (
+	EmergencyActionStart = "start"
+	EmergencyActionStop  = "stop"
+)
+
+// EmergencyPattern returns the relay pattern for an emergency mode, played
+// in a loop on every zone until the emergency is stopped
+func EmergencyPattern(mode string) []time.Duration {
+	switch mode {
+	case EmergencyLockdown:
+		return []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}
+	case EmergencyEvacuation:
+		return []time.Duration{time.Minute}
+	}
+	return nil
+}
+
+// IsValidEmergencyMode reports whether the mode is a known emergency mode
+func IsValidEmergencyMode(mode string) bool {
+	return EmergencyPattern(mode) != nil
+}
+
+// EmergencyState is the single persisted row describing the current
+// emergency, so that it survives a service restart
+type EmergencyState struct {
+	BaseModel
+	Active        bool       `json:"active"`
+	Mode          string     `json:"mode,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	StartedByID   int64      `json:"startedById,omitempty"`
+	StartedByName string     `json:"startedBy,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	LastHash      string     `json:"-"` // Hash of the newest log entry, to detect a truncated log
+}
+
+// TableName specifies the table name for EmergencyState
+func (EmergencyState) TableName() string {
+	return "emergency_state"
+}
+
+// EmergencyLogEntry records who started or stopped an emergency. Entries
+// form a hash chain: each hash covers the entry and the previous hash, so
+// editing or deleting an entry breaks every hash after it.
+type EmergencyLogEntry struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"` // "start" or "stop"
+	Mode      string    `json:"mode"`
+	UserID    int64     `json:"userId"`
+	Username  string    `json:"username"`
+	Reason    string    `json:"reason,omitempty"`
+	PrevHash  string    `json:"prevHash"`
+	Hash      string    `json:"hash" gorm:"uniqueIndex"`
+}
+
+// TableName specifies the table name for EmergencyLogEntry
+func (EmergencyLogEntry) TableName() string {
+	return "emergency_log"
+}
+
+// ComputeHash returns the chain hash of the entry
+func (e *EmergencyLogEntry) ComputeHash() string {
+	payload := fmt.Sprintf("%d|%s|%s|%s|%d|%s|%s|%s",
+		e.ID, e.Timestamp.UTC().Format(time.RFC3339Nano), e.Action, e.Mode,
+		e.UserID, e.Username, e.Reason, e.PrevHash)
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyEmergencyLog checks the hash chain of log entries in ID order against
+// the last hash recorded in the emergency state. It returns the ID of the
+// first entry that does not match, or 0 if the whole chain is intact.
+func VerifyEmergencyLog(entries []EmergencyLogEntry, lastHash string) (int64, bool) {
+	prevHash := ""
+	for _, entry := range entries {
+		if entry.PrevHash != prevHash || entry.Hash != entry.ComputeHash() {
+			return entry.ID, false
+		}
+		prevHash = entry.Hash
+	}
+	if prevHash != lastHash {
+		return 0, false
+	}
+	return 0, true
+}
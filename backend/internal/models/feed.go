@@ -0,0 +1,51 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Calendar feed horizon in days
+const (
+	DefaultFeedDays = 60
+	MaxFeedDays     = 366
+)
+
+// CalendarFeed publishes the bell times as an iCalendar subscription that
+// calendar clients fetch with the token in the URL, since they cannot log
+// in. A feed can be limited to one schedule or one zone. Only a hash of the
+// token is stored.
+type CalendarFeed struct {
+	BaseModel
+	Name       string     `json:"name"`
+	UserID     int64      `json:"userId" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ScheduleID *int64     `json:"scheduleId"`
+	ZoneID     *int64     `json:"zoneId"`
+	Days       int        `json:"days"` // How far ahead the feed reaches
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// TableName specifies the table name for CalendarFeed
+func (CalendarFeed) TableName() string {
+	return "calendar_feeds"
+}
+
+// NewFeedToken generates a random feed token and the hash stored for it
+func NewFeedToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashFeedToken(token), nil
+}
+
+// HashFeedToken returns the hash stored for a feed token
+func HashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
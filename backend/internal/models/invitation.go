@@ -0,0 +1,52 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// InvitationLifetime is how long an invitation can be accepted
+const InvitationLifetime = 7 * 24 * time.Hour
+
+// Invitation lets the holder of the emailed token register an account with
+// the given role. Only a hash of the token is stored.
+type Invitation struct {
+	BaseModel
+	Email         string     `json:"email" gorm:"type:varchar(255);index;not null"`
+	Role          string     `json:"role" gorm:"type:varchar(50);not null"`
+	TokenHash     string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	InvitedByID   int64      `json:"invitedById"`
+	InvitedByName string     `json:"invitedBy"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	AcceptedAt    *time.Time `json:"acceptedAt"`
+}
+
+// TableName specifies the table name for Invitation
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// NewInvitationToken generates a random invitation token and the hash stored
+// for it
+func NewInvitationToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashInvitationToken(token), nil
+}
+
+// HashInvitationToken returns the hash stored for an invitation token
+func HashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsUsable reports whether the invitation can still be accepted
+func (i *Invitation) IsUsable(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Login limits. The IP and username limits count failed logins within
+// their window; the account lockout counts consecutive failures and doubles
+// with every lockout until a login succeeds.
+const (
+	LoginIPWindow              = 15 * time.Minute
+	MaxFailedLoginsPerIP       = 20
+	LoginUsernameWindow        = time.Hour
+	MaxFailedLoginsPerUsername = 30
+	MaxConsecutiveFailedLogins = 5
+	BaseLockoutDuration        = time.Minute
+	MaxLockoutDuration         = 24 * time.Hour
+)
+
+// RateLimitEntry holds the recent attempts of a rate limiter key, so
+// limits survive a restart
+type RateLimitEntry struct {
+	Key       string    `gorm:"column:bucket_key;type:varchar(255);primaryKey"`
+	Attempts  string    `gorm:"type:text"` // JSON array of attempt times
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for RateLimitEntry
+func (RateLimitEntry) TableName() string {
+	return "rate_limits"
+}
+
+// LockoutDuration returns how long an account is locked for its nth
+// lockout in a row
+func LockoutDuration(lockouts int) time.Duration {
+	d := BaseLockoutDuration
+	for i := 1; i < lockouts && d < MaxLockoutDuration; i++ {
+		d *= 2
+	}
+	if d > MaxLockoutDuration {
+		d = MaxLockoutDuration
+	}
+	return d
+}
+
+// IsLocked reports whether the account is locked out at the given time
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// UnlockIPRequest names an IP address whose failed logins are cleared
+type UnlockIPRequest struct {
+	IPAddress string `json:"ipAddress" binding:"required"`
+}
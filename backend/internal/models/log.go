@@ -6,12 +6,15 @@ import "time"
 type LogEntry struct {
     ID        int64     `json:"id" gorm:"primaryKey"`
     Timestamp time.Time `json:"timestamp"`
-    Trigger   string    `json:"trigger"` // "schedule" or "manual"
+    Trigger   string    `json:"trigger"` // "schedule", "manual", "missed", "suppressed" or "stopped"
     UserID    int64     `json:"userId,omitempty"`
     Username  string    `json:"username,omitempty"`
     ScheduleID int64    `json:"scheduleId,omitempty"`
     ScheduleName string `json:"scheduleName,omitempty"`
     ScheduleTime string `json:"scheduleTime,omitempty"`
+    ZoneID    int64     `json:"zoneId,omitempty"`
+    ZoneName  string    `json:"zoneName,omitempty"`
+    PatternName string  `json:"patternName,omitempty"`
     CreatedAt time.Time `json:"createdAt"`
 }
 
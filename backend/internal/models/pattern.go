@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// MaxPatternLength bounds the total playing time of a ring pattern
+const MaxPatternLength = 2 * time.Minute
+
+// RingPattern is a named sequence of relay on/off periods, e.g. three short
+// rings for a fire drill
+type RingPattern struct {
+	BaseModel
+	Name        string `json:"name" gorm:"uniqueIndex"`
+	Description string `json:"description"`
+	Steps       string `json:"steps" gorm:"type:text"` // JSON array of milliseconds, alternating on and off, starting with on
+}
+
+// TableName specifies the table name for RingPattern
+func (RingPattern) TableName() string {
+	return "ring_patterns"
+}
+
+// Durations parses and validates the steps of the pattern
+func (p *RingPattern) Durations() ([]time.Duration, error) {
+	var steps []int
+	if err := json.Unmarshal([]byte(p.Steps), &steps); err != nil {
+		return nil, err
+	}
+	return PatternDurations(steps)
+}
+
+// PatternDurations converts steps in milliseconds to durations. A pattern
+// needs at least one on period and must not play longer than MaxPatternLength.
+func PatternDurations(steps []int) ([]time.Duration, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("pattern needs at least one step")
+	}
+
+	var total time.Duration
+	durations := make([]time.Duration, len(steps))
+	for i, step := range steps {
+		if step <= 0 {
+			return nil, errors.New("pattern steps must be positive")
+		}
+		durations[i] = time.Duration(step) * time.Millisecond
+		total += durations[i]
+	}
+	if total > MaxPatternLength {
+		return nil, errors.New("pattern is longer than two minutes")
+	}
+	return durations, nil
+}
+
+// DefaultRingPatterns returns the pattern library seeded on first boot
+func DefaultRingPatterns() []RingPattern {
+	return []RingPattern{
+		{Name: "Fire drill", Description: "Three short rings", Steps: "[1000,500,1000,500,1000]"},
+		{Name: "End of day", Description: "One long ring", Steps: "[10000]"},
+		{Name: "Double", Description: "Two rings", Steps: "[2000,1000,2000]"},
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bell_scheduler/pkg/rrule"
+)
+
+// MaxWeekInterval bounds the week interval of a time slot
+const MaxWeekInterval = 52
+
+// Anchor returns the date the week interval and rule of the time slot count
+// from: AnchorDate, else StartDate, else the zero time
+func (ts *TimeSlot) Anchor() (time.Time, error) {
+	anchor := ts.AnchorDate
+	if anchor == "" {
+		anchor = ts.StartDate
+	}
+	if anchor == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(CalendarDateFormat, anchor)
+}
+
+// ValidateRecurrence checks the validity window, week interval and rule of
+// the time slot
+func (ts *TimeSlot) ValidateRecurrence() error {
+	for _, date := range []struct{ name, value string }{
+		{"startDate", ts.StartDate}, {"endDate", ts.EndDate}, {"anchorDate", ts.AnchorDate},
+	} {
+		if date.value == "" {
+			continue
+		}
+		if _, err := time.Parse(CalendarDateFormat, date.value); err != nil {
+			return fmt.Errorf("%s must be in YYYY-MM-DD format", date.name)
+		}
+	}
+	if ts.StartDate != "" && ts.EndDate != "" && ts.EndDate < ts.StartDate {
+		return errors.New("endDate must not be before startDate")
+	}
+
+	if ts.WeekInterval < 0 || ts.WeekInterval > MaxWeekInterval {
+		return fmt.Errorf("weekInterval must be between 1 and %d", MaxWeekInterval)
+	}
+	anchor, _ := ts.Anchor()
+	if ts.WeekInterval > 1 && anchor.IsZero() {
+		return errors.New("weekInterval needs an anchorDate or startDate")
+	}
+
+	if ts.RRule == "" {
+		return nil
+	}
+	if ts.WeekInterval > 1 {
+		return errors.New("use INTERVAL in the rrule instead of weekInterval")
+	}
+	rule, err := rrule.Parse(ts.RRule)
+	if err != nil {
+		return fmt.Errorf("invalid rrule: %w", err)
+	}
+	if rule.NeedsStart() && anchor.IsZero() {
+		return errors.New("the rrule needs an anchorDate or startDate")
+	}
+	return nil
+}
@@ -8,8 +8,24 @@ type LoginRequest struct {
 
 // LoginResponse represents a login response
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string   `json:"token"`
+	RefreshToken string   `json:"refreshToken"`
+	ExpiresIn    int      `json:"expiresIn"`
+	User         User     `json:"user"`
+	Permissions  []string `json:"permissions"`
+}
+
+// RefreshRequest carries a refresh token, to rotate it or to log out
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// SetupRequest creates the first administrator of a new installation
+type SetupRequest struct {
+	SetupCode string `json:"setupCode" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required,min=8"`
 }
 
 // RegisterRequest represents a registration request
@@ -17,7 +33,8 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
-	Role     string `json:"role" binding:"required,oneof=user admin"`
+	// InviteToken is required when registration is invite-only
+	InviteToken string `json:"inviteToken"`
 }
 
 // ForgotPasswordRequest represents a password reset request
@@ -55,9 +72,134 @@ type UpdateScheduleRequest struct {
 	TimeSlots   []TimeSlot `json:"timeSlots" binding:"required,dive"`
 }
 
+// ScheduleImportRequest represents a request to import a schedule file
+type ScheduleImportRequest struct {
+	Format     string `json:"format" binding:"required,oneof=json yaml csv"`
+	Data       string `json:"data" binding:"required"`                                    // The file's content
+	OnConflict string `json:"onConflict" binding:"omitempty,oneof=skip overwrite rename"` // Defaults to skip
+	DryRun     bool   `json:"dryRun"`                                                     // Only return what would be imported
+}
+
+// BackupRequest represents a request to create or restore a backup
+type BackupRequest struct {
+	Passphrase string `json:"passphrase"` // Defaults to BACKUP_PASSPHRASE
+}
+
 // UpdateSettingsRequest represents a settings update request
 type UpdateSettingsRequest struct {
 	RingDuration int    `json:"ringDuration" binding:"required,min=1,max=60"`
 	GPIOPin      int    `json:"gpioPin" binding:"required,min=1,max=40"`
 	Timezone     string `json:"timezone" binding:"required"`
+	// Optional, existing values are kept when omitted
+	OutputDriver  string `json:"outputDriver" binding:"omitempty,oneof=rpio gpiochip sysfs mock none"`
+	GPIOChip      string `json:"gpioChip"`
+	CatchUpPolicy string `json:"catchUpPolicy" binding:"omitempty,oneof=fire_late log_missed"`
+	CatchUpGrace  *int   `json:"catchUpGrace" binding:"omitempty,min=0,max=3600"` // seconds
+	OverlapPolicy string `json:"overlapPolicy" binding:"omitempty,oneof=reject queue extend"`
+	// RegistrationPolicy is optional, the existing value is kept when omitted
+	RegistrationPolicy    string `json:"registrationPolicy" binding:"omitempty,oneof=disabled invite approval"`
+	RequireAdminTwoFactor *bool  `json:"requireAdminTwoFactor"`
+}
+
+// CalendarOverrideRequest represents a calendar override create or update request
+type CalendarOverrideRequest struct {
+	Name        string `json:"name" binding:"required"`
+	StartDate   string `json:"startDate" binding:"required"`
+	EndDate     string `json:"endDate"`
+	Action      string `json:"action" binding:"required,oneof=no_bells use_schedule use_default"`
+	ScheduleID  *int64 `json:"scheduleId"`
+	Description string `json:"description"`
+}
+
+// CalendarImportMapping maps iCalendar events with a category to a
+// calendar override action
+type CalendarImportMapping struct {
+	Category   string `json:"category" binding:"required"` // Matched against CATEGORIES, then SUMMARY; "*" matches every event
+	Action     string `json:"action" binding:"required,oneof=no_bells use_schedule use_default skip"`
+	ScheduleID *int64 `json:"scheduleId"` // Only used with the "use_schedule" action
+}
+
+// CalendarImportRequest represents a request to import the events of an
+// iCalendar document as calendar overrides
+type CalendarImportRequest struct {
+	Calendar string                  `json:"calendar" binding:"required"` // The .ics document
+	Mappings []CalendarImportMapping `json:"mappings" binding:"required,min=1,dive"`
+	From     string                  `json:"from"` // Optional YYYY-MM-DD, events ending earlier are skipped
+	To       string                  `json:"to"`   // Optional YYYY-MM-DD, events starting later are skipped
+}
+
+// ScheduleActivationRequest represents a request to run a schedule for a
+// window of time. Times are RFC 3339, or YYYY-MM-DDTHH:MM in the scheduler's
+// timezone.
+type ScheduleActivationRequest struct {
+	ScheduleID int64  `json:"scheduleId" binding:"required"`
+	StartsAt   string `json:"startsAt" binding:"required"`
+	EndsAt     string `json:"endsAt" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// CalendarFeedRequest represents a calendar feed creation request
+type CalendarFeedRequest struct {
+	Name       string `json:"name" binding:"required"`
+	ScheduleID *int64 `json:"scheduleId"`
+	ZoneID     *int64 `json:"zoneId"`
+	Days       int    `json:"days" binding:"omitempty,min=1,max=366"`
+}
+
+// ZoneRequest represents a zone create or update request
+type ZoneRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	OutputDriver string `json:"outputDriver" binding:"required,oneof=rpio gpiochip sysfs mock none"`
+	GPIOChip     string `json:"gpioChip"`
+	GPIOPin      int    `json:"gpioPin" binding:"min=0"`
+	RingDuration int    `json:"ringDuration" binding:"min=0,max=60"`
+	IsDefault    bool   `json:"isDefault"`
+}
+
+// TriggerRequest represents an optional manual trigger request body
+type TriggerRequest struct {
+	ZoneIDs   []int64 `json:"zoneIds"`   // Empty rings every zone
+	PatternID *int64  `json:"patternId"` // Nil rings once for the zone's ring duration
+}
+
+// StopRequest represents an optional request body to stop ringing bells
+type StopRequest struct {
+	ZoneIDs []int64 `json:"zoneIds"` // Empty stops every zone
+}
+
+// RingPatternRequest represents a ring pattern create or update request
+type RingPatternRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Steps       []int  `json:"steps" binding:"required,min=1,max=32,dive,min=50,max=60000"` // Milliseconds, alternating on and off
+}
+
+// StartEmergencyRequest represents a request to start emergency signalling
+type StartEmergencyRequest struct {
+	Mode   string `json:"mode" binding:"required,oneof=lockdown evacuation"`
+	Reason string `json:"reason"`
+}
+
+// StopEmergencyRequest represents a request to stop emergency signalling
+type StopEmergencyRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RoleRequest represents a role create or update request
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"dive,required"`
+}
+
+// InvitationRequest represents an invitation request
+type InvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
+}
+
+// ApproveUserRequest represents an account approval request
+type ApproveUserRequest struct {
+	Role string `json:"role"`
 }
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// ScheduleRevision is an immutable copy of what a schedule contained after a
+// save. Revisions are numbered from 1 for each schedule. The default,
+// temporary and active flags are not part of a revision, so restoring one
+// does not change which schedule is in use.
+type ScheduleRevision struct {
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	ScheduleID  int64     `json:"scheduleId" gorm:"not null;uniqueIndex:idx_schedule_revision"`
+	Number      int       `json:"number" gorm:"not null;uniqueIndex:idx_schedule_revision"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TimeSlots   string    `json:"-" gorm:"type:text"` // JSON array of RevisionSlot
+	AuthorID    int64     `json:"authorId,omitempty"`
+	AuthorName  string    `json:"authorName,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// TableName specifies the table name for ScheduleRevision
+func (ScheduleRevision) TableName() string {
+	return "schedule_revisions"
+}
+
+// RevisionSlot is a time slot as stored in a revision, without the IDs
+// that change with every save. Recurrence fields are left out when unset,
+// so revisions saved before they existed compare equal.
+type RevisionSlot struct {
+	TriggerTime  string `json:"triggerTime"`
+	Days         string `json:"days"`
+	Zones        string `json:"zones"`
+	PatternID    *int64 `json:"patternId"`
+	Description  string `json:"description"`
+	StartDate    string `json:"startDate,omitempty"`
+	EndDate      string `json:"endDate,omitempty"`
+	WeekInterval int    `json:"weekInterval,omitempty"`
+	AnchorDate   string `json:"anchorDate,omitempty"`
+	RRule        string `json:"rrule,omitempty"`
+}
+
+// RevisionMeta describes who saved a schedule and why. The zero value is a
+// save by the system.
+type RevisionMeta struct {
+	AuthorID   int64
+	AuthorName string
+	Note       string
+}
+
+// NewScheduleRevision captures the content of a schedule. The caller sets
+// the number and author.
+func NewScheduleRevision(schedule *Schedule) (*ScheduleRevision, error) {
+	slots := make([]RevisionSlot, 0, len(schedule.TimeSlots))
+	for _, slot := range schedule.TimeSlots {
+		slots = append(slots, RevisionSlot{
+			TriggerTime:  slot.TriggerTime,
+			Days:         slot.Days,
+			Zones:        slot.Zones,
+			PatternID:    slot.PatternID,
+			Description:  slot.Description,
+			StartDate:    slot.StartDate,
+			EndDate:      slot.EndDate,
+			WeekInterval: slot.WeekInterval,
+			AnchorDate:   slot.AnchorDate,
+			RRule:        slot.RRule,
+		})
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].TriggerTime < slots[j].TriggerTime
+	})
+
+	data, err := json.Marshal(slots)
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduleRevision{
+		ScheduleID:  schedule.ID,
+		Name:        schedule.Name,
+		Description: schedule.Description,
+		TimeSlots:   string(data),
+	}, nil
+}
+
+// Slots parses the time slots of the revision
+func (r *ScheduleRevision) Slots() ([]RevisionSlot, error) {
+	var slots []RevisionSlot
+	if r.TimeSlots == "" {
+		return slots, nil
+	}
+	if err := json.Unmarshal([]byte(r.TimeSlots), &slots); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// SameContent reports whether two revisions hold the same schedule
+func (r *ScheduleRevision) SameContent(other *ScheduleRevision) bool {
+	return r.Name == other.Name && r.Description == other.Description && r.TimeSlots == other.TimeSlots
+}
+
+// ScheduleTimeSlots turns the slots of the revision back into new time
+// slots of its schedule
+func (r *ScheduleRevision) ScheduleTimeSlots() ([]TimeSlot, error) {
+	slots, err := r.Slots()
+	if err != nil {
+		return nil, err
+	}
+	timeSlots := make([]TimeSlot, 0, len(slots))
+	for _, slot := range slots {
+		timeSlots = append(timeSlots, TimeSlot{
+			ScheduleID:   r.ScheduleID,
+			TriggerTime:  slot.TriggerTime,
+			Days:         slot.Days,
+			Zones:        slot.Zones,
+			PatternID:    slot.PatternID,
+			Description:  slot.Description,
+			StartDate:    slot.StartDate,
+			EndDate:      slot.EndDate,
+			WeekInterval: slot.WeekInterval,
+			AnchorDate:   slot.AnchorDate,
+			RRule:        slot.RRule,
+		})
+	}
+	return timeSlots, nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Permissions granted to roles
+const (
+	PermSchedulesWrite = "schedules:write" // Schedules, time slots, calendar overrides and ring patterns
+	PermBellTrigger    = "bell:trigger"    // Ring and stop the bell manually
+	PermUsersManage    = "users:manage"    // Users and roles
+	PermSettingsWrite  = "settings:write"  // Settings and bell zones
+	PermLogsRead       = "logs:read"       // Bell logs
+	PermEmergency      = "emergency:signal"
+	PermAuditRead      = "audit:read"     // Audit log of changes and logins
+	PermBackupsManage  = "backups:manage" // Create, download and restore backups of all data
+)
+
+// AllPermissions lists every permission a role can hold
+var AllPermissions = []string{
+	PermSchedulesWrite,
+	PermBellTrigger,
+	PermUsersManage,
+	PermSettingsWrite,
+	PermLogsRead,
+	PermEmergency,
+	PermAuditRead,
+	PermBackupsManage,
+}
+
+// Built-in role names
+const (
+	RoleAdmin  = "admin"
+	RoleUser   = "user"
+	RoleViewer = "viewer"
+)
+
+// Role is a named set of permissions assigned to users by name
+type Role struct {
+	BaseModel
+	Name        string `json:"name" gorm:"uniqueIndex;not null"`
+	Description string `json:"description"`
+	Permissions string `json:"permissions" gorm:"type:text"` // JSON array of permission names
+	IsBuiltin   bool   `json:"isBuiltin" gorm:"default:false"`
+}
+
+// TableName specifies the table name for Role
+func (Role) TableName() string {
+	return "roles"
+}
+
+// PermissionList parses the permissions of the role. The admin role always
+// holds every permission, so it cannot be locked out.
+func (r *Role) PermissionList() ([]string, error) {
+	if r.Name == RoleAdmin {
+		return AllPermissions, nil
+	}
+	var permissions []string
+	if r.Permissions == "" {
+		return permissions, nil
+	}
+	if err := json.Unmarshal([]byte(r.Permissions), &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// HasPermission reports whether the role grants the permission
+func (r *Role) HasPermission(permission string) bool {
+	permissions, err := r.PermissionList()
+	if err != nil {
+		return false
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPermissions validates and stores the permissions of the role
+func (r *Role) SetPermissions(permissions []string) error {
+	for _, p := range permissions {
+		if !IsValidPermission(p) {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	data, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+	r.Permissions = string(data)
+	return nil
+}
+
+// IsValidPermission reports whether the permission is known
+func IsValidPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles returns the built-in roles seeded on first boot
+func DefaultRoles() []Role {
+	roles := []struct {
+		name, description string
+		permissions       []string
+	}{
+		{RoleAdmin, "Full access", AllPermissions},
+		{RoleUser, "Manage schedules and ring the bell", []string{PermSchedulesWrite, PermBellTrigger, PermLogsRead}},
+		{RoleViewer, "Read-only access, e.g. for display boards", []string{}},
+	}
+
+	defaults := make([]Role, 0, len(roles))
+	for _, r := range roles {
+		role := Role{Name: r.name, Description: r.description, IsBuiltin: true}
+		role.SetPermissions(r.permissions)
+		defaults = append(defaults, role)
+	}
+	return defaults
+}
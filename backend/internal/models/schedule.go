@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+
 	"gorm.io/gorm"
 )
 
@@ -17,9 +19,17 @@ type Schedule struct {
 type TimeSlot struct {
 	BaseModel
 	ScheduleID  int64  `json:"scheduleId" gorm:"index"`
-	TriggerTime string `json:"triggerTime"`           // HH:MM format
-	Days        string `json:"days" gorm:"type:text"` // JSON array of days
+	TriggerTime string `json:"triggerTime"`            // HH:MM format
+	Days        string `json:"days" gorm:"type:text"`  // JSON array of days
+	Zones       string `json:"zones" gorm:"type:text"` // JSON array of zone IDs, empty rings every zone
+	PatternID   *int64 `json:"patternId"`              // Ring pattern, nil rings once for the zone's ring duration
 	Description string `json:"description"`
+	// Optional recurrence, see recurrence.go
+	StartDate    string `json:"startDate"`    // YYYY-MM-DD, first date the slot runs
+	EndDate      string `json:"endDate"`      // YYYY-MM-DD, last date the slot runs
+	WeekInterval int    `json:"weekInterval"` // Run every Nth week, 0 or 1 for every week
+	AnchorDate   string `json:"anchorDate"`   // YYYY-MM-DD in a week the slot runs, defaults to StartDate
+	RRule        string `json:"rrule"`        // RFC 5545 rule selecting the dates instead of Days
 }
 
 // BeforeCreate converts the Days array to JSON string
@@ -27,6 +37,9 @@ func (ts *TimeSlot) BeforeCreate(tx *gorm.DB) error {
 	if ts.Days == "" {
 		ts.Days = "[]"
 	}
+	if ts.Zones == "" {
+		ts.Zones = "[]"
+	}
 	return nil
 }
 
@@ -35,5 +48,20 @@ func (ts *TimeSlot) AfterFind(tx *gorm.DB) error {
 	if ts.Days == "" {
 		ts.Days = "[]"
 	}
+	if ts.Zones == "" {
+		ts.Zones = "[]"
+	}
 	return nil
 }
+
+// ZoneIDs returns the zones targeted by the time slot, empty meaning all zones
+func (ts *TimeSlot) ZoneIDs() ([]int64, error) {
+	if ts.Zones == "" {
+		return nil, nil
+	}
+	var ids []int64
+	if err := json.Unmarshal([]byte(ts.Zones), &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Token lifetimes
+const (
+	AccessTokenLifetime  = 15 * time.Minute
+	RefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// Session is a signed-in device. Access tokens name the session they were
+// issued for, so revoking it signs the device out. The refresh token rotates
+// on every use and only hashes are stored.
+type Session struct {
+	BaseModel
+	UserID    int64  `json:"userId" gorm:"index;not null"`
+	TokenHash string `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	// PreviousHash is the refresh token replaced by the last rotation.
+	// Presenting it again means the token was copied, so the session is revoked.
+	PreviousHash string     `json:"-" gorm:"type:varchar(64);index"`
+	UserAgent    string     `json:"userAgent"`
+	IPAddress    string     `json:"ipAddress"`
+	LastUsedAt   time.Time  `json:"lastUsedAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// NewRefreshToken generates a random refresh token and the hash stored for it
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash stored for a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,19 +2,69 @@ package models
 
 import "time"
 
+// Catch-up policies for triggers skipped by a stall or clock jump
+const (
+	CatchUpFireLate  = "fire_late"  // Ring late as long as the grace window has not passed
+	CatchUpLogMissed = "log_missed" // Never ring late, only record the missed trigger
+)
+
+// Overlap policies for a trigger that arrives while the bell is ringing
+const (
+	OverlapReject = "reject" // Refuse the new ring
+	OverlapQueue  = "queue"  // Play the new ring after the current one
+	OverlapExtend = "extend" // Replace the current ring, keeping the relay on
+)
+
+// Output drivers for the bell relay
+const (
+	OutputDriverRPIO     = "rpio"     // Raspberry Pi memory-mapped GPIO
+	OutputDriverGPIOChip = "gpiochip" // Linux GPIO character device (/dev/gpiochipN)
+	OutputDriverSysfs    = "sysfs"    // Legacy /sys/class/gpio interface
+	OutputDriverMock     = "mock"     // Records state changes in memory
+	OutputDriverNone     = "none"     // Does nothing
+)
+
+// Registration policies for the public registration endpoint
+const (
+	RegistrationDisabled = "disabled" // Only administrators create accounts
+	RegistrationInvite   = "invite"   // Accounts need an invitation token
+	RegistrationApproval = "approval" // Anyone may register; an administrator approves the account
+)
+
+// DefaultCatchUpGrace is how late a skipped trigger may still ring by default
+const DefaultCatchUpGrace = 2 * time.Minute
+
 // Settings represents the application settings
 type Settings struct {
 	BaseModel
-	RingDuration time.Duration `json:"ringDuration"`
-	GPIOPin      int           `json:"gpioPin"`
-	Timezone     string        `json:"timezone"`
+	RingDuration  time.Duration `json:"ringDuration"`
+	GPIOPin       int           `json:"gpioPin"`
+	OutputDriver  string        `json:"outputDriver" gorm:"default:rpio"`
+	GPIOChip      string        `json:"gpioChip" gorm:"default:gpiochip0"` // Only used by the gpiochip driver
+	Timezone      string        `json:"timezone"`
+	CatchUpPolicy string        `json:"catchUpPolicy" gorm:"default:fire_late"`
+	CatchUpGrace  time.Duration `json:"catchUpGrace" gorm:"default:120000000000"`
+	OverlapPolicy string        `json:"overlapPolicy" gorm:"default:reject"`
+	// RegistrationPolicy controls self-registration; invitations are
+	// accepted under every policy but disabled
+	RegistrationPolicy string `json:"registrationPolicy" gorm:"default:disabled"`
+	// RequireAdminTwoFactor makes administrators enrol in two-factor
+	// authentication before they can do anything else
+	RequireAdminTwoFactor bool `json:"requireAdminTwoFactor" gorm:"not null;default:false"`
 }
 
 // DefaultSettings returns the default application settings
 func DefaultSettings() *Settings {
 	return &Settings{
-		RingDuration: 5 * time.Second,
-		GPIOPin:      17, // Default to GPIO17
-		Timezone:     "UTC",
+		RingDuration:  5 * time.Second,
+		GPIOPin:       17, // Default to GPIO17
+		OutputDriver:  OutputDriverRPIO,
+		GPIOChip:      "gpiochip0",
+		Timezone:      "UTC",
+		CatchUpPolicy: CatchUpFireLate,
+		CatchUpGrace:  DefaultCatchUpGrace,
+		OverlapPolicy: OverlapReject,
+
+		RegistrationPolicy: RegistrationDisabled,
 	}
 }
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes are issued at a time
+	RecoveryCodeCount = 10
+	// TwoFactorChallengeLifetime is how long the second login step may take
+	TwoFactorChallengeLifetime = 5 * time.Minute
+	// TwoFactorIssuer names the service in authenticator apps
+	TwoFactorIssuer = "Bell Scheduler"
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes generates recovery codes and returns them with the
+// JSON array of hashes stored on the user
+func NewRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = HashRecoveryCode(code)
+	}
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(data), nil
+}
+
+// HashRecoveryCode returns the hash stored for a recovery code. Case,
+// dashes and spaces are ignored.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecoveryCodeHashes returns the hashes of the user's unused recovery codes
+func (u *User) RecoveryCodeHashes() []string {
+	var hashes []string
+	if u.RecoveryCodes == "" {
+		return hashes
+	}
+	if err := json.Unmarshal([]byte(u.RecoveryCodes), &hashes); err != nil {
+		return nil
+	}
+	return hashes
+}
+
+// TwoFactorCodeRequest carries a code from an authenticator app, or a
+// recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest is the second step of a login with two-factor
+// authentication
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest turns two-factor authentication off
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
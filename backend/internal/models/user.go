@@ -20,6 +20,20 @@ type User struct {
 	ResetToken          string `gorm:"type:varchar(255)"`
 	ResetTokenExpiry    *time.Time
 	ForcePasswordChange bool `gorm:"type:boolean;default:false"`
+	// PendingApproval is set for self-registered accounts until an
+	// administrator approves them
+	PendingApproval bool `json:"pendingApproval" gorm:"not null;default:false;index"`
+	// Two-factor authentication. The secret is stored while enrolment is
+	// in progress and TOTPEnabled is set once a code has been confirmed.
+	TOTPSecret    string `json:"-" gorm:"type:varchar(64)"`
+	TOTPEnabled   bool   `json:"totpEnabled" gorm:"not null;default:false"`
+	TOTPLastStep  int64  `json:"-" gorm:"not null;default:0"`
+	RecoveryCodes string `json:"-" gorm:"type:text"` // JSON array of recovery code hashes
+	// Brute-force protection. FailedLogins counts consecutive failures and
+	// Lockouts the lockouts since the last successful login.
+	FailedLogins int        `json:"failedLogins" gorm:"not null;default:0"`
+	Lockouts     int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil  *time.Time `json:"lockedUntil"`
 }
 
 // HashPassword hashes the user's password using bcrypt
@@ -103,5 +117,5 @@ type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
-	Role     string `json:"role" binding:"omitempty,oneof=admin user"`
+	Role     string `json:"role"`
 }
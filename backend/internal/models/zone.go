@@ -0,0 +1,31 @@
+package models
+
+// Zone is an independently wired group of bells, e.g. a building or the gym,
+// with its own output pin and ring duration
+type Zone struct {
+	BaseModel
+	Name         string `json:"name" gorm:"uniqueIndex"`
+	Description  string `json:"description"`
+	OutputDriver string `json:"outputDriver"` // "rpio", "gpiochip", "sysfs", "mock" or "none"
+	GPIOChip     string `json:"gpioChip"`     // Only used by the gpiochip driver
+	GPIOPin      int    `json:"gpioPin"`
+	RingDuration int    `json:"ringDuration"` // Seconds, 0 uses the global ring duration
+	IsDefault    bool   `json:"isDefault"`    // The default zone mirrors the output settings
+}
+
+// TableName specifies the table name for Zone
+func (Zone) TableName() string {
+	return "zones"
+}
+
+// DefaultZone returns the zone seeded from the output settings on first boot
+func DefaultZone(settings *Settings) *Zone {
+	return &Zone{
+		Name:         "Main",
+		Description:  "Default bell zone",
+		OutputDriver: settings.OutputDriver,
+		GPIOChip:     settings.GPIOChip,
+		GPIOPin:      settings.GPIOPin,
+		IsDefault:    true,
+	}
+}
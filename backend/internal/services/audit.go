@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+)
+
+// Actor identifies who performed an audited action. Anonymous requests
+// have no ID.
+type Actor struct {
+	ID        int64
+	Name      string
+	IPAddress string
+}
+
+// AuditService records audit entries. Failing to record one is logged
+// rather than failing the action it describes.
+type AuditService struct {
+	repo  *store.AuditRepository
+	clock Clock
+}
+
+// NewAuditService creates a new audit service instance
+func NewAuditService(repo *store.AuditRepository, clock Clock) *AuditService {
+	return &AuditService{
+		repo:  repo,
+		clock: clock,
+	}
+}
+
+// Record stores an entry performed by the actor
+func (s *AuditService) Record(actor Actor, entry models.AuditEntry) {
+	entry.Timestamp = s.clock.Now()
+	entry.ActorID = actor.ID
+	entry.ActorName = actor.Name
+	entry.IPAddress = actor.IPAddress
+	if err := s.repo.Create(&entry); err != nil {
+		fmt.Printf("Failed to record audit entry %s: %v\n", entry.Action, err)
+	}
+}
+
+// Fields the database maintains, which are left out of audit diffs
+var unauditedFields = map[string]bool{
+	"id":        true,
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// Fields whose values are never written to the audit log
+var redactedFields = map[string]bool{
+	"password":         true,
+	"ResetToken":       true,
+	"ResetTokenExpiry": true,
+}
+
+// Snapshot captures the JSON fields of a value, so a handler can record
+// what it looked like before changing it. Nil gives a nil snapshot.
+func Snapshot(v interface{}) map[string]interface{} {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return stripUnaudited(fields).(map[string]interface{})
+}
+
+// stripUnaudited removes the unaudited fields from nested values too, so
+// saving time slots unchanged does not show as a change
+func stripUnaudited(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if unauditedFields[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripUnaudited(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stripUnaudited(value)
+		}
+	}
+	return v
+}
+
+// AuditChanges returns the fields that differ between two snapshots as a
+// JSON object, each with its before and after value. A nil snapshot stands
+// for a record that did not exist, so every field of the other is listed.
+// It returns an empty string when nothing changed.
+func AuditChanges(before, after map[string]interface{}) string {
+	changes := make(map[string]map[string]interface{})
+	diff := func(key string) {
+		if _, done := changes[key]; done {
+			return
+		}
+		b, inBefore := before[key]
+		a, inAfter := after[key]
+		if inBefore && inAfter && reflect.DeepEqual(a, b) {
+			return
+		}
+		change := make(map[string]interface{})
+		if before != nil {
+			change["before"] = redact(key, b)
+		}
+		if after != nil {
+			change["after"] = redact(key, a)
+		}
+		changes[key] = change
+	}
+	for key := range before {
+		diff(key)
+	}
+	for key := range after {
+		diff(key)
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func redact(key string, value interface{}) interface{} {
+	if redactedFields[key] && value != nil && value != "" {
+		return "[redacted]"
+	}
+	return value
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditChanges(t *testing.T) {
+	schedule := &models.Schedule{
+		Name:      "Normal day",
+		TimeSlots: []models.TimeSlot{{TriggerTime: "08:30"}},
+	}
+	schedule.ID = 4
+	before := Snapshot(schedule)
+
+	schedule.Name = "Short day"
+	schedule.UpdatedAt = schedule.UpdatedAt.Add(1)
+	schedule.TimeSlots[0].UpdatedAt = schedule.TimeSlots[0].UpdatedAt.Add(1)
+
+	var changes map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(AuditChanges(before, Snapshot(schedule))), &changes))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"name": {"before": "Normal day", "after": "Short day"},
+	}, changes)
+
+	assert.Empty(t, AuditChanges(Snapshot(schedule), Snapshot(schedule)))
+}
+
+func TestAuditChanges_CreatedAndDeleted(t *testing.T) {
+	zone := &models.Zone{Name: "Hall"}
+
+	var created map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(AuditChanges(nil, Snapshot(zone))), &created))
+	assert.Equal(t, map[string]interface{}{"after": "Hall"}, created["name"])
+
+	var deleted map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(AuditChanges(Snapshot(zone), nil)), &deleted))
+	assert.Equal(t, map[string]interface{}{"before": "Hall"}, deleted["name"])
+}
+
+func TestAuditChanges_RedactsPasswords(t *testing.T) {
+	user := &models.User{Username: "teacher", Password: "$2a$10$old"}
+	before := Snapshot(user)
+	user.Password = "$2a$10$new"
+
+	changes := AuditChanges(before, Snapshot(user))
+	assert.NotContains(t, changes, "$2a$")
+	assert.Contains(t, changes, `"password":{"after":"[redacted]","before":"[redacted]"}`)
+}
@@ -0,0 +1,713 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Why a backup was made, which is part of its file name
+const (
+	BackupManual     = "manual"
+	BackupScheduled  = "scheduled"
+	BackupPreRestore = "pre-restore" // Made by a restore before it replaces the data
+)
+
+// File extensions of plain and encrypted backups
+const (
+	BackupExtension          = ".db"
+	EncryptedBackupExtension = ".db.enc"
+)
+
+const (
+	backupPrefix     = "bell_scheduler-"
+	backupTimeFormat = "20060102-150405"
+	// An encrypted backup starts with backupMagic, then the scrypt salt and
+	// the AES-GCM nonce, followed by the sealed database
+	backupMagic    = "BELLBAK1"
+	backupSaltSize = 16
+	sqliteHeader   = "SQLite format 3\x00"
+)
+
+// backupNamePattern matches the names of backups. Backups made in the same
+// second are told apart by a number.
+var backupNamePattern = regexp.MustCompile(`^bell_scheduler-(\d{8}-\d{6})-(manual|scheduled|pre-restore)(?:-(\d+))?\.db(\.enc)?$`)
+
+var (
+	// restoreKeepTables are left as they are by a restore. The audit log and
+	// the emergency records describe what happened and are not rewound,
+	// and lockouts stay in force.
+	restoreKeepTables = map[string]bool{
+		"audit_entries":   true,
+		"emergency_state": true,
+		"emergency_log":   true,
+		"rate_limits":     true,
+	}
+	// restoreClearTables are emptied by a restore. The user IDs of sessions
+	// may belong to someone else in the backup, so everyone signs in again.
+	restoreClearTables = map[string]bool{
+		"sessions": true,
+	}
+	// backupRequiredTables must exist for a file to be taken as a backup
+	backupRequiredTables = []string{"users", "roles", "settings", "schedules", "time_slots"}
+)
+
+var (
+	// ErrBackupNotFound is returned for a backup name that is not stored
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrInvalidBackup is returned for a file that is not a usable backup
+	ErrInvalidBackup = errors.New("the file is not a bell scheduler backup")
+	// ErrPassphraseRequired is returned for an encrypted backup without a passphrase
+	ErrPassphraseRequired = errors.New("the backup is encrypted and needs a passphrase")
+	// ErrWrongPassphrase is returned when an encrypted backup cannot be opened
+	ErrWrongPassphrase = errors.New("the passphrase is wrong or the backup is damaged")
+	// ErrRestoreDuringEmergency is returned when restoring while an
+	// emergency is active, which the restore could otherwise end unnoticed
+	ErrRestoreDuringEmergency = errors.New("a backup cannot be restored during an emergency")
+)
+
+// BackupInfo describes a stored backup
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+	Encrypted bool      `json:"encrypted"`
+
+	sequence int
+}
+
+// BackupContents summarises what a backup holds
+type BackupContents struct {
+	Tables     int   `json:"tables"`
+	Users      int64 `json:"users"`
+	Schedules  int64 `json:"schedules"`
+	LogEntries int64 `json:"logEntries"`
+}
+
+// BackupRestore is the result of a restore
+type BackupRestore struct {
+	Contents BackupContents `json:"contents"`
+	// Previous is the backup of the data the restore replaced
+	Previous BackupInfo `json:"previous"`
+}
+
+// BackupStatus describes how backups are made
+type BackupStatus struct {
+	Interval  int    `json:"interval"`  // Seconds between automatic backups, 0 when they are off
+	Keep      int    `json:"keep"`      // Automatic backups kept
+	Encrypted bool   `json:"encrypted"` // Whether backups are encrypted by default
+	LastError string `json:"lastError,omitempty"`
+}
+
+// BackupService makes consistent copies of the database while the server
+// runs, stores them in a directory and restores them
+type BackupService struct {
+	db            *gorm.DB
+	dir           string
+	passphrase    string
+	emergencyRepo *store.EmergencyRepository
+	loader        *StateLoader
+	clock         Clock
+
+	// mu serialises backups and restores
+	mu        sync.Mutex
+	interval  time.Duration
+	keep      int
+	lastError string
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewBackupService creates a new backup service instance. The passphrase
+// encrypts backups made without one of their own, and may be empty. The
+// loader reloads the scheduler after a restore and may be nil when no
+// scheduler runs.
+func NewBackupService(db *gorm.DB, dir, passphrase string, emergencyRepo *store.EmergencyRepository, loader *StateLoader, clock Clock) *BackupService {
+	return &BackupService{
+		db:            db,
+		dir:           dir,
+		passphrase:    passphrase,
+		emergencyRepo: emergencyRepo,
+		loader:        loader,
+		clock:         clock,
+	}
+}
+
+// Status returns how backups are made
+func (s *BackupService) Status() BackupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BackupStatus{
+		Interval:  int(s.interval.Seconds()),
+		Keep:      s.keep,
+		Encrypted: s.passphrase != "",
+		LastError: s.lastError,
+	}
+}
+
+// Snapshot returns a consistent copy of the database, encrypted when a
+// passphrase is given. It does not block the server's writes.
+func (s *BackupService) Snapshot(passphrase string) ([]byte, error) {
+	file, err := os.CreateTemp("", "bell_scheduler-snapshot-*.db")
+	if err != nil {
+		return nil, err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	// VACUUM INTO writes the database as of one transaction into an empty file
+	if err := s.db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return nil, fmt.Errorf("failed to copy the database: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return data, nil
+	}
+	return encryptBackup(data, passphrase)
+}
+
+// Create stores a backup in the backup directory. Without a passphrase
+// the configured one is used.
+func (s *BackupService) Create(reason, passphrase string) (*BackupInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(reason, passphrase)
+}
+
+func (s *BackupService) create(reason, passphrase string) (*BackupInfo, error) {
+	if passphrase == "" {
+		passphrase = s.passphrase
+	}
+	data, err := s.Snapshot(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	info := BackupInfo{
+		Reason:    reason,
+		CreatedAt: s.clock.Now().UTC().Truncate(time.Second),
+		Size:      int64(len(data)),
+		Encrypted: passphrase != "",
+	}
+	extension := BackupExtension
+	if info.Encrypted {
+		extension = EncryptedBackupExtension
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the backup directory: %w", err)
+	}
+	base := backupPrefix + info.CreatedAt.Format(backupTimeFormat) + "-" + reason
+	info.Name = base + extension
+	path := filepath.Join(s.dir, info.Name)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		info.Name = fmt.Sprintf("%s-%d%s", base, n, extension)
+		path = filepath.Join(s.dir, info.Name)
+	}
+	// Write under another name first so a partial file is never listed
+	partial := path + ".partial"
+	if err := os.WriteFile(partial, data, 0o600); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to write the backup: %w", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to write the backup: %w", err)
+	}
+	return &info, nil
+}
+
+// List returns the stored backups, newest first
+func (s *BackupService) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		info.Size = stat.Size()
+		backups = append(backups, info)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].CreatedAt.After(backups[j].CreatedAt)
+		}
+		return backups[i].sequence > backups[j].sequence
+	})
+	return backups, nil
+}
+
+// Path returns the file of a stored backup
+func (s *BackupService) Path(name string) (string, error) {
+	if _, ok := parseBackupName(name); !ok {
+		return "", ErrBackupNotFound
+	}
+	path := filepath.Join(s.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrBackupNotFound
+	}
+	return path, nil
+}
+
+// Delete removes a stored backup
+func (s *BackupService) Delete(name string) error {
+	path, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Prune deletes the oldest automatic backups beyond the newest keep.
+// Backups made by hand or before a restore are left alone.
+func (s *BackupService) Prune(keep int) error {
+	backups, err := s.List()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, backup := range backups {
+		if backup.Reason != BackupScheduled {
+			continue
+		}
+		kept++
+		if kept <= keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, backup.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inspect checks that the data is a backup that can be restored and
+// summarises it. Without a passphrase the configured one is used.
+func (s *BackupService) Inspect(data []byte, passphrase string) (*BackupContents, error) {
+	path, err := s.unpack(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	return inspectBackup(path)
+}
+
+// RestoreFile restores a stored backup
+func (s *BackupService) RestoreFile(name, passphrase string) (*BackupRestore, error) {
+	path, err := s.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Restore(data, passphrase)
+}
+
+// Restore replaces the data with the backup's and reloads the scheduler.
+// The backup is checked before anything changes, and the current data is
+// backed up first. The audit log and emergency records are kept, and
+// every session ends.
+func (s *BackupService) Restore(data []byte, passphrase string) (*BackupRestore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emergencyRepo != nil {
+		state, err := s.emergencyRepo.GetState()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load emergency state: %w", err)
+		}
+		if state.Active {
+			return nil, ErrRestoreDuringEmergency
+		}
+	}
+
+	path, err := s.unpack(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	contents, err := inspectBackup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.create(BackupPreRestore, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up the current data: %w", err)
+	}
+	result := &BackupRestore{Contents: *contents, Previous: *previous}
+
+	if err := s.copyFrom(path); err != nil {
+		return nil, fmt.Errorf("failed to restore: %w", err)
+	}
+	if s.loader != nil {
+		if err := s.loader.Reload(); err != nil {
+			return result, fmt.Errorf("restored, but failed to reload the scheduler: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// Start makes a backup every interval in the background, keeping the
+// newest keep of them. A zero interval turns automatic backups off.
+func (s *BackupService) Start(interval time.Duration, keep int) {
+	s.mu.Lock()
+	s.interval = interval
+	s.keep = keep
+	s.mu.Unlock()
+	if interval <= 0 {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(interval, keep)
+}
+
+// Stop stops the automatic backups and waits for one in progress
+func (s *BackupService) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+func (s *BackupService) run(interval time.Duration, keep int) {
+	defer close(s.done)
+	for {
+		timer := s.clock.NewTimer(interval)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		s.mu.Lock()
+		_, err := s.create(BackupScheduled, "")
+		if err != nil {
+			s.lastError = err.Error()
+		} else {
+			s.lastError = ""
+		}
+		s.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Automatic backup failed: %v\n", err)
+			continue
+		}
+		if err := s.Prune(keep); err != nil {
+			fmt.Printf("Failed to delete old backups: %v\n", err)
+		}
+	}
+}
+
+// unpack decrypts the backup if needed and writes the database to a
+// temporary file, which the caller removes
+func (s *BackupService) unpack(data []byte, passphrase string) (string, error) {
+	if bytes.HasPrefix(data, []byte(backupMagic)) {
+		if passphrase == "" {
+			passphrase = s.passphrase
+		}
+		if passphrase == "" {
+			return "", ErrPassphraseRequired
+		}
+		var err error
+		if data, err = decryptBackup(data, passphrase); err != nil {
+			return "", err
+		}
+	}
+	if !bytes.HasPrefix(data, []byte(sqliteHeader)) {
+		return "", ErrInvalidBackup
+	}
+
+	file, err := os.CreateTemp("", "bell_scheduler-restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// copyFrom replaces the rows of every table with those of the database at
+// path in one transaction. Tables and columns are matched by name, so a
+// backup from an older version restores what it has and leaves the
+// defaults of columns added since.
+func (s *BackupService) copyFrom(path string) error {
+	// ATTACH only applies to one connection of the pool
+	return s.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("ATTACH DATABASE ? AS restored", path).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("DETACH DATABASE restored")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			tables, err := tableNames(tx, "main")
+			if err != nil {
+				return err
+			}
+			restored, err := tableNames(tx, "restored")
+			if err != nil {
+				return err
+			}
+			inBackup := make(map[string]bool, len(restored))
+			for _, table := range restored {
+				inBackup[table] = true
+			}
+
+			for _, table := range tables {
+				if restoreKeepTables[table] {
+					continue
+				}
+				if err := tx.Exec("DELETE FROM main." + quoteIdentifier(table)).Error; err != nil {
+					return err
+				}
+				if restoreClearTables[table] || !inBackup[table] {
+					continue
+				}
+
+				columns, err := sharedColumns(tx, table)
+				if err != nil {
+					return err
+				}
+				if len(columns) == 0 {
+					continue
+				}
+				list := strings.Join(columns, ", ")
+				err = tx.Exec(fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM restored.%s",
+					quoteIdentifier(table), list, list, quoteIdentifier(table))).Error
+				if err != nil {
+					return fmt.Errorf("table %s: %w", table, err)
+				}
+			}
+			return restoreDefaultZone(tx)
+		})
+	})
+}
+
+// restoreDefaultZone creates the default zone when the restored data has
+// none, as a backup from before zones existed would leave no bell to ring
+func restoreDefaultZone(tx *gorm.DB) error {
+	var zoneCount int64
+	if err := tx.Model(&models.Zone{}).Count(&zoneCount).Error; err != nil {
+		return err
+	}
+	if zoneCount > 0 {
+		return nil
+	}
+	var settings models.Settings
+	if err := tx.First(&settings).Error; err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	return tx.Create(models.DefaultZone(&settings)).Error
+}
+
+// inspectBackup checks the integrity and tables of the database at path
+// and counts what it holds
+func inspectBackup(path string) (*BackupContents, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var check []string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&check).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if len(check) != 1 || check[0] != "ok" {
+		return nil, fmt.Errorf("%w: the database is damaged", ErrInvalidBackup)
+	}
+
+	tables, err := tableNames(db, "main")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	present := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		present[table] = true
+	}
+	for _, table := range backupRequiredTables {
+		if !present[table] {
+			return nil, fmt.Errorf("%w: the %s table is missing", ErrInvalidBackup, table)
+		}
+	}
+
+	contents := &BackupContents{Tables: len(tables)}
+	if err := db.Table("users").Count(&contents.Users).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if err := db.Table("schedules").Count(&contents.Schedules).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if present["log_entries"] {
+		if err := db.Table("log_entries").Count(&contents.LogEntries).Error; err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+	}
+	return contents, nil
+}
+
+// tableNames lists the tables of an attached database, leaving out
+// SQLite's own
+func tableNames(db *gorm.DB, schema string) ([]string, error) {
+	var names []string
+	err := db.Raw("SELECT name FROM " + quoteIdentifier(schema) +
+		".sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY name").Scan(&names).Error
+	return names, err
+}
+
+// sharedColumns returns the quoted names of the columns a table has both
+// in the live database and in the restored one
+func sharedColumns(db *gorm.DB, table string) ([]string, error) {
+	type column struct {
+		Name string
+	}
+	var live, restored []column
+	if err := db.Raw("PRAGMA main.table_info(" + quoteIdentifier(table) + ")").Scan(&live).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Raw("PRAGMA restored.table_info(" + quoteIdentifier(table) + ")").Scan(&restored).Error; err != nil {
+		return nil, err
+	}
+
+	inBackup := make(map[string]bool, len(restored))
+	for _, c := range restored {
+		inBackup[c.Name] = true
+	}
+	var columns []string
+	for _, c := range live {
+		if inBackup[c.Name] {
+			columns = append(columns, quoteIdentifier(c.Name))
+		}
+	}
+	return columns, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// parseBackupName reads the time and reason from a backup's file name
+func parseBackupName(name string) (BackupInfo, bool) {
+	match := backupNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return BackupInfo{}, false
+	}
+	created, err := time.Parse(backupTimeFormat, match[1])
+	if err != nil {
+		return BackupInfo{}, false
+	}
+	info := BackupInfo{Name: name, Reason: match[2], CreatedAt: created, Encrypted: match[4] != ""}
+	if match[3] != "" {
+		info.sequence, _ = strconv.Atoi(match[3])
+	}
+	return info, true
+}
+
+// encryptBackup seals the data with AES-256-GCM under a key derived from
+// the passphrase with scrypt. The header is authenticated too.
+func encryptBackup(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(backupMagic), salt...)
+	out := make([]byte, 0, len(header)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, header), nil
+}
+
+// decryptBackup opens a backup sealed by encryptBackup
+func decryptBackup(data []byte, passphrase string) ([]byte, error) {
+	headerSize := len(backupMagic) + backupSaltSize
+	if len(data) < headerSize {
+		return nil, ErrInvalidBackup
+	}
+	aead, err := backupCipher(passphrase, data[len(backupMagic):headerSize])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize+aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidBackup
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/config"
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type backupFixture struct {
+	db           *gorm.DB
+	clock        *FakeClock
+	scheduler    *SchedulerService
+	scheduleRepo *store.ScheduleRepository
+	loader       *StateLoader
+	backups      *BackupService
+}
+
+// newBackupFixture opens a fully migrated database file, since backups
+// copy the file and restores need every table
+func newBackupFixture(t *testing.T, passphrase string) *backupFixture {
+	db, err := config.OpenDB(filepath.Join(t.TempDir(), "bell.db"), logger.Silent)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.Model(&models.Zone{}).Where("1 = 1").Update("output_driver", models.OutputDriverMock).Error)
+
+	clock := NewFakeClock(time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC))
+	zones := NewZoneManager(5*time.Second, clock)
+	t.Cleanup(zones.Close)
+	scheduleRepo := store.NewScheduleRepository(db)
+	scheduler := NewSchedulerService(zones, store.NewLogRepository(db), scheduleRepo, clock)
+	loader := NewStateLoader(store.NewSettingsRepository(db), store.NewZoneRepository(db), scheduleRepo,
+		store.NewCalendarRepository(db), store.NewActivationRepository(db), store.NewPatternRepository(db), scheduler)
+	require.NoError(t, loader.Reload())
+
+	return &backupFixture{
+		db:           db,
+		clock:        clock,
+		scheduler:    scheduler,
+		scheduleRepo: scheduleRepo,
+		loader:       loader,
+		backups:      NewBackupService(db, t.TempDir(), passphrase, store.NewEmergencyRepository(db), loader, clock),
+	}
+}
+
+func (f *backupFixture) scheduleNames(t *testing.T) []string {
+	schedules, err := f.scheduleRepo.GetAll()
+	require.NoError(t, err)
+	var names []string
+	for _, schedule := range schedules {
+		names = append(names, schedule.Name)
+	}
+	return names
+}
+
+func (f *backupFixture) count(t *testing.T, model interface{}) int64 {
+	var n int64
+	require.NoError(t, f.db.Model(model).Count(&n).Error)
+	return n
+}
+
+func TestStateLoader_ReloadWithFailingZone(t *testing.T) {
+	f := newBackupFixture(t, "")
+	hall := &models.Zone{Name: "Hall", OutputDriver: "relay-board"}
+	require.NoError(t, f.db.Create(hall).Error)
+	require.NoError(t, f.scheduleRepo.Create(&models.Schedule{Name: "Exams", TimeSlots: []models.TimeSlot{weekdaySlot("09:00", schoolDays...)}}, models.RevisionMeta{}))
+
+	// The zone falls back to a silent output and the rest still loads
+	require.NoError(t, f.loader.Reload())
+	output, ok := f.scheduler.Zones().Output(hall.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.OutputDriverNone, output.Name())
+	var names []string
+	for _, schedule := range f.scheduler.GetSchedules() {
+		names = append(names, schedule.Name)
+	}
+	assert.Contains(t, names, "Exams")
+}
+
+func TestBackupService_RestoreRoundTrip(t *testing.T) {
+	f := newBackupFixture(t, "")
+	require.NoError(t, f.scheduleRepo.Create(&models.Schedule{Name: "Regular", TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}, models.RevisionMeta{}))
+	require.NoError(t, f.db.Create(&models.User{Username: "head", Email: "head@school.test", Password: "hash", Role: models.RoleAdmin}).Error)
+	require.NoError(t, f.db.Create(&models.AuditEntry{Action: models.AuditLogin, Timestamp: f.clock.Now()}).Error)
+
+	backup, err := f.backups.Create(BackupManual, "")
+	require.NoError(t, err)
+	assert.Equal(t, "bell_scheduler-20261017-180000-manual.db", backup.Name)
+	assert.False(t, backup.Encrypted)
+
+	// Change everything a restore rewinds, and what it keeps
+	f.clock.Advance(time.Hour)
+	require.NoError(t, f.db.Where("1 = 1").Delete(&models.Schedule{}).Error)
+	require.NoError(t, f.scheduleRepo.Create(&models.Schedule{Name: "Exams", TimeSlots: []models.TimeSlot{weekdaySlot("09:00", schoolDays...)}}, models.RevisionMeta{}))
+	require.NoError(t, f.db.Model(&models.Settings{}).Where("1 = 1").Update("timezone", "Europe/Berlin").Error)
+	require.NoError(t, f.scheduler.SetTimezone("Europe/Berlin"))
+	require.NoError(t, f.db.Create(&models.Session{UserID: 1, TokenHash: "hash", ExpiresAt: f.clock.Now().Add(time.Hour)}).Error)
+	require.NoError(t, f.db.Create(&models.AuditEntry{Action: models.AuditScheduleDeleted, Timestamp: f.clock.Now()}).Error)
+
+	result, err := f.backups.RestoreFile(backup.Name, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Contents.Users)
+	assert.Equal(t, int64(1), result.Contents.Schedules)
+	assert.Equal(t, BackupPreRestore, result.Previous.Reason)
+
+	assert.Equal(t, []string{"Regular"}, f.scheduleNames(t))
+	assert.Equal(t, int64(1), f.count(t, &models.User{}))
+	assert.Equal(t, "UTC", f.scheduler.Location().String(), "the scheduler is reloaded")
+	assert.Equal(t, int64(0), f.count(t, &models.Session{}), "everyone signs in again")
+	assert.Equal(t, int64(2), f.count(t, &models.AuditEntry{}), "the audit log is not rewound")
+
+	// The data the restore replaced can be restored in turn
+	_, err = f.backups.RestoreFile(result.Previous.Name, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Exams"}, f.scheduleNames(t))
+	assert.Equal(t, "Europe/Berlin", f.scheduler.Location().String())
+
+	backups, err := f.backups.List()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	assert.Equal(t, backup.Name, backups[2].Name, "newest first")
+}
+
+func TestBackupService_RestoreWithoutZones(t *testing.T) {
+	f := newBackupFixture(t, "")
+	require.NoError(t, f.db.Model(&models.Settings{}).Where("1 = 1").Update("output_driver", models.OutputDriverMock).Error)
+
+	// A backup from before zones existed has no zones table
+	path := filepath.Join(t.TempDir(), "old.db")
+	require.NoError(t, f.db.Exec("VACUUM INTO ?", path).Error)
+	old, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, old.Exec("DROP TABLE zones").Error)
+	if sqlDB, err := old.DB(); err == nil {
+		sqlDB.Close()
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = f.backups.Restore(data, "")
+	require.NoError(t, err)
+
+	var zones []models.Zone
+	require.NoError(t, f.db.Find(&zones).Error)
+	require.Len(t, zones, 1)
+	assert.True(t, zones[0].IsDefault)
+	assert.Equal(t, models.OutputDriverMock, zones[0].OutputDriver)
+}
+
+func TestBackupService_Encryption(t *testing.T) {
+	f := newBackupFixture(t, "correct horse")
+	require.NoError(t, f.db.Create(&models.User{Username: "head", Email: "head@school.test", Password: "hash", Role: models.RoleAdmin}).Error)
+
+	backup, err := f.backups.Create(BackupManual, "")
+	require.NoError(t, err)
+	assert.True(t, backup.Encrypted)
+	assert.True(t, strings.HasSuffix(backup.Name, EncryptedBackupExtension))
+
+	path, err := f.backups.Path(backup.Name)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "head@school.test")
+
+	contents, err := f.backups.Inspect(data, "")
+	require.NoError(t, err, "the configured passphrase is used by default")
+	assert.Equal(t, int64(1), contents.Users)
+
+	_, err = f.backups.Inspect(data, "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	_, err = f.backups.Inspect(data[:len(data)-1], "correct horse")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	plain := NewBackupService(f.db, t.TempDir(), "", nil, nil, f.clock)
+	_, err = plain.Inspect(data, "")
+	assert.ErrorIs(t, err, ErrPassphraseRequired)
+
+	// A passphrase of its own overrides the configured one
+	other, err := plain.Snapshot("other")
+	require.NoError(t, err)
+	_, err = f.backups.Inspect(other, "other")
+	assert.NoError(t, err)
+}
+
+func TestBackupService_RejectsInvalidBackups(t *testing.T) {
+	f := newBackupFixture(t, "")
+	require.NoError(t, f.scheduleRepo.Create(&models.Schedule{Name: "Regular", TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}, models.RevisionMeta{}))
+
+	_, err := f.backups.Restore([]byte("not a backup"), "")
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	// A database of another application
+	other, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "other.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, other.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY)").Error)
+	plain := NewBackupService(other, t.TempDir(), "", nil, nil, f.clock)
+	data, err := plain.Snapshot("")
+	require.NoError(t, err)
+	_, err = f.backups.Restore(data, "")
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	// A damaged backup
+	data, err = f.backups.Snapshot("")
+	require.NoError(t, err)
+	for i := 4096; i < len(data); i++ {
+		data[i] = 0xFF
+	}
+	_, err = f.backups.Restore(data, "")
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	// Nothing changed and no pre-restore backup was made
+	assert.Equal(t, []string{"Regular"}, f.scheduleNames(t))
+	backups, err := f.backups.List()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	_, err = f.backups.Path("../bell.db")
+	assert.ErrorIs(t, err, ErrBackupNotFound)
+}
+
+func TestBackupService_RefusesRestoreDuringEmergency(t *testing.T) {
+	f := newBackupFixture(t, "")
+	data, err := f.backups.Snapshot("")
+	require.NoError(t, err)
+
+	require.NoError(t, f.db.Create(&models.EmergencyState{Active: true, Mode: models.EmergencyLockdown}).Error)
+	_, err = f.backups.Restore(data, "")
+	assert.ErrorIs(t, err, ErrRestoreDuringEmergency)
+}
+
+func TestBackupService_ScheduledBackupsAreRotated(t *testing.T) {
+	f := newBackupFixture(t, "")
+	manual, err := f.backups.Create(BackupManual, "")
+	require.NoError(t, err)
+
+	f.backups.Start(time.Hour, 2)
+	defer f.backups.Stop()
+	for i := 0; i < 4; i++ {
+		f.clock.BlockUntil(1)
+		f.clock.Advance(time.Hour)
+	}
+	// The next timer is set once the last backup and pruning are done
+	f.clock.BlockUntil(1)
+
+	backups, err := f.backups.List()
+	require.NoError(t, err)
+	var names []string
+	for _, backup := range backups {
+		names = append(names, backup.Name)
+	}
+	assert.Equal(t, []string{
+		"bell_scheduler-20261017-220000-scheduled.db",
+		"bell_scheduler-20261017-210000-scheduled.db",
+		manual.Name,
+	}, names, "older automatic backups are deleted, others are kept")
+
+	status := f.backups.Status()
+	assert.Equal(t, 3600, status.Interval)
+	assert.Equal(t, 2, status.Keep)
+	assert.Empty(t, status.LastError)
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+	"bell_scheduler/pkg/ical"
+)
+
+// Results of importing an iCalendar event
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportDelete    = "delete" // A previously imported event has been cancelled
+	ImportSkip      = "skip"
+)
+
+// CalendarImportOptions controls how iCalendar events become overrides
+type CalendarImportOptions struct {
+	Mappings []models.CalendarImportMapping
+	From     string // Optional YYYY-MM-DD
+	To       string // Optional YYYY-MM-DD
+	// Location is used for event times without a usable timezone
+	Location *time.Location
+}
+
+// CalendarImportItem is what an import does with one event
+type CalendarImportItem struct {
+	UID          string   `json:"uid"`
+	RecurrenceID string   `json:"recurrenceId,omitempty"` // Set for a changed instance of a recurring event
+	Summary      string   `json:"summary"`
+	Categories   []string `json:"categories"`
+	StartDate    string   `json:"startDate"`
+	EndDate      string   `json:"endDate"`
+	Action       string   `json:"action,omitempty"`
+	ScheduleID   *int64   `json:"scheduleId,omitempty"`
+	Result       string   `json:"result"`
+	Reason       string   `json:"reason,omitempty"`
+	OverrideID   int64    `json:"overrideId,omitempty"` // The existing override that is updated or deleted
+
+	key      string
+	previous *models.CalendarOverride
+	override *models.CalendarOverride
+}
+
+// CalendarImportPlan lists what importing a calendar will do
+type CalendarImportPlan struct {
+	CalendarName string               `json:"calendarName"`
+	Items        []CalendarImportItem `json:"items"`
+	Created      int                  `json:"created"`
+	Updated      int                  `json:"updated"`
+	Unchanged    int                  `json:"unchanged"`
+	Deleted      int                  `json:"deleted"`
+	Skipped      int                  `json:"skipped"`
+}
+
+// CalendarImporter turns the events of an iCalendar document, such as a
+// school district's calendar, into calendar overrides. Events are matched
+// to an action by their categories, and overrides remember the event's UID
+// and RECURRENCE-ID so importing an updated calendar changes them instead of
+// adding more.
+type CalendarImporter struct {
+	calendarRepo *store.CalendarRepository
+	scheduleRepo *store.ScheduleRepository
+}
+
+// NewCalendarImporter creates a new calendar importer
+func NewCalendarImporter(calendarRepo *store.CalendarRepository, scheduleRepo *store.ScheduleRepository) *CalendarImporter {
+	return &CalendarImporter{
+		calendarRepo: calendarRepo,
+		scheduleRepo: scheduleRepo,
+	}
+}
+
+// Plan works out what importing the calendar would do without changing
+// anything
+func (i *CalendarImporter) Plan(data []byte, opts CalendarImportOptions) (*CalendarImportPlan, error) {
+	if err := i.validate(&opts); err != nil {
+		return nil, err
+	}
+	calendar, err := ical.Parse(data, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar: %w", err)
+	}
+
+	existing, err := i.calendarRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]models.CalendarOverride)
+	for _, override := range existing {
+		if override.SourceUID != "" {
+			imported[override.SourceUID] = override
+		}
+	}
+
+	plan := &CalendarImportPlan{CalendarName: calendar.Name}
+	seen := make(map[string]bool)
+	for _, event := range calendar.Events {
+		key := event.Key()
+		if seen[key] {
+			return nil, fmt.Errorf("invalid calendar: event %s appears more than once", key)
+		}
+		seen[key] = true
+
+		item := planEvent(event, opts, imported)
+		switch item.Result {
+		case ImportCreate:
+			plan.Created++
+		case ImportUpdate:
+			plan.Updated++
+		case ImportUnchanged:
+			plan.Unchanged++
+		case ImportDelete:
+			plan.Deleted++
+		default:
+			plan.Skipped++
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// Apply writes the overrides of a plan in one transaction, so a failed
+// import changes nothing
+func (i *CalendarImporter) Apply(plan *CalendarImportPlan) error {
+	return i.calendarRepo.Transaction(func(calendarRepo *store.CalendarRepository) error {
+		for n := range plan.Items {
+			item := &plan.Items[n]
+			var err error
+			switch item.Result {
+			case ImportCreate:
+				err = calendarRepo.Create(item.override)
+			case ImportUpdate:
+				err = calendarRepo.Update(item.override)
+			case ImportDelete:
+				err = calendarRepo.Delete(item.OverrideID)
+			}
+			if err != nil {
+				return fmt.Errorf("event %s: %w", item.key, err)
+			}
+			if item.override != nil {
+				item.OverrideID = item.override.ID
+			}
+		}
+		return nil
+	})
+}
+
+// Changes returns snapshots of the overrides an applied plan replaced and
+// wrote, keyed by event, for the audit log
+func (p *CalendarImportPlan) Changes() (map[string]interface{}, map[string]interface{}) {
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	for _, item := range p.Items {
+		switch item.Result {
+		case ImportCreate, ImportUpdate, ImportDelete:
+		default:
+			continue
+		}
+		if item.previous != nil {
+			before[item.key] = Snapshot(item.previous)
+		}
+		if item.override != nil {
+			after[item.key] = Snapshot(item.override)
+		}
+	}
+	return before, after
+}
+
+func (i *CalendarImporter) validate(opts *CalendarImportOptions) error {
+	if len(opts.Mappings) == 0 {
+		return errors.New("at least one category mapping is required")
+	}
+	for _, mapping := range opts.Mappings {
+		if strings.TrimSpace(mapping.Category) == "" {
+			return errors.New("mapping category must not be empty")
+		}
+		switch mapping.Action {
+		case models.CalendarActionUseSchedule:
+			if mapping.ScheduleID == nil {
+				return fmt.Errorf("mapping %q: scheduleId is required for the use_schedule action", mapping.Category)
+			}
+			if _, err := i.scheduleRepo.Get(*mapping.ScheduleID); err != nil {
+				return fmt.Errorf("mapping %q: schedule not found", mapping.Category)
+			}
+		case models.CalendarActionNoBells, models.CalendarActionUseDefault, models.CalendarImportSkip:
+		default:
+			return fmt.Errorf("mapping %q: unknown action %q", mapping.Category, mapping.Action)
+		}
+	}
+	for _, date := range []string{opts.From, opts.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(models.CalendarDateFormat, date); err != nil {
+			return fmt.Errorf("%q must be in YYYY-MM-DD format", date)
+		}
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return nil
+}
+
+// planEvent decides what to do with one event
+func planEvent(event ical.Event, opts CalendarImportOptions, imported map[string]models.CalendarOverride) CalendarImportItem {
+	startDate, endDate := eventDates(event, opts.Location)
+	item := CalendarImportItem{
+		UID:          event.UID,
+		RecurrenceID: event.RecurrenceID,
+		Summary:      event.Summary,
+		Categories:   event.Categories,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Result:       ImportSkip,
+		key:          event.Key(),
+	}
+	if item.Categories == nil {
+		item.Categories = []string{}
+	}
+	existing, wasImported := imported[item.key]
+	if wasImported {
+		item.OverrideID = existing.ID
+		item.previous = &existing
+	}
+
+	if event.Status == "CANCELLED" {
+		if wasImported {
+			item.Result = ImportDelete
+			item.Reason = "the event was cancelled"
+		} else {
+			item.Reason = "the event is cancelled"
+		}
+		return item
+	}
+	if event.RRule != "" {
+		item.Reason = "recurring events are not supported"
+		return item
+	}
+	if (opts.From != "" && endDate < opts.From) || (opts.To != "" && startDate > opts.To) {
+		item.Reason = "outside the import range"
+		return item
+	}
+
+	mapping := matchMapping(event, opts.Mappings)
+	if mapping == nil {
+		item.Reason = "no mapping matches the event"
+		return item
+	}
+	if mapping.Action == models.CalendarImportSkip {
+		item.Reason = fmt.Sprintf("mapping %q skips it", mapping.Category)
+		return item
+	}
+	item.Action = mapping.Action
+	if mapping.Action == models.CalendarActionUseSchedule {
+		item.ScheduleID = mapping.ScheduleID
+	}
+
+	name := event.Summary
+	if name == "" {
+		name = event.UID
+	}
+	override := &models.CalendarOverride{
+		Name:        name,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Action:      item.Action,
+		ScheduleID:  item.ScheduleID,
+		Description: event.Description,
+		SourceUID:   item.key,
+	}
+	item.override = override
+	item.Result = ImportCreate
+	if wasImported {
+		override.BaseModel = existing.BaseModel
+		item.Result = ImportUpdate
+		if sameOverride(&existing, override) {
+			item.Result = ImportUnchanged
+			item.override = nil
+		}
+	}
+	return item
+}
+
+// matchMapping returns the first mapping naming one of the event's
+// categories, or failing that the first whose category appears in the
+// summary or is "*"
+func matchMapping(event ical.Event, mappings []models.CalendarImportMapping) *models.CalendarImportMapping {
+	for i := range mappings {
+		for _, category := range event.Categories {
+			if strings.EqualFold(strings.TrimSpace(mappings[i].Category), category) {
+				return &mappings[i]
+			}
+		}
+	}
+	summary := strings.ToLower(event.Summary)
+	for i := range mappings {
+		category := strings.ToLower(strings.TrimSpace(mappings[i].Category))
+		if category == models.CalendarImportAny || strings.Contains(summary, category) {
+			return &mappings[i]
+		}
+	}
+	return nil
+}
+
+// eventDates returns the first and last date an event covers. All-day
+// events end the day before their DTEND; timed events end on the date they
+// end, unless that is midnight.
+func eventDates(event ical.Event, loc *time.Location) (string, string) {
+	if event.AllDay {
+		end := event.End.AddDate(0, 0, -1)
+		if end.Before(event.Start) {
+			end = event.Start
+		}
+		return event.Start.Format(models.CalendarDateFormat), end.Format(models.CalendarDateFormat)
+	}
+
+	start := event.Start.In(loc)
+	end := event.End.In(loc)
+	if end.After(start) && end.Hour() == 0 && end.Minute() == 0 && end.Second() == 0 {
+		end = end.Add(-time.Nanosecond)
+	}
+	if end.Before(start) {
+		end = start
+	}
+	return start.Format(models.CalendarDateFormat), end.Format(models.CalendarDateFormat)
+}
+
+// sameOverride reports whether importing would leave the override as it is
+func sameOverride(a, b *models.CalendarOverride) bool {
+	sameSchedule := (a.ScheduleID == nil && b.ScheduleID == nil) ||
+		(a.ScheduleID != nil && b.ScheduleID != nil && *a.ScheduleID == *b.ScheduleID)
+	return a.Name == b.Name && a.StartDate == b.StartDate && a.EndDate == b.EndDate &&
+		a.Action == b.Action && sameSchedule && a.Description == b.Description
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func districtCalendar(events ...string) []byte {
+	lines := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "X-WR-CALNAME:District"}, events...)
+	return []byte(strings.Join(append(lines, "END:VCALENDAR"), "\r\n"))
+}
+
+func TestCalendarImporter(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), "UTC")
+	halfDay := &models.Schedule{Name: "Half day", TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}
+	f.addSchedule(t, halfDay)
+	calendarRepo := store.NewCalendarRepository(f.db)
+	importer := NewCalendarImporter(calendarRepo, f.scheduleRepo)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	opts := CalendarImportOptions{
+		Mappings: []models.CalendarImportMapping{
+			{Category: "Holiday", Action: models.CalendarActionNoBells},
+			{Category: "early release", Action: models.CalendarActionUseSchedule, ScheduleID: &halfDay.ID},
+			{Category: "Sports", Action: models.CalendarImportSkip},
+		},
+		From:     "2026-09-01",
+		Location: berlin,
+	}
+	data := districtCalendar(
+		"BEGIN:VEVENT", "UID:winter", "SUMMARY:Winter break", "CATEGORIES:HOLIDAY",
+		"DTSTART;VALUE=DATE:20261221", "DTEND;VALUE=DATE:20270102", "END:VEVENT",
+		// Matched by its summary, on its local date
+		"BEGIN:VEVENT", "UID:early", "SUMMARY:Early Release Day",
+		"DTSTART:20261119T230000Z", "DTEND:20261120T120000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:match", "SUMMARY:Football match", "CATEGORIES:Sports",
+		"DTSTART;VALUE=DATE:20261010", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:old", "SUMMARY:Summer break", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20260720", "DTEND;VALUE=DATE:20260829", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:concert", "SUMMARY:Concert", "DTSTART;VALUE=DATE:20261205", "END:VEVENT",
+	)
+
+	plan, err := importer.Plan(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "District", plan.CalendarName)
+	assert.Equal(t, 2, plan.Created)
+	assert.Equal(t, 3, plan.Skipped)
+	require.Len(t, plan.Items, 5)
+	assert.Equal(t, "2027-01-01", plan.Items[0].EndDate)
+	assert.Equal(t, "2026-11-20", plan.Items[1].StartDate)
+	assert.Equal(t, "2026-11-20", plan.Items[1].EndDate)
+	assert.Equal(t, &halfDay.ID, plan.Items[1].ScheduleID)
+	assert.Equal(t, `mapping "Sports" skips it`, plan.Items[2].Reason)
+	assert.Equal(t, "outside the import range", plan.Items[3].Reason)
+	assert.Equal(t, "no mapping matches the event", plan.Items[4].Reason)
+
+	// Planning writes nothing
+	overrides, err := calendarRepo.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	require.NoError(t, importer.Apply(plan))
+	overrides, err = calendarRepo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	assert.Equal(t, "Early Release Day", overrides[0].Name)
+	assert.Equal(t, models.CalendarActionUseSchedule, overrides[0].Action)
+	assert.Equal(t, "winter", overrides[1].SourceUID)
+
+	// Importing the updated calendar changes the imported overrides
+	data = districtCalendar(
+		"BEGIN:VEVENT", "UID:winter", "SUMMARY:Winter break", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20261222", "DTEND;VALUE=DATE:20270102", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:early", "SUMMARY:Early Release Day", "STATUS:CANCELLED",
+		"DTSTART:20261119T230000Z", "DTEND:20261120T120000Z", "END:VEVENT",
+	)
+	plan, err = importer.Plan(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, plan.Updated)
+	assert.Equal(t, 1, plan.Deleted)
+	require.NoError(t, importer.Apply(plan))
+
+	overrides, err = calendarRepo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "2026-12-22", overrides[0].StartDate)
+
+	plan, err = importer.Plan(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, plan.Unchanged)
+
+	// An import that fails part way changes nothing
+	data = districtCalendar(
+		"BEGIN:VEVENT", "UID:spring", "SUMMARY:Spring break", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20270329", "DTEND;VALUE=DATE:20270410", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:teacher", "SUMMARY:Teacher day", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20270208", "END:VEVENT",
+	)
+	plan, err = importer.Plan(data, opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, plan.Created)
+	plan.Items[1].override.ID = overrides[0].ID
+	assert.Error(t, importer.Apply(plan))
+	overrides, err = calendarRepo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "winter", overrides[0].SourceUID)
+
+	opts.Mappings = []models.CalendarImportMapping{{Category: "Holiday", Action: models.CalendarActionUseSchedule}}
+	_, err = importer.Plan(data, opts)
+	assert.Error(t, err, "use_schedule needs a schedule")
+}
+
+func TestCalendarImporter_RecurrenceID(t *testing.T) {
+	f := newSchedulerFixture(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), "UTC")
+	calendarRepo := store.NewCalendarRepository(f.db)
+	importer := NewCalendarImporter(calendarRepo, f.scheduleRepo)
+	opts := CalendarImportOptions{
+		Mappings: []models.CalendarImportMapping{{Category: "Holiday", Action: models.CalendarActionNoBells}},
+	}
+
+	// An instance moved out of a recurring series shares the series' UID
+	data := districtCalendar(
+		"BEGIN:VEVENT", "UID:inset", "SUMMARY:Inset day", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20261102", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:inset", "RECURRENCE-ID;VALUE=DATE:20270301", "SUMMARY:Inset day",
+		"CATEGORIES:Holiday", "DTSTART;VALUE=DATE:20270302", "END:VEVENT",
+	)
+	plan, err := importer.Plan(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.Created)
+	assert.Equal(t, "20270301", plan.Items[1].RecurrenceID)
+	require.NoError(t, importer.Apply(plan))
+
+	before, after := plan.Changes()
+	assert.Empty(t, before)
+	assert.Len(t, after, 2)
+	assert.Contains(t, after, "inset;RECURRENCE-ID=20270301")
+
+	overrides, err := calendarRepo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	assert.Equal(t, "inset", overrides[0].SourceUID)
+	assert.Equal(t, "inset;RECURRENCE-ID=20270301", overrides[1].SourceUID)
+
+	// Each is matched to its own override on the next import
+	data = districtCalendar(
+		"BEGIN:VEVENT", "UID:inset", "SUMMARY:Inset day", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20261102", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:inset", "RECURRENCE-ID;VALUE=DATE:20270301", "SUMMARY:Inset day",
+		"CATEGORIES:Holiday", "DTSTART;VALUE=DATE:20270303", "END:VEVENT",
+	)
+	plan, err = importer.Plan(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, plan.Unchanged)
+	assert.Equal(t, 1, plan.Updated)
+	assert.Equal(t, overrides[1].ID, plan.Items[1].OverrideID)
+
+	before, after = plan.Changes()
+	assert.Equal(t, []string{"inset;RECURRENCE-ID=20270301"}, keys(before))
+	assert.Equal(t, "2027-03-02", before["inset;RECURRENCE-ID=20270301"].(map[string]interface{})["startDate"])
+	assert.Equal(t, "2027-03-03", after["inset;RECURRENCE-ID=20270301"].(map[string]interface{})["startDate"])
+
+	// The same event twice in one file is ambiguous
+	data = districtCalendar(
+		"BEGIN:VEVENT", "UID:inset", "RECURRENCE-ID;VALUE=DATE:20270301", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20270302", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:inset", "RECURRENCE-ID;VALUE=DATE:20270301", "CATEGORIES:Holiday",
+		"DTSTART;VALUE=DATE:20270303", "END:VEVENT",
+	)
+	_, err = importer.Plan(data, opts)
+	assert.EqualError(t, err, "invalid calendar: event inset;RECURRENCE-ID=20270301 appears more than once")
+}
+
+func keys(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
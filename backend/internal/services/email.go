@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"net/smtp"
+	"strings"
+	"time"
 )
 
 type EmailService struct {
@@ -11,15 +13,17 @@ type EmailService struct {
 	username string
 	password string
 	from     string
+	baseURL  string // Frontend address used in links
 }
 
-func NewEmailService(host string, port int, username, password, from string) *EmailService {
+func NewEmailService(host string, port int, username, password, from, baseURL string) *EmailService {
 	return &EmailService{
 		host:     host,
 		port:     port,
 		username: username,
 		password: password,
 		from:     from,
+		baseURL:  strings.TrimRight(baseURL, "/"),
 	}
 }
 
@@ -32,8 +36,26 @@ func (s *EmailService) SendPasswordResetEmail(to, token string) error {
 		%s/reset-password?token=%s
 		
 		If you did not request this, please ignore this email.
-		`, "http://localhost:3000", token)
+		`, s.baseURL, token)
 
+	return s.send(to, subject, body)
+}
+
+func (s *EmailService) SendInvitationEmail(to, token, invitedBy string, expires time.Time) error {
+	subject := "You have been invited to Bell Scheduler"
+	body := fmt.Sprintf(`
+		%s has invited you to create a Bell Scheduler account.
+		Please click the following link to register:
+		
+		%s/register?invite=%s
+		
+		The invitation expires on %s.
+		`, invitedBy, s.baseURL, token, expires.Format("2 January 2006 15:04 MST"))
+
+	return s.send(to, subject, body)
+}
+
+func (s *EmailService) send(to, subject, body string) error {
 	msg := fmt.Sprintf("From: %s\r\n"+
 		"To: %s\r\n"+
 		"Subject: %s\r\n"+
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+)
+
+var (
+	// ErrEmergencyAlreadyActive is returned when starting a second emergency
+	ErrEmergencyAlreadyActive = errors.New("an emergency is already active")
+	// ErrNoEmergency is returned when stopping while no emergency is active
+	ErrNoEmergency = errors.New("no emergency is active")
+)
+
+// EmergencyService starts and stops lockdown and evacuation signalling.
+// The state is persisted so an emergency survives a restart, and every
+// transition is written to the hash-chained emergency log.
+type EmergencyService struct {
+	repo      *store.EmergencyRepository
+	scheduler *SchedulerService
+	clock     Clock
+	mu        sync.Mutex
+}
+
+// NewEmergencyService creates a new emergency service instance
+func NewEmergencyService(repo *store.EmergencyRepository, scheduler *SchedulerService, clock Clock) *EmergencyService {
+	return &EmergencyService{
+		repo:      repo,
+		scheduler: scheduler,
+		clock:     clock,
+	}
+}
+
+// Restore resumes an emergency that was active when the service stopped
+func (e *EmergencyService) Restore() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.repo.GetState()
+	if err != nil {
+		return fmt.Errorf("failed to load emergency state: %w", err)
+	}
+	if !state.Active {
+		return nil
+	}
+	fmt.Printf("Resuming %s emergency started by %s\n", state.Mode, state.StartedByName)
+	return e.scheduler.StartEmergency(state.Mode)
+}
+
+// Status returns the current emergency state
+func (e *EmergencyService) Status() (*models.EmergencyState, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.repo.GetState()
+}
+
+// Start begins an emergency. The state and log entry are saved before the
+// outputs are switched, so a failing output still leaves a record.
+func (e *EmergencyService) Start(mode, reason string, userID int64, username string) (*models.EmergencyState, error) {
+	if !models.IsValidEmergencyMode(mode) {
+		return nil, fmt.Errorf("unknown emergency mode %q", mode)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.repo.GetState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load emergency state: %w", err)
+	}
+	if state.Active {
+		return nil, ErrEmergencyAlreadyActive
+	}
+
+	now := e.clock.Now()
+	state.Active = true
+	state.Mode = mode
+	state.Reason = reason
+	state.StartedByID = userID
+	state.StartedByName = username
+	state.StartedAt = &now
+
+	entry := &models.EmergencyLogEntry{
+		Timestamp: now,
+		Action:    models.EmergencyActionStart,
+		Mode:      mode,
+		UserID:    userID,
+		Username:  username,
+		Reason:    reason,
+	}
+	if err := e.repo.SaveTransition(state, entry); err != nil {
+		return nil, fmt.Errorf("failed to save emergency state: %w", err)
+	}
+
+	if err := e.scheduler.StartEmergency(mode); err != nil {
+		return state, fmt.Errorf("failed to signal emergency: %w", err)
+	}
+	return state, nil
+}
+
+// Stop ends the active emergency and resumes scheduled bells
+func (e *EmergencyService) Stop(reason string, userID int64, username string) (*models.EmergencyState, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.repo.GetState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load emergency state: %w", err)
+	}
+	if !state.Active {
+		return nil, ErrNoEmergency
+	}
+
+	entry := &models.EmergencyLogEntry{
+		Timestamp: e.clock.Now(),
+		Action:    models.EmergencyActionStop,
+		Mode:      state.Mode,
+		UserID:    userID,
+		Username:  username,
+		Reason:    reason,
+	}
+	state.Active = false
+	state.Mode = ""
+	state.Reason = ""
+	state.StartedByID = 0
+	state.StartedByName = ""
+	state.StartedAt = nil
+	if err := e.repo.SaveTransition(state, entry); err != nil {
+		return nil, fmt.Errorf("failed to save emergency state: %w", err)
+	}
+
+	if err := e.scheduler.StopEmergency(); err != nil {
+		return state, fmt.Errorf("failed to stop emergency signal: %w", err)
+	}
+	return state, nil
+}
+
+// Log returns the emergency log and whether its hash chain is intact. When
+// it is not, brokenID is the first entry that fails verification, or 0 if
+// entries were removed from the end.
+func (e *EmergencyService) Log() (entries []models.EmergencyLogEntry, intact bool, brokenID int64, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.repo.GetState()
+	if err != nil {
+		return nil, false, 0, err
+	}
+	entries, err = e.repo.GetLog()
+	if err != nil {
+		return nil, false, 0, err
+	}
+	brokenID, intact = models.VerifyEmergencyLog(entries, state.LastHash)
+	return entries, intact, brokenID, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmergency_SuppressesScheduleAndSignals(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 59, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...), weekdaySlot("08:05", schoolDays...)}})
+	emergency := NewEmergencyService(store.NewEmergencyRepository(f.db), f.scheduler, f.clock)
+
+	_, err := emergency.Start(models.EmergencyLockdown, "Intruder reported", 1, "admin")
+	require.NoError(t, err)
+	f.waitForRelaysSettled(t)
+	_, err = emergency.Start(models.EmergencyEvacuation, "", 1, "admin")
+	assert.ErrorIs(t, err, ErrEmergencyAlreadyActive)
+	assert.ErrorIs(t, f.scheduler.TriggerNow(2, "teacher", nil, nil), ErrEmergencyActive)
+
+	f.simulate(t, start.Add(2*time.Minute))
+	assert.Empty(t, f.logs(t, "schedule"))
+	assert.Len(t, f.logs(t, "suppressed"), 1)
+
+	// Lockdown pulses half a second on, half a second off
+	events := f.output.Events()
+	require.True(t, len(events) > 4)
+	assert.Equal(t, OutputEvent{On: true, At: start}, events[0])
+	assert.Equal(t, OutputEvent{On: false, At: start.Add(500 * time.Millisecond)}, events[1])
+	assert.Equal(t, OutputEvent{On: true, At: start.Add(time.Second)}, events[2])
+
+	state, err := emergency.Stop("All clear", 1, "admin")
+	require.NoError(t, err)
+	assert.False(t, state.Active)
+	assert.False(t, f.zones.IsActive())
+	events = f.output.Events()
+	assert.False(t, events[len(events)-1].On)
+
+	// Scheduled bells resume
+	f.simulate(t, start.Add(7*time.Minute))
+	assert.Len(t, f.logs(t, "schedule"), 1)
+
+	_, err = emergency.Stop("", 1, "admin")
+	assert.ErrorIs(t, err, ErrNoEmergency)
+}
+
+func TestEmergency_SurvivesRestart(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 59, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	repo := store.NewEmergencyRepository(f.db)
+
+	_, err := NewEmergencyService(repo, f.scheduler, f.clock).Start(models.EmergencyEvacuation, "Fire alarm", 1, "admin")
+	require.NoError(t, err)
+	require.NoError(t, f.scheduler.StopEmergency())
+	require.Equal(t, "", f.scheduler.EmergencyMode())
+
+	// A new service over the same database picks the emergency up again
+	restarted := NewEmergencyService(repo, f.scheduler, f.clock)
+	require.NoError(t, restarted.Restore())
+	assert.Equal(t, models.EmergencyEvacuation, f.scheduler.EmergencyMode())
+	assert.True(t, f.zones.IsActive())
+
+	state, err := restarted.Status()
+	require.NoError(t, err)
+	assert.True(t, state.Active)
+	assert.Equal(t, "admin", state.StartedByName)
+}
+
+func TestEmergency_LogIsTamperEvident(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 59, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	emergency := NewEmergencyService(store.NewEmergencyRepository(f.db), f.scheduler, f.clock)
+
+	for i := 0; i < 2; i++ {
+		_, err := emergency.Start(models.EmergencyLockdown, "Drill", 1, "admin")
+		require.NoError(t, err)
+		f.clock.Advance(time.Minute)
+		_, err = emergency.Stop("Drill over", 2, "principal")
+		require.NoError(t, err)
+	}
+
+	entries, intact, _, err := emergency.Log()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.True(t, intact)
+	assert.Equal(t, "principal", entries[1].Username)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+
+	// Rewriting who stopped the emergency breaks the chain at that entry
+	require.NoError(t, f.db.Model(&models.EmergencyLogEntry{}).Where("id = ?", entries[1].ID).
+		Update("username", "someone else").Error)
+	_, intact, brokenID, err := emergency.Log()
+	require.NoError(t, err)
+	assert.False(t, intact)
+	assert.Equal(t, entries[1].ID, brokenID)
+
+	// Dropping the newest entry is detected as well
+	require.NoError(t, f.db.Model(&models.EmergencyLogEntry{}).Where("id = ?", entries[1].ID).
+		Update("username", "principal").Error)
+	require.NoError(t, f.db.Delete(&models.EmergencyLogEntry{}, entries[3].ID).Error)
+	_, intact, brokenID, err = emergency.Log()
+	require.NoError(t, err)
+	assert.False(t, intact)
+	assert.Zero(t, brokenID)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on the event bus
+const (
+	EventBellStarted       = "bell-started"
+	EventBellStopped       = "bell-stopped"
+	EventScheduleActivated = "schedule-activated"
+	EventTemporaryReset    = "temporary-reset"
+	EventSettingsChanged   = "settings-changed"
+	EventEmergencyStarted  = "emergency-started"
+	EventEmergencyStopped  = "emergency-stopped"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 32
+
+// Event is a state change pushed to subscribers of /api/events
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// BellEvent is the payload of bell-started and bell-stopped events
+type BellEvent struct {
+	ZoneID   int64  `json:"zoneId"`
+	ZoneName string `json:"zoneName"`
+}
+
+// ScheduleEvent is the payload of schedule-activated events. ScheduleID is 0
+// when no schedule runs today, ActivationID is set while a scheduled
+// activation runs.
+type ScheduleEvent struct {
+	ScheduleID   int64  `json:"scheduleId"`
+	ScheduleName string `json:"scheduleName,omitempty"`
+	IsTemporary  bool   `json:"isTemporary"`
+	ActivationID int64  `json:"activationId,omitempty"`
+}
+
+// TemporaryResetEvent is the payload of temporary-reset events
+type TemporaryResetEvent struct {
+	ScheduleID          int64  `json:"scheduleId"`
+	ScheduleName        string `json:"scheduleName"`
+	DefaultScheduleID   int64  `json:"defaultScheduleId"`
+	DefaultScheduleName string `json:"defaultScheduleName"`
+}
+
+// EmergencyEvent is the payload of emergency-started and emergency-stopped events
+type EmergencyEvent struct {
+	Mode string `json:"mode"`
+}
+
+// EventBus fans events out to any number of subscribers. Publishing never
+// blocks, so a stalled client cannot hold up the scheduler or a relay.
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	clock       Clock
+}
+
+// NewEventBus creates a new event bus
+func NewEventBus(clock Clock) *EventBus {
+	return &EventBus{
+		subscribers: make(map[chan Event]struct{}),
+		clock:       clock,
+	}
+}
+
+// Subscribe returns a channel receiving every published event and a function
+// that cancels the subscription and closes the channel
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to every subscriber
+func (b *EventBus) Publish(eventType string, data interface{}) {
+	event := Event{Type: eventType, Time: b.clock.Now(), Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Drop the event for a subscriber that is not keeping up
+		}
+	}
+}
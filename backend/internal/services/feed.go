@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/pkg/ical"
+)
+
+const (
+	// feedRefreshInterval is how often calendar clients are asked to fetch a
+	// feed again
+	feedRefreshInterval = time.Hour
+	// bellEventLength is how long a bell lasts in calendar clients
+	bellEventLength = time.Minute
+	feedProdID      = "-//Bell Scheduler//Calendar Feed//EN"
+	feedUIDDomain   = "bell-scheduler"
+)
+
+// CalendarFeed renders the bells from the start of today for the feed's
+// number of days, and the calendar overrides in that period, as an
+// iCalendar document. UIDs depend only on the schedule, the time a bell
+// rings and what it rings for, or the override ID, so clients update events
+// instead of duplicating them.
+func (s *SchedulerService) CalendarFeed(feed *models.CalendarFeed) []byte {
+	days := feed.Days
+	if days <= 0 {
+		days = models.DefaultFeedDays
+	}
+	local := s.localNow()
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location())
+	// Bells are due after from, so start just before midnight
+	from := today.Add(-time.Second)
+	to := today.AddDate(0, 0, days)
+
+	filter := BellFilter{}
+	if feed.ScheduleID != nil {
+		filter.ScheduleID = *feed.ScheduleID
+	}
+	if feed.ZoneID != nil {
+		filter.ZoneID = *feed.ZoneID
+	}
+
+	calendar := &ical.Calendar{
+		ProdID:          feedProdID,
+		Name:            feed.Name,
+		RefreshInterval: feedRefreshInterval,
+	}
+	for _, bell := range s.Bells(from, to, filter) {
+		summary := bell.Description
+		if summary == "" {
+			summary = "Bell"
+		}
+		details := []string{"Schedule: " + bell.ScheduleName}
+		if bell.PatternName != "" {
+			details = append(details, "Pattern: "+bell.PatternName)
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         bellUID(bell),
+			Start:       bell.At,
+			End:         bell.At.Add(bellEventLength),
+			Summary:     summary,
+			Description: strings.Join(details, "\n"),
+			Location:    strings.Join(bell.Zones, ", "),
+			Categories:  []string{"Bell", bell.ScheduleName},
+		})
+	}
+
+	first := today.Format(models.CalendarDateFormat)
+	last := to.AddDate(0, 0, -1).Format(models.CalendarDateFormat)
+	for _, override := range s.GetCalendarOverrides() {
+		if override.EndDate < first || override.StartDate > last {
+			continue
+		}
+		start, err := time.Parse(models.CalendarDateFormat, override.StartDate)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(models.CalendarDateFormat, override.EndDate)
+		if err != nil {
+			continue
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:          fmt.Sprintf("override-%d@%s", override.ID, feedUIDDomain),
+			Start:        start,
+			End:          end.AddDate(0, 0, 1),
+			AllDay:       true,
+			Summary:      override.Name,
+			Description:  strings.TrimSpace(s.describeOverride(override) + "\n" + override.Description),
+			Categories:   []string{"Calendar"},
+			LastModified: override.UpdatedAt,
+		})
+	}
+
+	return calendar.Marshal(s.clock.Now())
+}
+
+// bellUID identifies a bell in the feed. Bells of one schedule can ring at
+// the same time in different zones, so a hash of the zones and description
+// tells them apart. Time slot IDs are not used, as saving a schedule
+// recreates its time slots.
+func bellUID(bell UpcomingBell) string {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%v\n%s", bell.ZoneIDs, bell.Description)
+	return fmt.Sprintf("bell-%s-%d-%08x@%s", bell.At.UTC().Format("20060102T150405Z"), bell.ScheduleID, hash.Sum32(), feedUIDDomain)
+}
+
+// describeOverride explains what a calendar override does to the bells
+func (s *SchedulerService) describeOverride(override models.CalendarOverride) string {
+	switch override.Action {
+	case models.CalendarActionNoBells:
+		return "No bells"
+	case models.CalendarActionUseDefault:
+		return "Default schedule"
+	case models.CalendarActionUseSchedule:
+		if override.ScheduleID != nil {
+			s.mu.RLock()
+			schedule := s.findSchedule(func(schedule models.Schedule) bool { return schedule.ID == *override.ScheduleID })
+			s.mu.RUnlock()
+			if schedule != nil {
+				return "Schedule: " + schedule.Name
+			}
+		}
+	}
+	return ""
+}
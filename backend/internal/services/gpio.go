@@ -1,100 +1,381 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	"github.com/stianeikeland/go-rpio/v4"
+	"bell_scheduler/internal/models"
 )
 
-// GPIOService handles GPIO operations for the bell system
+// maxQueuedRings bounds the rings waiting behind the current one under the
+// queue overlap policy
+const maxQueuedRings = 8
+
+var (
+	// ErrRelayBusy is returned when a ring is rejected because another is in progress
+	ErrRelayBusy = errors.New("relay is already active")
+	// ErrQueueFull is returned when too many rings are waiting
+	ErrQueueFull = errors.New("too many rings queued")
+	// ErrOutputClosed is returned once the service has been closed
+	ErrOutputClosed = errors.New("output is closed")
+	// ErrEmergencyActive is returned for normal rings during an emergency
+	ErrEmergencyActive = errors.New("emergency signal is active")
+)
+
+// GPIOService handles GPIO operations for the bell system. A single owner
+// goroutine drives the output; every other method sends it a command, so the
+// relay state never races with HTTP handlers or the scheduler.
 type GPIOService struct {
-	pin      rpio.Pin
-	duration time.Duration
-	isActive bool
-	mock     bool
+	commands chan gpioCommand
+	done     chan struct{}
+	clock    Clock
+
+	// Snapshot of the owner's state for readers
+	mu       sync.Mutex
+	active   bool
+	driver   OutputDriver
+	listener func(active bool)
 }
 
-// NewGPIOService creates a new GPIO service instance
-func NewGPIOService(pinNumber int, duration time.Duration) (*GPIOService, error) {
-	service := &GPIOService{
-		duration: duration,
-		isActive: false,
-	}
+type gpioCommandKind int
 
-	// Try to open GPIO, if it fails, run in mock mode
-	if err := rpio.Open(); err != nil {
-		fmt.Println("Running in mock GPIO mode")
-		service.mock = true
-		return service, nil
-	}
+const (
+	cmdPlay gpioCommandKind = iota
+	cmdCancel
+	cmdSetDuration
+	cmdSetOverlapPolicy
+	cmdReconfigure
+	cmdEmergency
+	cmdClose
+)
 
-	service.mock = false
-	service.pin = rpio.Pin(pinNumber)
-	service.pin.Output()
-	service.pin.Low() // Ensure pin starts in low state
+type gpioCommand struct {
+	kind     gpioCommandKind
+	pattern  []time.Duration
+	duration time.Duration
+	policy   string
+	name     string
+	chip     string
+	pin      int
+	reply    chan error
+}
 
-	return service, nil
+// ring is a pattern being played by the owner goroutine
+type ring struct {
+	steps []time.Duration
+	index int
+	timer Timer
+}
+
+// gpioState is owned by the run goroutine
+type gpioState struct {
+	driver   OutputDriver
+	duration time.Duration
+	policy   string
+	on       bool
+	current  *ring
+	queue    [][]time.Duration
+	// emergency loops until cleared and takes priority over every ring
+	emergency []time.Duration
+}
+
+// NewGPIOService creates a new GPIO service instance using the given output
+// driver. Overlapping triggers are rejected until SetOverlapPolicy says otherwise.
+func NewGPIOService(driver OutputDriver, duration time.Duration, clock Clock) *GPIOService {
+	s := &GPIOService{
+		commands: make(chan gpioCommand),
+		done:     make(chan struct{}),
+		clock:    clock,
+		driver:   driver,
+	}
+	go s.run(&gpioState{
+		driver:   driver,
+		duration: duration,
+		policy:   models.OverlapReject,
+	})
+	return s
 }
 
 // Trigger activates the relay for the configured duration
 func (s *GPIOService) Trigger() error {
-	if s.isActive {
-		return fmt.Errorf("relay is already active")
-	}
+	return s.Play(nil)
+}
 
-	s.isActive = true
-	if !s.mock {
-		s.pin.High()
-	} else {
-		fmt.Println("\n🔔 BELL TRIGGERED 🔔")
-		fmt.Println("┌─────────────────┐")
-		fmt.Println("│   BELL RINGING  │")
-		fmt.Println("│     ╔═══╗      │")
-		fmt.Println("│     ║   ║      │")
-		fmt.Println("│     ║   ║      │")
-		fmt.Println("│     ║   ║      │")
-		fmt.Println("│     ╚═══╝      │")
-		fmt.Println("└─────────────────┘")
-		fmt.Printf("Duration: %v\n", s.duration)
-	}
+// Play switches the relay through a ring pattern of alternating on and off
+// periods, starting with on. An empty pattern rings once for the configured
+// duration. While another ring is in progress the overlap policy decides
+// whether the ring is rejected, queued or replaces the current one.
+func (s *GPIOService) Play(pattern []time.Duration) error {
+	return s.send(gpioCommand{kind: cmdPlay, pattern: pattern})
+}
 
-	// Start a goroutine to handle the duration
-	go func() {
-		time.Sleep(s.duration)
-		if !s.mock {
-			s.pin.Low()
-		} else {
-			fmt.Println("\n🔕 BELL STOPPED 🔕")
-			fmt.Println("┌─────────────────┐")
-			fmt.Println("│   BELL STOPPED  │")
-			fmt.Println("│     ╔═══╗      │")
-			fmt.Println("│     ║   ║      │")
-			fmt.Println("│     ║   ║      │")
-			fmt.Println("│     ║   ║      │")
-			fmt.Println("│     ╚═══╝      │")
-			fmt.Println("└─────────────────┘")
-		}
-		s.isActive = false
-	}()
+// Cancel stops the ring in progress and drops any queued rings
+func (s *GPIOService) Cancel() error {
+	return s.send(gpioCommand{kind: cmdCancel})
+}
 
-	return nil
+// StartEmergency stops any ring and plays the pattern in a loop until
+// StopEmergency. Normal rings are refused in the meantime.
+func (s *GPIOService) StartEmergency(pattern []time.Duration) error {
+	if len(pattern) == 0 {
+		return errors.New("emergency pattern needs at least one step")
+	}
+	return s.send(gpioCommand{kind: cmdEmergency, pattern: pattern})
+}
+
+// StopEmergency ends the emergency signal and switches the relay off
+func (s *GPIOService) StopEmergency() error {
+	return s.send(gpioCommand{kind: cmdEmergency})
 }
 
 // SetDuration updates the trigger duration
 func (s *GPIOService) SetDuration(duration time.Duration) {
-	s.duration = duration
+	s.send(gpioCommand{kind: cmdSetDuration, duration: duration})
+}
+
+// SetOverlapPolicy sets how a trigger is handled while the relay is active
+func (s *GPIOService) SetOverlapPolicy(policy string) {
+	s.send(gpioCommand{kind: cmdSetOverlapPolicy, policy: policy})
+}
+
+// SetStateListener registers a function called from the owner goroutine
+// whenever a ring starts or ends. It must not block.
+func (s *GPIOService) SetStateListener(listener func(active bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = listener
 }
 
-// IsActive returns whether the relay is currently active
+// IsActive returns whether a ring is in progress
 func (s *GPIOService) IsActive() bool {
-	return s.isActive
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
 }
 
-// Close cleans up GPIO resources
+// Driver returns the output driver currently in use
+func (s *GPIOService) Driver() OutputDriver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.driver
+}
+
+// Reconfigure stops any ring, closes the current output driver and opens the
+// named driver for the pin. If the new driver cannot be opened the service
+// keeps running with the no-op driver and the error is returned.
+func (s *GPIOService) Reconfigure(name, chip string, pin int) error {
+	return s.send(gpioCommand{kind: cmdReconfigure, name: name, chip: chip, pin: pin})
+}
+
+// Close stops any ring and cleans up GPIO resources
 func (s *GPIOService) Close() {
-	if !s.mock {
-		s.pin.Low()
-		rpio.Close()
+	s.send(gpioCommand{kind: cmdClose})
+}
+
+// send hands a command to the owner goroutine and waits for its result
+func (s *GPIOService) send(cmd gpioCommand) error {
+	cmd.reply = make(chan error, 1)
+	select {
+	case s.commands <- cmd:
+		return <-cmd.reply
+	case <-s.done:
+		return ErrOutputClosed
+	}
+}
+
+func (s *GPIOService) run(state *gpioState) {
+	defer close(s.done)
+
+	for {
+		var timerC <-chan time.Time
+		if state.current != nil {
+			timerC = state.current.timer.C()
+		}
+
+		select {
+		case cmd := <-s.commands:
+			cmd.reply <- s.handle(state, cmd)
+			if cmd.kind == cmdClose {
+				return
+			}
+		case <-timerC:
+			s.advance(state)
+		}
+	}
+}
+
+func (s *GPIOService) handle(state *gpioState, cmd gpioCommand) error {
+	switch cmd.kind {
+	case cmdPlay:
+		if state.emergency != nil {
+			return ErrEmergencyActive
+		}
+		pattern := cmd.pattern
+		if len(pattern) == 0 {
+			pattern = []time.Duration{state.duration}
+		}
+		if state.current == nil {
+			return s.start(state, pattern)
+		}
+		switch state.policy {
+		case models.OverlapQueue:
+			if len(state.queue) >= maxQueuedRings {
+				return ErrQueueFull
+			}
+			state.queue = append(state.queue, pattern)
+			return nil
+		case models.OverlapExtend:
+			// The new ring takes over from now; the relay stays on if it is
+			state.current.timer.Stop()
+			state.current = nil
+			return s.start(state, pattern)
+		default:
+			return ErrRelayBusy
+		}
+
+	case cmdCancel:
+		if state.emergency != nil {
+			return ErrEmergencyActive
+		}
+		s.stop(state)
+		return nil
+
+	case cmdEmergency:
+		s.stop(state)
+		state.emergency = cmd.pattern
+		if state.emergency != nil {
+			return s.start(state, state.emergency)
+		}
+		return nil
+
+	case cmdSetDuration:
+		state.duration = cmd.duration
+		return nil
+
+	case cmdSetOverlapPolicy:
+		state.policy = cmd.policy
+		return nil
+
+	case cmdReconfigure:
+		s.stop(state)
+		if err := state.driver.Close(); err != nil {
+			fmt.Printf("Failed to close %s output driver: %v\n", state.driver.Name(), err)
+		}
+		driver, err := NewOutputDriver(cmd.name, cmd.chip, cmd.pin, s.clock)
+		if err != nil {
+			driver = NewNoopDriver()
+		}
+		state.driver = driver
+		s.mu.Lock()
+		s.driver = driver
+		s.mu.Unlock()
+		if state.emergency != nil {
+			// Keep signalling on the new output
+			if startErr := s.start(state, state.emergency); startErr != nil && err == nil {
+				err = startErr
+			}
+		}
+		return err
+
+	case cmdClose:
+		state.emergency = nil
+		s.stop(state)
+		if err := state.driver.Close(); err != nil {
+			fmt.Printf("Failed to close %s output driver: %v\n", state.driver.Name(), err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %d", cmd.kind)
+}
+
+// start begins playing a pattern, switching the relay on for its first step
+func (s *GPIOService) start(state *gpioState, pattern []time.Duration) error {
+	if !state.on {
+		if err := state.driver.On(); err != nil {
+			return fmt.Errorf("failed to switch relay on: %w", err)
+		}
+		state.on = true
+	}
+
+	state.current = &ring{steps: pattern, timer: s.clock.NewTimer(pattern[0])}
+	s.setActive(true)
+	return nil
+}
+
+// advance moves the current ring to its next step, or finishes it and starts
+// the next queued ring
+func (s *GPIOService) advance(state *gpioState) {
+	current := state.current
+	current.index++
+	if current.index < len(current.steps) {
+		// Even steps are on periods, odd steps off periods
+		s.setRelay(state, current.index%2 == 0)
+		current.timer = s.clock.NewTimer(current.steps[current.index])
+		return
+	}
+
+	if state.emergency != nil {
+		// Loop the emergency pattern; a pattern ending on an on period
+		// simply keeps the relay on
+		current.index = 0
+		s.setRelay(state, true)
+		current.timer = s.clock.NewTimer(current.steps[0])
+		return
+	}
+
+	s.setRelay(state, false)
+	state.current = nil
+	for len(state.queue) > 0 {
+		next := state.queue[0]
+		state.queue = state.queue[1:]
+		if err := s.start(state, next); err != nil {
+			fmt.Printf("Failed to play queued ring: %v\n", err)
+			continue
+		}
+		return
+	}
+	s.setActive(false)
+}
+
+// stop ends the ring in progress and drops the queue
+func (s *GPIOService) stop(state *gpioState) {
+	if state.current != nil {
+		state.current.timer.Stop()
+		state.current = nil
+	}
+	state.queue = nil
+	s.setRelay(state, false)
+	s.setActive(false)
+}
+
+func (s *GPIOService) setRelay(state *gpioState, on bool) {
+	if state.on == on {
+		return
+	}
+	if on {
+		if err := state.driver.On(); err != nil {
+			fmt.Printf("Failed to switch relay on: %v\n", err)
+			return
+		}
+	} else {
+		if err := state.driver.Off(); err != nil {
+			fmt.Printf("Failed to switch relay off: %v\n", err)
+			return
+		}
+	}
+	state.on = on
+}
+
+func (s *GPIOService) setActive(active bool) {
+	s.mu.Lock()
+	changed := s.active != active
+	s.active = active
+	listener := s.listener
+	s.mu.Unlock()
+
+	if changed && listener != nil {
+		listener(active)
 	}
 }
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var gpioEpoch = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+func newTestGPIO(t *testing.T, policy string) (*GPIOService, *MockDriver, *FakeClock) {
+	clock := NewFakeClock(gpioEpoch)
+	driver := NewMockDriver(clock)
+	gpio := NewGPIOService(driver, 5*time.Second, clock)
+	gpio.SetOverlapPolicy(policy)
+	t.Cleanup(gpio.Close)
+	return gpio, driver, clock
+}
+
+// advanceRelay moves the fake clock and waits for the owner goroutine to handle
+// the timers that fell due
+func advanceRelay(t *testing.T, gpio *GPIOService, clock *FakeClock, d time.Duration) {
+	clock.Advance(d)
+	require.Eventually(t, func() bool {
+		// An active relay always has exactly one step timer pending
+		active := 0
+		if gpio.IsActive() {
+			active = 1
+		}
+		return clock.Pending() == active
+	}, time.Second, time.Millisecond)
+}
+
+func gpioAt(offset time.Duration) time.Time {
+	return gpioEpoch.Add(offset)
+}
+
+func TestGPIOService_RejectsOverlap(t *testing.T) {
+	gpio, driver, clock := newTestGPIO(t, models.OverlapReject)
+
+	require.NoError(t, gpio.Trigger())
+	assert.ErrorIs(t, gpio.Trigger(), ErrRelayBusy)
+
+	advanceRelay(t, gpio, clock, 5*time.Second)
+	assert.False(t, gpio.IsActive())
+	assert.Equal(t, []OutputEvent{{On: true, At: gpioAt(0)}, {On: false, At: gpioAt(5 * time.Second)}}, driver.Events())
+
+	// Once idle the relay accepts a new ring
+	assert.NoError(t, gpio.Trigger())
+}
+
+func TestGPIOService_QueuesOverlap(t *testing.T) {
+	gpio, driver, clock := newTestGPIO(t, models.OverlapQueue)
+
+	require.NoError(t, gpio.Trigger())
+	require.NoError(t, gpio.Play([]time.Duration{time.Second}))
+
+	advanceRelay(t, gpio, clock, 5*time.Second)
+	assert.True(t, gpio.IsActive(), "queued ring should start when the first ends")
+	advanceRelay(t, gpio, clock, time.Second)
+	assert.False(t, gpio.IsActive())
+
+	assert.Equal(t, []OutputEvent{
+		{On: true, At: gpioAt(0)},
+		{On: false, At: gpioAt(5 * time.Second)},
+		{On: true, At: gpioAt(5 * time.Second)},
+		{On: false, At: gpioAt(6 * time.Second)},
+	}, driver.Events())
+}
+
+func TestGPIOService_QueueLimit(t *testing.T) {
+	gpio, _, _ := newTestGPIO(t, models.OverlapQueue)
+
+	require.NoError(t, gpio.Trigger())
+	for i := 0; i < maxQueuedRings; i++ {
+		require.NoError(t, gpio.Trigger())
+	}
+	assert.ErrorIs(t, gpio.Trigger(), ErrQueueFull)
+}
+
+func TestGPIOService_ExtendsOverlap(t *testing.T) {
+	gpio, driver, clock := newTestGPIO(t, models.OverlapExtend)
+
+	require.NoError(t, gpio.Trigger())
+	advanceRelay(t, gpio, clock, 3*time.Second)
+	require.NoError(t, gpio.Trigger())
+
+	// The relay stays on until five seconds after the second trigger
+	advanceRelay(t, gpio, clock, 4*time.Second)
+	assert.True(t, gpio.IsActive())
+	advanceRelay(t, gpio, clock, time.Second)
+	assert.False(t, gpio.IsActive())
+
+	assert.Equal(t, []OutputEvent{{On: true, At: gpioAt(0)}, {On: false, At: gpioAt(8 * time.Second)}}, driver.Events())
+}
+
+func TestGPIOService_Cancel(t *testing.T) {
+	gpio, driver, clock := newTestGPIO(t, models.OverlapQueue)
+
+	require.NoError(t, gpio.Play([]time.Duration{time.Second, time.Second, time.Second}))
+	require.NoError(t, gpio.Trigger())
+	advanceRelay(t, gpio, clock, time.Second)
+	advanceRelay(t, gpio, clock, 500*time.Millisecond)
+
+	// Cancelling during an off period drops the rest and the queued ring
+	require.NoError(t, gpio.Cancel())
+	assert.False(t, gpio.IsActive())
+	assert.Zero(t, clock.Pending())
+
+	require.NoError(t, gpio.Trigger())
+	require.NoError(t, gpio.Cancel())
+
+	assert.Equal(t, []OutputEvent{
+		{On: true, At: gpioAt(0)},
+		{On: false, At: gpioAt(time.Second)},
+		{On: true, At: gpioAt(1500 * time.Millisecond)},
+		{On: false, At: gpioAt(1500 * time.Millisecond)},
+	}, driver.Events())
+}
+
+func TestGPIOService_Closed(t *testing.T) {
+	gpio, driver, _ := newTestGPIO(t, models.OverlapReject)
+
+	require.NoError(t, gpio.Trigger())
+	gpio.Close()
+
+	assert.False(t, gpio.IsActive())
+	assert.ErrorIs(t, gpio.Trigger(), ErrOutputClosed)
+	assert.Len(t, driver.Events(), 2)
+}
+
+// TestGPIOService_ConcurrentUse exercises every entry point at once; run with
+// -race to check the relay state is only touched by the owner goroutine
+func TestGPIOService_ConcurrentUse(t *testing.T) {
+	for _, policy := range []string{models.OverlapReject, models.OverlapQueue, models.OverlapExtend} {
+		t.Run(policy, func(t *testing.T) {
+			gpio, driver, clock := newTestGPIO(t, policy)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						switch (i + j) % 5 {
+						case 0, 1:
+							gpio.Trigger()
+						case 2:
+							gpio.SetDuration(time.Duration(j+1) * time.Millisecond)
+						case 3:
+							gpio.IsActive()
+							gpio.Driver()
+						case 4:
+							clock.Advance(time.Millisecond)
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			require.NoError(t, gpio.Cancel())
+			assert.False(t, gpio.IsActive())
+
+			// Whatever happened, the relay ends switched off
+			events := driver.Events()
+			require.NotEmpty(t, events)
+			assert.False(t, events[len(events)-1].On)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+	"bell_scheduler/pkg/ratelimiter"
+)
+
+// LoginGuard protects logins against password guessing. Failed logins are
+// limited per IP address and per username, and consecutive failures lock
+// the account for a period that doubles with every lockout. The limits are
+// persisted, so a restart does not reset them.
+type LoginGuard struct {
+	users      *store.GormUserRepository
+	audit      *AuditService
+	byIP       *ratelimiter.RateLimiter
+	byUsername *ratelimiter.RateLimiter
+	clock      Clock
+}
+
+// NewLoginGuard creates a new login guard, keeping its counters in limits
+func NewLoginGuard(users *store.GormUserRepository, audit *AuditService, limits ratelimiter.Store, clock Clock) *LoginGuard {
+	return &LoginGuard{
+		users:      users,
+		audit:      audit,
+		byIP:       ratelimiter.NewPersistentRateLimiter(models.LoginIPWindow, models.MaxFailedLoginsPerIP, limits).WithClock(clock.Now),
+		byUsername: ratelimiter.NewPersistentRateLimiter(models.LoginUsernameWindow, models.MaxFailedLoginsPerUsername, limits).WithClock(clock.Now),
+		clock:      clock,
+	}
+}
+
+// Check returns how long the login must wait, or zero if it may go ahead.
+// The user is nil when no account has the username.
+func (g *LoginGuard) Check(user *models.User, username, ip string) time.Duration {
+	var wait time.Duration
+	if allowed, d := g.byIP.Check(ipKey(ip)); !allowed && d > wait {
+		wait = d
+	}
+	if allowed, d := g.byUsername.Check(usernameKey(username)); !allowed && d > wait {
+		wait = d
+	}
+	if user != nil && user.IsLocked(g.clock.Now()) {
+		if d := user.LockedUntil.Sub(g.clock.Now()); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// Failed counts a failed login, locking the account once it has failed too
+// many times in a row
+func (g *LoginGuard) Failed(user *models.User, username, ip string) error {
+	actor := Actor{IPAddress: ip}
+	if g.byIP.Add(ipKey(ip)) {
+		g.audit.Record(actor, models.AuditEntry{
+			Action:  models.AuditIPBlocked,
+			Details: fmt.Sprintf("%d failed logins within %s", models.MaxFailedLoginsPerIP, models.LoginIPWindow),
+		})
+	}
+	g.byUsername.Add(usernameKey(username))
+
+	if user == nil {
+		return nil
+	}
+	failures, err := g.users.RecordFailedLogin(user.ID)
+	if err != nil {
+		return err
+	}
+	user.FailedLogins = failures
+	if failures < models.MaxConsecutiveFailedLogins {
+		return nil
+	}
+
+	d := models.LockoutDuration(user.Lockouts + 1)
+	until := g.clock.Now().Add(d)
+	if err := g.users.Lock(user.ID, until); err != nil {
+		return err
+	}
+	user.FailedLogins = 0
+	user.Lockouts++
+	user.LockedUntil = &until
+
+	g.audit.Record(actor, models.AuditEntry{
+		Action:     models.AuditAccountLocked,
+		TargetType: "user",
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		Details:    fmt.Sprintf("Locked for %s after %d failed logins (lockout %d)", d, failures, user.Lockouts),
+	})
+	return nil
+}
+
+// Succeeded resets the failures of an account after a successful login.
+// The IP address keeps its count, so one known password cannot be used to
+// keep guessing others.
+func (g *LoginGuard) Succeeded(user *models.User) error {
+	g.byUsername.Clear(usernameKey(user.Username))
+	if user.FailedLogins == 0 && user.Lockouts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	if err := g.users.ResetFailedLogins(user.ID); err != nil {
+		return err
+	}
+	user.FailedLogins = 0
+	user.Lockouts = 0
+	user.LockedUntil = nil
+	return nil
+}
+
+// Unlock lets a locked out user log in again
+func (g *LoginGuard) Unlock(user *models.User) error {
+	if err := g.users.ResetFailedLogins(user.ID); err != nil {
+		return err
+	}
+	g.byUsername.Clear(usernameKey(user.Username))
+	user.FailedLogins = 0
+	user.Lockouts = 0
+	user.LockedUntil = nil
+	return nil
+}
+
+// UnblockIP clears the failed logins of an IP address
+func (g *LoginGuard) UnblockIP(ip string) {
+	g.byIP.Clear(ipKey(ip))
+}
+
+func ipKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// usernameKey ignores case, so variations of a username share a limit
+func usernameKey(username string) string {
+	return "login:user:" + strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loginGuardFixture struct {
+	guard  *LoginGuard
+	users  *store.GormUserRepository
+	audit  *store.AuditRepository
+	limits *store.RateLimitRepository
+	clock  *FakeClock
+	user   *models.User
+}
+
+func newLoginGuardFixture(t *testing.T) *loginGuardFixture {
+	db, users, user := newTestUser(t)
+	require.NoError(t, db.AutoMigrate(&models.AuditEntry{}, &models.RateLimitEntry{}))
+	clock := NewFakeClock(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	audit := store.NewAuditRepository(db)
+	limits := store.NewRateLimitRepository(db)
+	return &loginGuardFixture{
+		guard:  NewLoginGuard(users, NewAuditService(audit, clock), limits, clock),
+		users:  users,
+		audit:  audit,
+		limits: limits,
+		clock:  clock,
+		user:   user,
+	}
+}
+
+// failLogins fails n logins of the fixture user from the address
+func (f *loginGuardFixture) failLogins(t *testing.T, n int, ip string) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, f.guard.Failed(f.user, f.user.Username, ip))
+	}
+}
+
+func TestLoginGuard_LockoutDoubles(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	f.failLogins(t, models.MaxConsecutiveFailedLogins-1, "10.0.0.1")
+	assert.Zero(t, f.guard.Check(f.user, f.user.Username, "10.0.0.1"))
+
+	f.failLogins(t, 1, "10.0.0.1")
+	assert.Equal(t, time.Minute, f.guard.Check(f.user, f.user.Username, "10.0.0.2"))
+
+	// The lockout is recorded on the user, not only in memory
+	stored, err := f.users.GetByID(f.user.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.IsLocked(f.clock.Now()))
+	assert.Equal(t, 1, stored.Lockouts)
+
+	f.clock.Advance(time.Minute)
+	assert.Zero(t, f.guard.Check(stored, stored.Username, "10.0.0.2"))
+	f.failLogins(t, models.MaxConsecutiveFailedLogins, "10.0.0.2")
+	assert.Equal(t, 2*time.Minute, f.guard.Check(f.user, f.user.Username, "10.0.0.3"))
+
+	entries, err := f.audit.GetByAction(models.AuditAccountLocked)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, f.user.ID, entries[0].TargetID)
+	assert.Equal(t, "10.0.0.2", entries[0].IPAddress)
+}
+
+func TestLoginGuard_SuccessResetsLockouts(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	f.failLogins(t, models.MaxConsecutiveFailedLogins, "10.0.0.1")
+	f.clock.Advance(time.Minute)
+	require.NoError(t, f.guard.Succeeded(f.user))
+
+	stored, err := f.users.GetByID(f.user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.FailedLogins)
+	assert.Zero(t, stored.Lockouts)
+	assert.Nil(t, stored.LockedUntil)
+
+	// The next lockout starts again from the shortest duration
+	f.failLogins(t, models.MaxConsecutiveFailedLogins, "10.0.0.1")
+	assert.Equal(t, time.Minute, f.guard.Check(f.user, f.user.Username, "10.0.0.2"))
+}
+
+func TestLoginGuard_LimitsUsernamesWithoutAccount(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	for i := 0; i < models.MaxFailedLoginsPerUsername; i++ {
+		require.NoError(t, f.guard.Failed(nil, "Nobody", "10.0.0.1"))
+	}
+	assert.Equal(t, models.LoginUsernameWindow, f.guard.Check(nil, "nobody", "10.0.0.9"))
+	assert.Zero(t, f.guard.Check(nil, "somebody", "10.0.0.9"))
+}
+
+func TestLoginGuard_LimitsIPAddress(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	for i := 0; i < models.MaxFailedLoginsPerIP; i++ {
+		require.NoError(t, f.guard.Failed(nil, "guess"+string(rune('a'+i)), "10.0.0.1"))
+		f.clock.Advance(time.Second)
+	}
+	assert.NotZero(t, f.guard.Check(nil, "someone", "10.0.0.1"))
+	assert.Zero(t, f.guard.Check(nil, "someone", "10.0.0.2"))
+
+	entries, err := f.audit.GetByAction(models.AuditIPBlocked)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	f.guard.UnblockIP("10.0.0.1")
+	assert.Zero(t, f.guard.Check(nil, "someone", "10.0.0.1"))
+}
+
+func TestLoginGuard_LimitsSurviveRestart(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	for i := 0; i < models.MaxFailedLoginsPerUsername; i++ {
+		require.NoError(t, f.guard.Failed(nil, "nobody", "10.0.0.1"))
+	}
+
+	restarted := NewLoginGuard(f.users, NewAuditService(f.audit, f.clock), f.limits, f.clock)
+	assert.NotZero(t, restarted.Check(nil, "nobody", "10.0.0.2"))
+
+	f.clock.Advance(models.LoginUsernameWindow)
+	assert.Zero(t, restarted.Check(nil, "nobody", "10.0.0.2"))
+}
+
+func TestLoginGuard_Unlock(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	f.failLogins(t, models.MaxConsecutiveFailedLogins, "10.0.0.1")
+	require.NoError(t, f.guard.Unlock(f.user))
+
+	stored, err := f.users.GetByID(f.user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.Lockouts)
+	assert.Zero(t, f.guard.Check(stored, stored.Username, "10.0.0.2"))
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/pkg/rrule"
+)
+
+// occurrence is a concrete instant at which a time slot is due
+type occurrence struct {
+	At         time.Time
+	Schedule   models.Schedule
+	TimeSlot   models.TimeSlot
+	Activation *models.ScheduleActivation // Activation that selected the schedule, if any
+}
+
+// key identifies the occurrence for de-duplication. Time slots of one
+// schedule may share a minute when they ring different zones, so the key
+// includes the slot's trigger time and zones.
+func (o occurrence) key() string {
+	zones := o.TimeSlot.Zones
+	if ids, err := o.TimeSlot.ZoneIDs(); err == nil {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		zones = fmt.Sprint(ids)
+	}
+	return fmt.Sprintf("%d@%d/%s/%s", o.Schedule.ID, o.At.Unix(), o.TimeSlot.TriggerTime, zones)
+}
+
+// occurrencesBetween expands the schedules into concrete trigger instants in
+// the window (from, to], ordered by time. Callers must hold at least a read lock.
+func (s *SchedulerService) occurrencesBetween(from, to time.Time) []occurrence {
+	loc := s.location
+	start := from.In(loc)
+	end := to.In(loc)
+
+	var result []occurrence
+	due := func(at time.Time) bool {
+		return at.After(from) && !at.After(to)
+	}
+	// Walk the calendar dates covered by the window. UTC is only used as a
+	// neutral container for the date arithmetic.
+	firstDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
+		schedule := s.resolveSchedule(noon)
+		// Temporary schedules are reset at the next local midnight, so later
+		// dates run the default schedule unless an override says otherwise
+		if schedule != nil && schedule.IsTemporary && day.After(firstDay) && s.overrideFor(noon) == nil {
+			schedule = s.findSchedule(func(schedule models.Schedule) bool {
+				return schedule.IsDefault && !schedule.IsTemporary
+			})
+		}
+		if schedule != nil {
+			for _, timeSlot := range schedule.TimeSlots {
+				at, ok := s.slotInstant(timeSlot, day, loc)
+				// Activations replace the schedule for their window
+				if ok && due(at) && s.activationAt(at) == nil {
+					result = append(result, occurrence{At: at, Schedule: *schedule, TimeSlot: timeSlot})
+				}
+			}
+		}
+
+		for i := range s.activations {
+			activation := &s.activations[i]
+			if !activation.EndsAt.After(from) || activation.StartsAt.After(to) {
+				continue
+			}
+			activated := s.findSchedule(func(schedule models.Schedule) bool { return schedule.ID == activation.ScheduleID })
+			if activated == nil {
+				continue
+			}
+			for _, timeSlot := range activated.TimeSlots {
+				at, ok := s.slotInstant(timeSlot, day, loc)
+				if ok && due(at) && s.activationAt(at) == activation {
+					result = append(result, occurrence{At: at, Schedule: *activated, TimeSlot: timeSlot, Activation: activation})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+	return result
+}
+
+// slotInstant returns when the time slot is due on the given date, and false
+// if it does not run that day
+func (s *SchedulerService) slotInstant(timeSlot models.TimeSlot, day time.Time, loc *time.Location) (time.Time, bool) {
+	if !s.slotRunsOn(timeSlot, day) {
+		return time.Time{}, false
+	}
+	trigger, err := time.Parse("15:04", timeSlot.TriggerTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return wallClockInstant(day.Year(), day.Month(), day.Day(), trigger.Hour(), trigger.Minute(), loc), true
+}
+
+// slotRunsOn reports whether the time slot runs on the date: inside its
+// validity window, and selected by its rule, or by its weekdays and week
+// interval. Slots with malformed settings never run.
+func (s *SchedulerService) slotRunsOn(timeSlot models.TimeSlot, day time.Time) bool {
+	date := day.Format(models.CalendarDateFormat)
+	if (timeSlot.StartDate != "" && date < timeSlot.StartDate) || (timeSlot.EndDate != "" && date > timeSlot.EndDate) {
+		return false
+	}
+	anchor, err := timeSlot.Anchor()
+	if err != nil {
+		return false
+	}
+
+	if timeSlot.RRule != "" {
+		rule, ok := s.rules[ruleKey(timeSlot)]
+		if !ok {
+			return false
+		}
+		return rule.Occurs(anchor, day)
+	}
+
+	var days []string
+	if err := json.Unmarshal([]byte(timeSlot.Days), &days); err != nil {
+		return false
+	}
+	for _, name := range days {
+		if name == day.Weekday().String() {
+			return timeSlot.WeekInterval <= 1 || inWeekInterval(anchor, day, timeSlot.WeekInterval)
+		}
+	}
+	return false
+}
+
+// ruleKey identifies the rule of a time slot together with the date it
+// counts from, as a rule bounded by COUNT depends on both
+func ruleKey(timeSlot models.TimeSlot) string {
+	return timeSlot.AnchorDate + "/" + timeSlot.StartDate + "/" + timeSlot.RRule
+}
+
+// parseRules parses the rules of the time slots once, bounded by their
+// anchors, so they are not parsed again each time a date is checked.
+// Malformed rules are left out and their slots never run.
+func parseRules(schedules []models.Schedule) map[string]*rrule.Rule {
+	rules := make(map[string]*rrule.Rule)
+	for _, schedule := range schedules {
+		for _, timeSlot := range schedule.TimeSlots {
+			key := ruleKey(timeSlot)
+			if _, ok := rules[key]; ok || timeSlot.RRule == "" {
+				continue
+			}
+			anchor, err := timeSlot.Anchor()
+			if err != nil {
+				continue
+			}
+			rule, err := rrule.Parse(timeSlot.RRule)
+			if err != nil {
+				fmt.Printf("Ignoring invalid rule %q of schedule %d: %v\n", timeSlot.RRule, schedule.ID, err)
+				continue
+			}
+			rules[key] = rule.Bound(anchor)
+		}
+	}
+	return rules
+}
+
+// inWeekInterval reports whether the date is in a week that is a multiple of
+// interval weeks before or after the anchor's. Weeks start on Monday.
+func inWeekInterval(anchor, day time.Time, interval int) bool {
+	if anchor.IsZero() {
+		return false
+	}
+	weeks := int(mondayOf(day).Sub(mondayOf(anchor)).Hours()/24) / 7
+	return ((weeks%interval)+interval)%interval == 0
+}
+
+// mondayOf returns midnight UTC of the Monday starting the date's week
+func mondayOf(day time.Time) time.Time {
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"bell_scheduler/internal/models"
+)
+
+// OutputDriver switches the physical bell relay on and off
+type OutputDriver interface {
+	// Name returns the driver identifier used in the settings
+	Name() string
+	// On energizes the relay
+	On() error
+	// Off releases the relay
+	Off() error
+	// Close releases the relay and any resources held by the driver
+	Close() error
+}
+
+// NewOutputDriver opens the named output driver for a pin. The chip is only
+// used by the gpiochip driver.
+func NewOutputDriver(name, chip string, pin int, clock Clock) (OutputDriver, error) {
+	switch name {
+	case models.OutputDriverRPIO, "":
+		return newRPIODriver(pin)
+	case models.OutputDriverGPIOChip:
+		return newGPIOChipDriver(chip, pin)
+	case models.OutputDriverSysfs:
+		return newSysfsDriver(pin)
+	case models.OutputDriverMock:
+		return NewMockDriver(clock), nil
+	case models.OutputDriverNone:
+		return NewNoopDriver(), nil
+	default:
+		return nil, fmt.Errorf("unknown output driver %q", name)
+	}
+}
+
+// NoopDriver is an output driver that does nothing, for running without hardware
+type NoopDriver struct{}
+
+// NewNoopDriver creates a new no-op output driver
+func NewNoopDriver() *NoopDriver {
+	return &NoopDriver{}
+}
+
+func (d *NoopDriver) Name() string { return models.OutputDriverNone }
+func (d *NoopDriver) On() error    { return nil }
+func (d *NoopDriver) Off() error   { return nil }
+func (d *NoopDriver) Close() error { return nil }
+
+// OutputEvent is a relay state change recorded by MockDriver
+type OutputEvent struct {
+	On bool
+	At time.Time
+}
+
+// MockDriver records relay state changes in memory, for tests and demos
+type MockDriver struct {
+	mu     sync.Mutex
+	clock  Clock
+	events []OutputEvent
+	closed bool
+}
+
+// NewMockDriver creates a new recording output driver
+func NewMockDriver(clock Clock) *MockDriver {
+	return &MockDriver{clock: clock}
+}
+
+func (d *MockDriver) Name() string { return models.OutputDriverMock }
+
+func (d *MockDriver) On() error {
+	return d.record(true)
+}
+
+func (d *MockDriver) Off() error {
+	return d.record(false)
+}
+
+func (d *MockDriver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
+
+func (d *MockDriver) record(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return fmt.Errorf("mock driver is closed")
+	}
+	d.events = append(d.events, OutputEvent{On: on, At: d.clock.Now()})
+	return nil
+}
+
+// Events returns a copy of the recorded state changes
+func (d *MockDriver) Events() []OutputEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	events := make([]OutputEvent, len(d.events))
+	copy(events, d.events)
+	return events
+}
@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"bell_scheduler/internal/models"
+)
+
+// Structures and request codes from linux/gpio.h (GPIO character device ABI v1)
+const (
+	gpioHandlesMax           = 64
+	gpioHandleRequestOutput  = 1 << 1
+	gpioConsumerLabel        = "bell_scheduler"
+	iocRead                  = 2
+	iocWrite                 = 1
+	iocDirShift              = 30
+	iocSizeShift             = 16
+	iocTypeShift             = 8
+	gpioIoctlType            = 0xB4
+	gpioGetLineHandleNr      = 0x03
+	gpioHandleSetLineValueNr = 0x09
+)
+
+type gpioHandleRequest struct {
+	LineOffsets   [gpioHandlesMax]uint32
+	Flags         uint32
+	DefaultValues [gpioHandlesMax]uint8
+	ConsumerLabel [32]byte
+	Lines         uint32
+	Fd            int32
+}
+
+type gpioHandleData struct {
+	Values [gpioHandlesMax]uint8
+}
+
+// iowr mirrors the _IOWR macro
+func iowr(nr, size uintptr) uintptr {
+	return (iocRead|iocWrite)<<iocDirShift | size<<iocSizeShift | gpioIoctlType<<iocTypeShift | nr
+}
+
+var (
+	gpioGetLineHandleIoctl      = iowr(gpioGetLineHandleNr, unsafe.Sizeof(gpioHandleRequest{}))
+	gpioHandleSetLineValueIoctl = iowr(gpioHandleSetLineValueNr, unsafe.Sizeof(gpioHandleData{}))
+)
+
+// gpioChipDriver drives a GPIO line through the Linux GPIO character device
+// (/dev/gpiochipN), which works on any board with a mainline kernel
+type gpioChipDriver struct {
+	chip   *os.File
+	handle int
+}
+
+func newGPIOChipDriver(chip string, line int) (OutputDriver, error) {
+	if chip == "" {
+		chip = "gpiochip0"
+	}
+	path := chip
+	if !strings.HasPrefix(path, "/") {
+		path = "/dev/" + chip
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	req := gpioHandleRequest{Flags: gpioHandleRequestOutput, Lines: 1}
+	req.LineOffsets[0] = uint32(line)
+	copy(req.ConsumerLabel[:], gpioConsumerLabel)
+	if err := ioctl(f.Fd(), gpioGetLineHandleIoctl, unsafe.Pointer(&req)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to request line %d on %s: %w", line, path, err)
+	}
+
+	return &gpioChipDriver{chip: f, handle: int(req.Fd)}, nil
+}
+
+func (d *gpioChipDriver) Name() string { return models.OutputDriverGPIOChip }
+
+func (d *gpioChipDriver) On() error {
+	return d.set(1)
+}
+
+func (d *gpioChipDriver) Off() error {
+	return d.set(0)
+}
+
+func (d *gpioChipDriver) Close() error {
+	offErr := d.Off()
+	if err := syscall.Close(d.handle); err != nil {
+		return err
+	}
+	if err := d.chip.Close(); err != nil {
+		return err
+	}
+	return offErr
+}
+
+func (d *gpioChipDriver) set(value uint8) error {
+	var data gpioHandleData
+	data.Values[0] = value
+	return ioctl(uintptr(d.handle), gpioHandleSetLineValueIoctl, unsafe.Pointer(&data))
+}
+
+func ioctl(fd, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package services
+
+import "fmt"
+
+func newGPIOChipDriver(chip string, line int) (OutputDriver, error) {
+	return nil, fmt.Errorf("the gpiochip output driver is only supported on Linux")
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+// rpio maps the GPIO registers once per process, so the mapping is shared
+// by every pin and only released when the last driver is closed
+var (
+	rpioMu   sync.Mutex
+	rpioRefs int
+)
+
+// rpioDriver drives a Raspberry Pi GPIO pin through /dev/gpiomem
+type rpioDriver struct {
+	pin rpio.Pin
+}
+
+func newRPIODriver(pinNumber int) (OutputDriver, error) {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+
+	if rpioRefs == 0 {
+		if err := rpio.Open(); err != nil {
+			return nil, fmt.Errorf("failed to open rpio: %w", err)
+		}
+	}
+	rpioRefs++
+
+	pin := rpio.Pin(pinNumber)
+	pin.Output()
+	pin.Low() // Ensure pin starts in low state
+	return &rpioDriver{pin: pin}, nil
+}
+
+func (d *rpioDriver) Name() string { return models.OutputDriverRPIO }
+
+func (d *rpioDriver) On() error {
+	d.pin.High()
+	return nil
+}
+
+func (d *rpioDriver) Off() error {
+	d.pin.Low()
+	return nil
+}
+
+func (d *rpioDriver) Close() error {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+
+	d.pin.Low()
+	rpioRefs--
+	if rpioRefs > 0 {
+		return nil
+	}
+	return rpio.Close()
+}
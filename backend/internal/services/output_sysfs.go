@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"bell_scheduler/internal/models"
+)
+
+// sysfsGPIOPath is the legacy sysfs GPIO interface
+const sysfsGPIOPath = "/sys/class/gpio"
+
+// sysfsDriver drives a GPIO pin through the legacy /sys/class/gpio interface
+type sysfsDriver struct {
+	pin   int
+	value *os.File
+}
+
+func newSysfsDriver(pin int) (OutputDriver, error) {
+	pinPath := filepath.Join(sysfsGPIOPath, "gpio"+strconv.Itoa(pin))
+	if _, err := os.Stat(pinPath); os.IsNotExist(err) {
+		if err := writeSysfs(filepath.Join(sysfsGPIOPath, "export"), strconv.Itoa(pin)); err != nil {
+			return nil, fmt.Errorf("failed to export GPIO %d: %w", pin, err)
+		}
+	}
+
+	// udev may need a moment to fix up permissions on a freshly exported pin.
+	// Writing "low" sets the direction to output with the pin switched off.
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		if err = writeSysfs(filepath.Join(pinPath, "direction"), "low"); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set GPIO %d as output: %w", pin, err)
+	}
+
+	value, err := os.OpenFile(filepath.Join(pinPath, "value"), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GPIO %d value: %w", pin, err)
+	}
+	return &sysfsDriver{pin: pin, value: value}, nil
+}
+
+func (d *sysfsDriver) Name() string { return models.OutputDriverSysfs }
+
+func (d *sysfsDriver) On() error {
+	_, err := d.value.WriteAt([]byte("1"), 0)
+	return err
+}
+
+func (d *sysfsDriver) Off() error {
+	_, err := d.value.WriteAt([]byte("0"), 0)
+	return err
+}
+
+func (d *sysfsDriver) Close() error {
+	offErr := d.Off()
+	if err := d.value.Close(); err != nil {
+		return err
+	}
+	if err := writeSysfs(filepath.Join(sysfsGPIOPath, "unexport"), strconv.Itoa(d.pin)); err != nil {
+		return err
+	}
+	return offErr
+}
+
+func writeSysfs(path, value string) error {
+	return os.WriteFile(path, []byte(value), 0)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+
+	"bell_scheduler/internal/store"
+)
+
+// StateLoader loads what the scheduler keeps in memory from the database.
+// The server runs it when the database changes underneath it, after a
+// restore or when another process such as a command line tool wrote to it.
+type StateLoader struct {
+	settingsRepo   *store.SettingsRepository
+	zoneRepo       *store.ZoneRepository
+	scheduleRepo   *store.ScheduleRepository
+	calendarRepo   *store.CalendarRepository
+	activationRepo *store.ActivationRepository
+	patternRepo    *store.PatternRepository
+	scheduler      *SchedulerService
+}
+
+// NewStateLoader creates a new state loader instance
+func NewStateLoader(settingsRepo *store.SettingsRepository, zoneRepo *store.ZoneRepository, scheduleRepo *store.ScheduleRepository,
+	calendarRepo *store.CalendarRepository, activationRepo *store.ActivationRepository, patternRepo *store.PatternRepository,
+	scheduler *SchedulerService) *StateLoader {
+	return &StateLoader{
+		settingsRepo:   settingsRepo,
+		zoneRepo:       zoneRepo,
+		scheduleRepo:   scheduleRepo,
+		calendarRepo:   calendarRepo,
+		activationRepo: activationRepo,
+		patternRepo:    patternRepo,
+		scheduler:      scheduler,
+	}
+}
+
+// Reload applies the settings and replaces the zones, schedules, calendar
+// overrides, activations and ring patterns of the scheduler. It stops at
+// the first error reading the database, leaving the rest as they were. A
+// zone whose output cannot be opened only logs a warning, as on startup.
+func (l *StateLoader) Reload() error {
+	settings, err := l.settingsRepo.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	l.scheduler.SetDuration(settings.RingDuration)
+	if err := l.scheduler.SetTimezone(settings.Timezone); err != nil {
+		return err
+	}
+	l.scheduler.SetCatchUpPolicy(settings.CatchUpPolicy, settings.CatchUpGrace)
+	l.scheduler.SetOverlapPolicy(settings.OverlapPolicy)
+
+	zones, err := l.zoneRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to load zones: %w", err)
+	}
+	if err := l.scheduler.UpdateZones(zones); err != nil {
+		fmt.Printf("Warning: %v, affected zones will not ring\n", err)
+	}
+
+	patterns, err := l.patternRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to load ring patterns: %w", err)
+	}
+	l.scheduler.UpdatePatterns(patterns)
+
+	schedules, err := l.scheduleRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
+	}
+	l.scheduler.UpdateSchedules(schedules)
+
+	overrides, err := l.calendarRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to load calendar overrides: %w", err)
+	}
+	l.scheduler.UpdateCalendarOverrides(overrides)
+
+	activations, err := l.activationRepo.GetUnfinished(l.scheduler.Now())
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled activations: %w", err)
+	}
+	l.scheduler.UpdateActivations(activations)
+	return nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sort"
+
+	"bell_scheduler/internal/models"
+)
+
+// Kinds of time slot change between revisions
+const (
+	SlotAdded   = "added"
+	SlotRemoved = "removed"
+	SlotChanged = "changed"
+)
+
+// FieldChange is a schedule field that differs between revisions
+type FieldChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// SlotChange is a time slot added, removed or changed between revisions
+type SlotChange struct {
+	Type   string               `json:"type"`
+	Before *models.RevisionSlot `json:"before,omitempty"`
+	After  *models.RevisionSlot `json:"after,omitempty"`
+}
+
+// RevisionDiff describes how a revision differs from an earlier one
+type RevisionDiff struct {
+	Name        *FieldChange `json:"name,omitempty"`
+	Description *FieldChange `json:"description,omitempty"`
+	TimeSlots   []SlotChange `json:"timeSlots"`
+}
+
+// DiffRevisions compares a revision with an earlier one, or with nothing
+// when before is nil. Time slots have no identity across saves, so slots
+// that are identical in both are unchanged, and of the rest a removed and
+// an added slot at the same time are reported as one changed slot.
+func DiffRevisions(before, after *models.ScheduleRevision) (RevisionDiff, error) {
+	diff := RevisionDiff{TimeSlots: []SlotChange{}}
+
+	var oldSlots []models.RevisionSlot
+	if before != nil {
+		slots, err := before.Slots()
+		if err != nil {
+			return diff, err
+		}
+		oldSlots = slots
+		if before.Name != after.Name {
+			diff.Name = &FieldChange{Before: before.Name, After: after.Name}
+		}
+		if before.Description != after.Description {
+			diff.Description = &FieldChange{Before: before.Description, After: after.Description}
+		}
+	}
+	newSlots, err := after.Slots()
+	if err != nil {
+		return diff, err
+	}
+
+	// Drop the slots present in both
+	var removed []models.RevisionSlot
+	added := append([]models.RevisionSlot(nil), newSlots...)
+	for _, slot := range oldSlots {
+		if i := indexOfSlot(added, slot); i >= 0 {
+			added = append(added[:i], added[i+1:]...)
+			continue
+		}
+		removed = append(removed, slot)
+	}
+
+	for i := range removed {
+		change := SlotChange{Type: SlotRemoved, Before: &removed[i]}
+		for j := range added {
+			if added[j].TriggerTime == removed[i].TriggerTime {
+				after := added[j]
+				change = SlotChange{Type: SlotChanged, Before: &removed[i], After: &after}
+				added = append(added[:j], added[j+1:]...)
+				break
+			}
+		}
+		diff.TimeSlots = append(diff.TimeSlots, change)
+	}
+	for i := range added {
+		diff.TimeSlots = append(diff.TimeSlots, SlotChange{Type: SlotAdded, After: &added[i]})
+	}
+
+	sort.SliceStable(diff.TimeSlots, func(i, j int) bool {
+		return slotChangeTime(diff.TimeSlots[i]) < slotChangeTime(diff.TimeSlots[j])
+	})
+	return diff, nil
+}
+
+func indexOfSlot(slots []models.RevisionSlot, slot models.RevisionSlot) int {
+	for i, s := range slots {
+		if s.TriggerTime == slot.TriggerTime && s.Days == slot.Days && s.Zones == slot.Zones &&
+			s.Description == slot.Description && samePattern(s.PatternID, slot.PatternID) &&
+			s.StartDate == slot.StartDate && s.EndDate == slot.EndDate && s.WeekInterval == slot.WeekInterval &&
+			s.AnchorDate == slot.AnchorDate && s.RRule == slot.RRule {
+			return i
+		}
+	}
+	return -1
+}
+
+func samePattern(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func slotChangeTime(change SlotChange) string {
+	if change.After != nil {
+		return change.After.TriggerTime
+	}
+	return change.Before.TriggerTime
+}
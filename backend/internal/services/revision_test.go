@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"bell_scheduler/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func revisionOf(t *testing.T, name string, slots ...models.TimeSlot) *models.ScheduleRevision {
+	revision, err := models.NewScheduleRevision(&models.Schedule{Name: name, TimeSlots: slots})
+	require.NoError(t, err)
+	return revision
+}
+
+func TestDiffRevisions(t *testing.T) {
+	before := revisionOf(t, "Normal day",
+		models.TimeSlot{TriggerTime: "08:30", Days: "[1,2,3,4,5]"},
+		models.TimeSlot{TriggerTime: "10:15", Days: "[1,2,3,4,5]"},
+		models.TimeSlot{TriggerTime: "15:00", Days: "[1,2,3,4,5]"},
+	)
+	after := revisionOf(t, "Short day",
+		models.TimeSlot{TriggerTime: "12:30", Days: "[1,2,3,4,5]"},
+		models.TimeSlot{TriggerTime: "08:30", Days: "[1,2,3,4,5]"},
+		models.TimeSlot{TriggerTime: "10:15", Days: "[1,2,3,4]"},
+	)
+
+	diff, err := DiffRevisions(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, &FieldChange{Before: "Normal day", After: "Short day"}, diff.Name)
+	assert.Nil(t, diff.Description)
+
+	require.Len(t, diff.TimeSlots, 3)
+	assert.Equal(t, SlotChanged, diff.TimeSlots[0].Type)
+	assert.Equal(t, "[1,2,3,4,5]", diff.TimeSlots[0].Before.Days)
+	assert.Equal(t, "[1,2,3,4]", diff.TimeSlots[0].After.Days)
+	assert.Equal(t, SlotAdded, diff.TimeSlots[1].Type)
+	assert.Equal(t, "12:30", diff.TimeSlots[1].After.TriggerTime)
+	assert.Equal(t, SlotRemoved, diff.TimeSlots[2].Type)
+	assert.Equal(t, "15:00", diff.TimeSlots[2].Before.TriggerTime)
+}
+
+func TestDiffRevisions_First(t *testing.T) {
+	diff, err := DiffRevisions(nil, revisionOf(t, "Normal day", models.TimeSlot{TriggerTime: "08:30"}))
+	require.NoError(t, err)
+	assert.Nil(t, diff.Name)
+	require.Len(t, diff.TimeSlots, 1)
+	assert.Equal(t, SlotAdded, diff.TimeSlots[0].Type)
+}
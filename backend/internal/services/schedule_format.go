@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schedule file formats
+const (
+	ScheduleFormatJSON = "json"
+	ScheduleFormatYAML = "yaml"
+	ScheduleFormatCSV  = "csv"
+)
+
+// ScheduleFileVersion is the version of the schedule file format
+const ScheduleFileVersion = 1
+
+// csvColumns are the columns of a CSV schedule file, one row per time slot.
+// Lists are separated by semicolons; days may also be separated by commas.
+var csvColumns = []string{
+	"schedule", "schedule_description", "time", "days", "description", "zones", "pattern",
+	"start_date", "end_date", "week_interval", "anchor_date", "rrule",
+}
+
+// ScheduleFile holds schedules in a form that can be moved between
+// installations. Zones and ring patterns are referred to by name, since
+// their IDs differ from one installation to the next.
+type ScheduleFile struct {
+	Version    int                `json:"version" yaml:"version"`
+	ExportedAt time.Time          `json:"exportedAt" yaml:"exportedAt"`
+	Schedules  []PortableSchedule `json:"schedules" yaml:"schedules"`
+}
+
+// PortableSchedule is a schedule in a schedule file
+type PortableSchedule struct {
+	Name        string             `json:"name" yaml:"name"`
+	Description string             `json:"description,omitempty" yaml:"description,omitempty"`
+	TimeSlots   []PortableTimeSlot `json:"timeSlots" yaml:"timeSlots"`
+}
+
+// PortableTimeSlot is a time slot in a schedule file
+type PortableTimeSlot struct {
+	Time         string   `json:"time" yaml:"time"` // HH:MM
+	Days         []string `json:"days,omitempty" yaml:"days,omitempty,flow"`
+	Description  string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Zones        []string `json:"zones,omitempty" yaml:"zones,omitempty,flow"` // Empty rings every zone
+	Pattern      string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	StartDate    string   `json:"startDate,omitempty" yaml:"startDate,omitempty"`
+	EndDate      string   `json:"endDate,omitempty" yaml:"endDate,omitempty"`
+	WeekInterval int      `json:"weekInterval,omitempty" yaml:"weekInterval,omitempty"`
+	AnchorDate   string   `json:"anchorDate,omitempty" yaml:"anchorDate,omitempty"`
+	RRule        string   `json:"rrule,omitempty" yaml:"rrule,omitempty"`
+}
+
+// ScheduleFormatFromName returns the format of a file from its extension
+func ScheduleFormatFromName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return ScheduleFormatJSON, nil
+	case ".yaml", ".yml":
+		return ScheduleFormatYAML, nil
+	case ".csv":
+		return ScheduleFormatCSV, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %q, use json, yaml or csv", name)
+}
+
+// EncodeScheduleFile writes a schedule file in the given format
+func EncodeScheduleFile(file *ScheduleFile, format string) ([]byte, error) {
+	switch format {
+	case ScheduleFormatJSON:
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case ScheduleFormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(file); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), encoder.Close()
+	case ScheduleFormatCSV:
+		return encodeCSV(file)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// DecodeScheduleFile reads a schedule file in the given format. Unknown
+// fields are rejected so that typos do not go unnoticed.
+func DecodeScheduleFile(data []byte, format string) (*ScheduleFile, error) {
+	file := &ScheduleFile{}
+	switch format {
+	case ScheduleFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(file); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case ScheduleFormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	case ScheduleFormatCSV:
+		var err error
+		if file, err = decodeCSV(data); err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if file.Version > ScheduleFileVersion {
+		return nil, fmt.Errorf("file version %d is newer than the supported version %d", file.Version, ScheduleFileVersion)
+	}
+	return file, nil
+}
+
+func encodeCSV(file *ScheduleFile) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	for _, schedule := range file.Schedules {
+		// A schedule without time slots still gets a row
+		if len(schedule.TimeSlots) == 0 {
+			row := make([]string, len(csvColumns))
+			row[0], row[1] = schedule.Name, schedule.Description
+			if err := w.Write(row); err != nil {
+				return nil, err
+			}
+		}
+		for _, slot := range schedule.TimeSlots {
+			weekInterval := ""
+			if slot.WeekInterval > 0 {
+				weekInterval = strconv.Itoa(slot.WeekInterval)
+			}
+			if err := w.Write([]string{
+				schedule.Name, schedule.Description, slot.Time, strings.Join(slot.Days, ";"),
+				slot.Description, strings.Join(slot.Zones, ";"), slot.Pattern,
+				slot.StartDate, slot.EndDate, weekInterval, slot.AnchorDate, slot.RRule,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// decodeCSV reads rows of time slots, grouping them by schedule name in the
+// order the schedules first appear. Columns may be in any order, and only
+// schedule and time are required.
+func decodeCSV(data []byte) (*ScheduleFile, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing header row")
+		}
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, column := range csvColumns {
+			known = known || column == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		index[name] = i
+	}
+	for _, column := range []string{"schedule", "time"} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	file := &ScheduleFile{Version: ScheduleFileVersion}
+	positions := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		name := field("schedule")
+		if name == "" {
+			return nil, fmt.Errorf("line %d: schedule is empty", line)
+		}
+		position, ok := positions[name]
+		if !ok {
+			position = len(file.Schedules)
+			positions[name] = position
+			file.Schedules = append(file.Schedules, PortableSchedule{Name: name})
+		}
+		schedule := &file.Schedules[position]
+		if schedule.Description == "" {
+			schedule.Description = field("schedule_description")
+		}
+		if field("time") == "" {
+			continue
+		}
+
+		slot := PortableTimeSlot{
+			Time:        field("time"),
+			Days:        splitCSVList(field("days"), ";,"),
+			Description: field("description"),
+			Zones:       splitCSVList(field("zones"), ";"),
+			Pattern:     field("pattern"),
+			StartDate:   field("start_date"),
+			EndDate:     field("end_date"),
+			AnchorDate:  field("anchor_date"),
+			RRule:       field("rrule"),
+		}
+		if value := field("week_interval"); value != "" {
+			if slot.WeekInterval, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("line %d: week_interval %q is not a number", line, value)
+			}
+		}
+		schedule.TimeSlots = append(schedule.TimeSlots, slot)
+	}
+	return file, nil
+}
+
+// splitCSVList splits a list cell on any of the separators
+func splitCSVList(value, separators string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
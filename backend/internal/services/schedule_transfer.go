@@ -0,0 +1,405 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+)
+
+// What a schedule import does when a schedule with the same name exists
+const (
+	ConflictSkip      = "skip"      // Keep the existing schedule
+	ConflictOverwrite = "overwrite" // Replace the existing schedule's description and time slots
+	ConflictRename    = "rename"    // Import under a new name such as "Regular (2)"
+)
+
+// Results of importing a schedule, besides ImportCreate and ImportSkip
+const (
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+	ImportInvalid   = "invalid"
+)
+
+var (
+	// ErrInvalidImport is returned when applying a plan with invalid schedules
+	ErrInvalidImport = errors.New("the file has invalid schedules")
+	// ErrExportNotFound is returned when exporting a schedule that does not exist
+	ErrExportNotFound = errors.New("schedule not found")
+)
+
+// ScheduleImportItem is what an import does with one schedule
+type ScheduleImportItem struct {
+	Name       string   `json:"name"`
+	ImportedAs string   `json:"importedAs,omitempty"`
+	Result     string   `json:"result"`
+	TimeSlots  int      `json:"timeSlots"`
+	ScheduleID int64    `json:"scheduleId,omitempty"` // The schedule overwritten, or once applied created
+	Errors     []string `json:"errors,omitempty"`
+
+	schedule *models.Schedule
+}
+
+// ScheduleImportPlan lists what importing a schedule file will do. A plan
+// with invalid schedules cannot be applied.
+type ScheduleImportPlan struct {
+	Items       []ScheduleImportItem `json:"items"`
+	Valid       bool                 `json:"valid"`
+	Created     int                  `json:"created"`
+	Overwritten int                  `json:"overwritten"`
+	Renamed     int                  `json:"renamed"`
+	Skipped     int                  `json:"skipped"`
+	Invalid     int                  `json:"invalid"`
+}
+
+// ScheduleTransfer exports schedules to files and imports them, for moving
+// schedules between installations
+type ScheduleTransfer struct {
+	scheduleRepo *store.ScheduleRepository
+	zoneRepo     *store.ZoneRepository
+	patternRepo  *store.PatternRepository
+	clock        Clock
+}
+
+// NewScheduleTransfer creates a new schedule transfer service
+func NewScheduleTransfer(scheduleRepo *store.ScheduleRepository, zoneRepo *store.ZoneRepository, patternRepo *store.PatternRepository, clock Clock) *ScheduleTransfer {
+	return &ScheduleTransfer{
+		scheduleRepo: scheduleRepo,
+		zoneRepo:     zoneRepo,
+		patternRepo:  patternRepo,
+		clock:        clock,
+	}
+}
+
+// Export writes the schedules with the given IDs, or every schedule, in the
+// given format
+func (t *ScheduleTransfer) Export(ids []int64, format string) ([]byte, error) {
+	schedules, err := t.scheduleRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	zoneNames, _, err := t.zoneNames()
+	if err != nil {
+		return nil, err
+	}
+	patternNames, _, err := t.patternNames()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[int64]bool)
+	for _, schedule := range schedules {
+		exists[schedule.ID] = true
+	}
+	wanted := make(map[int64]bool)
+	for _, id := range ids {
+		if !exists[id] {
+			return nil, fmt.Errorf("%w: %d", ErrExportNotFound, id)
+		}
+		wanted[id] = true
+	}
+
+	file := &ScheduleFile{Version: ScheduleFileVersion, ExportedAt: t.clock.Now().UTC(), Schedules: []PortableSchedule{}}
+	for i := range schedules {
+		if len(ids) == 0 || wanted[schedules[i].ID] {
+			file.Schedules = append(file.Schedules, portableSchedule(&schedules[i], zoneNames, patternNames))
+		}
+	}
+	return EncodeScheduleFile(file, format)
+}
+
+// Plan validates a schedule file and works out what importing it would do
+// without changing anything
+func (t *ScheduleTransfer) Plan(data []byte, format, onConflict string) (*ScheduleImportPlan, error) {
+	switch onConflict {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q, use skip, overwrite or rename", onConflict)
+	}
+	file, err := DecodeScheduleFile(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := t.scheduleRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	_, zoneIDs, err := t.zoneNames()
+	if err != nil {
+		return nil, err
+	}
+	_, patternIDs, err := t.patternNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Names in use, and the first schedule with each name
+	taken := make(map[string]bool)
+	byName := make(map[string]*models.Schedule)
+	for i := range existing {
+		taken[existing[i].Name] = true
+		if byName[existing[i].Name] == nil {
+			byName[existing[i].Name] = &existing[i]
+		}
+	}
+
+	plan := &ScheduleImportPlan{Items: []ScheduleImportItem{}, Valid: true}
+	inFile := make(map[string]bool)
+	for _, portable := range file.Schedules {
+		name := strings.TrimSpace(portable.Name)
+		item := ScheduleImportItem{Name: name, TimeSlots: len(portable.TimeSlots)}
+
+		schedule, errs := importedSchedule(portable, zoneIDs, patternIDs)
+		if inFile[name] {
+			errs = append(errs, "the file has another schedule with this name")
+		}
+		inFile[name] = true
+		if len(errs) > 0 {
+			item.Result = ImportInvalid
+			item.Errors = errs
+			plan.Invalid++
+			plan.Valid = false
+			plan.Items = append(plan.Items, item)
+			continue
+		}
+
+		conflict := byName[name]
+		switch {
+		case conflict == nil:
+			item.Result = ImportCreate
+			item.ImportedAs = name
+			plan.Created++
+		case onConflict == ConflictSkip:
+			item.Result = ImportSkip
+			item.ScheduleID = conflict.ID
+			plan.Skipped++
+		case onConflict == ConflictOverwrite:
+			item.Result = ImportOverwrite
+			item.ImportedAs = name
+			item.ScheduleID = conflict.ID
+			schedule.ID = conflict.ID
+			plan.Overwritten++
+		default:
+			item.Result = ImportRename
+			item.ImportedAs = uniqueName(name, taken)
+			schedule.Name = item.ImportedAs
+			plan.Renamed++
+		}
+		if item.ImportedAs != "" {
+			taken[item.ImportedAs] = true
+			item.schedule = schedule
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// Apply writes the schedules of a valid plan in one transaction, so a
+// failed import changes nothing. Overwritten schedules keep whether they
+// are the default, temporary or active schedule.
+func (t *ScheduleTransfer) Apply(plan *ScheduleImportPlan, meta models.RevisionMeta) error {
+	if !plan.Valid {
+		return ErrInvalidImport
+	}
+	return t.scheduleRepo.Transaction(func(scheduleRepo *store.ScheduleRepository) error {
+		for i := range plan.Items {
+			item := &plan.Items[i]
+			switch item.Result {
+			case ImportCreate, ImportRename:
+				if err := scheduleRepo.Create(item.schedule, meta); err != nil {
+					return fmt.Errorf("schedule %q: %w", item.ImportedAs, err)
+				}
+				item.ScheduleID = item.schedule.ID
+			case ImportOverwrite:
+				existing, err := scheduleRepo.Get(item.ScheduleID)
+				if err != nil {
+					return fmt.Errorf("schedule %q: %w", item.Name, err)
+				}
+				existing.Description = item.schedule.Description
+				existing.TimeSlots = item.schedule.TimeSlots
+				if err := scheduleRepo.Update(existing, meta); err != nil {
+					return fmt.Errorf("schedule %q: %w", item.Name, err)
+				}
+				item.schedule = existing
+			}
+		}
+		return nil
+	})
+}
+
+// ImportedSchedules returns the schedules an applied plan created or
+// overwrote
+func (p *ScheduleImportPlan) ImportedSchedules() []*models.Schedule {
+	var schedules []*models.Schedule
+	for _, item := range p.Items {
+		if item.schedule != nil {
+			schedules = append(schedules, item.schedule)
+		}
+	}
+	return schedules
+}
+
+func (t *ScheduleTransfer) zoneNames() (map[int64]string, map[string]int64, error) {
+	zones, err := t.zoneRepo.GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make(map[int64]string)
+	ids := make(map[string]int64)
+	for _, zone := range zones {
+		names[zone.ID] = zone.Name
+		ids[zone.Name] = zone.ID
+	}
+	return names, ids, nil
+}
+
+func (t *ScheduleTransfer) patternNames() (map[int64]string, map[string]int64, error) {
+	patterns, err := t.patternRepo.GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make(map[int64]string)
+	ids := make(map[string]int64)
+	for _, pattern := range patterns {
+		names[pattern.ID] = pattern.Name
+		ids[pattern.Name] = pattern.ID
+	}
+	return names, ids, nil
+}
+
+// portableSchedule converts a schedule for a schedule file, leaving out
+// zones and patterns that no longer exist
+func portableSchedule(schedule *models.Schedule, zoneNames, patternNames map[int64]string) PortableSchedule {
+	portable := PortableSchedule{Name: schedule.Name, Description: schedule.Description, TimeSlots: []PortableTimeSlot{}}
+	for i := range schedule.TimeSlots {
+		slot := &schedule.TimeSlots[i]
+		var days []string
+		_ = json.Unmarshal([]byte(slot.Days), &days)
+		var zones []string
+		ids, _ := slot.ZoneIDs()
+		for _, id := range ids {
+			if name, ok := zoneNames[id]; ok {
+				zones = append(zones, name)
+			}
+		}
+		pattern := ""
+		if slot.PatternID != nil {
+			pattern = patternNames[*slot.PatternID]
+		}
+		portable.TimeSlots = append(portable.TimeSlots, PortableTimeSlot{
+			Time:         slot.TriggerTime,
+			Days:         days,
+			Description:  slot.Description,
+			Zones:        zones,
+			Pattern:      pattern,
+			StartDate:    slot.StartDate,
+			EndDate:      slot.EndDate,
+			WeekInterval: slot.WeekInterval,
+			AnchorDate:   slot.AnchorDate,
+			RRule:        slot.RRule,
+		})
+	}
+	return portable
+}
+
+// importedSchedule converts a schedule from a schedule file, returning what
+// is wrong with it
+func importedSchedule(portable PortableSchedule, zoneIDs, patternIDs map[string]int64) (*models.Schedule, []string) {
+	var errs []string
+	schedule := &models.Schedule{
+		Name:        strings.TrimSpace(portable.Name),
+		Description: portable.Description,
+		TimeSlots:   []models.TimeSlot{},
+	}
+	if schedule.Name == "" {
+		errs = append(errs, "name is required")
+	}
+
+	for _, portableSlot := range portable.TimeSlots {
+		invalid := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Sprintf("time slot %s: ", portableSlot.Time)+fmt.Sprintf(format, args...))
+		}
+		if _, err := time.Parse("15:04", portableSlot.Time); err != nil || len(portableSlot.Time) != 5 {
+			invalid("time must be in HH:MM format")
+		}
+
+		days := []string{}
+		for _, day := range portableSlot.Days {
+			weekday, ok := parseWeekday(day)
+			if !ok {
+				invalid("%q is not a day of the week", day)
+				continue
+			}
+			days = append(days, weekday)
+		}
+		if len(days) == 0 && portableSlot.RRule == "" {
+			invalid("days or rrule is required")
+		}
+
+		zones := []int64{}
+		for _, name := range portableSlot.Zones {
+			id, ok := zoneIDs[strings.TrimSpace(name)]
+			if !ok {
+				invalid("zone %q not found", name)
+				continue
+			}
+			zones = append(zones, id)
+		}
+		var patternID *int64
+		if name := strings.TrimSpace(portableSlot.Pattern); name != "" {
+			id, ok := patternIDs[name]
+			if !ok {
+				invalid("ring pattern %q not found", name)
+			}
+			patternID = &id
+		}
+
+		daysJSON, _ := json.Marshal(days)
+		zonesJSON, _ := json.Marshal(zones)
+		slot := models.TimeSlot{
+			TriggerTime:  portableSlot.Time,
+			Days:         string(daysJSON),
+			Zones:        string(zonesJSON),
+			PatternID:    patternID,
+			Description:  portableSlot.Description,
+			StartDate:    portableSlot.StartDate,
+			EndDate:      portableSlot.EndDate,
+			WeekInterval: portableSlot.WeekInterval,
+			AnchorDate:   portableSlot.AnchorDate,
+			RRule:        portableSlot.RRule,
+		}
+		if err := slot.ValidateRecurrence(); err != nil {
+			invalid("%v", err)
+		}
+		schedule.TimeSlots = append(schedule.TimeSlots, slot)
+	}
+	return schedule, errs
+}
+
+// parseWeekday reads a day name such as "Monday", "monday" or "Mon"
+func parseWeekday(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := day.String()
+		if value == strings.ToLower(name) || value == strings.ToLower(name[:3]) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// uniqueName returns the name with the lowest number that is not taken,
+// such as "Regular (2)"
+func uniqueName(name string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransferFixture(t *testing.T) (*schedulerFixture, *ScheduleTransfer) {
+	f := newSchedulerFixture(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), "UTC")
+	require.NoError(t, f.db.AutoMigrate(&models.Zone{}, &models.RingPattern{}))
+	zoneRepo := store.NewZoneRepository(f.db)
+	patternRepo := store.NewPatternRepository(f.db)
+	require.NoError(t, zoneRepo.Create(&models.Zone{Name: "Main", OutputDriver: models.OutputDriverMock}))
+	require.NoError(t, zoneRepo.Create(&models.Zone{Name: "Gym", OutputDriver: models.OutputDriverMock}))
+	require.NoError(t, patternRepo.Create(&models.RingPattern{Name: "Double", Steps: "[1000,500,1000]"}))
+	return f, NewScheduleTransfer(f.scheduleRepo, zoneRepo, patternRepo, f.clock)
+}
+
+func TestScheduleTransfer_RoundTrip(t *testing.T) {
+	for _, format := range []string{ScheduleFormatJSON, ScheduleFormatYAML, ScheduleFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			f, transfer := newTransferFixture(t)
+			patternID := int64(1)
+			gym := weekdaySlot("09:00", "Monday", "Friday")
+			gym.Zones = "[2]"
+			gym.PatternID = &patternID
+			gym.Description = "PE, then lunch"
+			biweekly := weekdaySlot("15:00", "Wednesday")
+			biweekly.WeekInterval = 2
+			biweekly.AnchorDate = "2026-09-02"
+			monthly := models.TimeSlot{TriggerTime: "16:00", Days: "[]"}
+			monthly.RRule = "FREQ=MONTHLY;BYDAY=-1FR"
+			f.addSchedule(t, &models.Schedule{Name: "Regular", Description: "Every day",
+				TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...), gym, biweekly, monthly}})
+			f.addSchedule(t, &models.Schedule{Name: "Empty"})
+
+			data, err := transfer.Export(nil, format)
+			require.NoError(t, err)
+
+			plan, err := transfer.Plan(data, format, ConflictRename)
+			require.NoError(t, err)
+			require.True(t, plan.Valid, "%+v", plan.Items)
+			assert.Equal(t, 2, plan.Renamed)
+			require.NoError(t, transfer.Apply(plan, models.RevisionMeta{}))
+
+			copied, err := f.scheduleRepo.Get(plan.Items[0].ScheduleID)
+			require.NoError(t, err)
+			assert.Equal(t, "Regular (2)", copied.Name)
+			assert.Equal(t, "Every day", copied.Description)
+			require.Len(t, copied.TimeSlots, 4)
+			assert.Equal(t, `["Monday","Friday"]`, copied.TimeSlots[1].Days)
+			assert.Equal(t, "[2]", copied.TimeSlots[1].Zones)
+			assert.Equal(t, &patternID, copied.TimeSlots[1].PatternID)
+			assert.Equal(t, "PE, then lunch", copied.TimeSlots[1].Description)
+			assert.Equal(t, 2, copied.TimeSlots[2].WeekInterval)
+			assert.Equal(t, "2026-09-02", copied.TimeSlots[2].AnchorDate)
+			assert.Equal(t, "FREQ=MONTHLY;BYDAY=-1FR", copied.TimeSlots[3].RRule)
+			assert.Equal(t, "Empty (2)", plan.Items[1].ImportedAs)
+		})
+	}
+}
+
+func TestScheduleTransfer_Conflicts(t *testing.T) {
+	f, transfer := newTransferFixture(t)
+	regular := &models.Schedule{Name: "Regular", IsActive: true, TimeSlots: []models.TimeSlot{weekdaySlot("08:00", "Monday")}}
+	f.addSchedule(t, regular)
+	data := []byte("schedule,time,days\nRegular,07:45,Mon;Tue\nExams,09:00,\"Monday, Friday\"\n")
+
+	plan, err := transfer.Plan(data, ScheduleFormatCSV, ConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ImportSkip, ImportCreate}, []string{plan.Items[0].Result, plan.Items[1].Result})
+
+	// Planning writes nothing
+	schedules, err := f.scheduleRepo.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, schedules, 1)
+
+	// An import that fails part way changes nothing
+	plan, err = transfer.Plan(data, ScheduleFormatCSV, ConflictOverwrite)
+	require.NoError(t, err)
+	plan.Items[1].schedule.ID = regular.ID
+	assert.Error(t, transfer.Apply(plan, models.RevisionMeta{}))
+	unchanged, err := f.scheduleRepo.Get(regular.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "08:00", unchanged.TimeSlots[0].TriggerTime)
+	revisions, err := f.scheduleRepo.GetRevisions(regular.ID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	schedules, err = f.scheduleRepo.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, schedules, 1)
+
+	plan, err = transfer.Plan(data, ScheduleFormatCSV, ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, plan.Overwritten)
+	require.NoError(t, transfer.Apply(plan, models.RevisionMeta{}))
+
+	overwritten, err := f.scheduleRepo.Get(regular.ID)
+	require.NoError(t, err)
+	assert.True(t, overwritten.IsActive, "overwriting keeps the active flag")
+	require.Len(t, overwritten.TimeSlots, 1)
+	assert.Equal(t, "07:45", overwritten.TimeSlots[0].TriggerTime)
+	assert.Equal(t, `["Monday","Tuesday"]`, overwritten.TimeSlots[0].Days)
+	exams, err := f.scheduleRepo.Get(plan.Items[1].ScheduleID)
+	require.NoError(t, err)
+	assert.Equal(t, `["Monday","Friday"]`, exams.TimeSlots[0].Days)
+
+	_, err = transfer.Plan(data, ScheduleFormatCSV, "merge")
+	assert.Error(t, err)
+}
+
+func TestScheduleTransfer_Invalid(t *testing.T) {
+	_, transfer := newTransferFixture(t)
+	data := []byte(strings.Join([]string{
+		"schedules:",
+		"  - name: Regular",
+		"    timeSlots:",
+		"      - {time: '8:00', days: [Monday]}",
+		"      - {time: '09:00', days: [Funday], zones: [Hall], pattern: Triple}",
+		"      - {time: '10:00'}",
+		"  - name: Regular",
+		"    timeSlots: []",
+	}, "\n"))
+
+	plan, err := transfer.Plan(data, ScheduleFormatYAML, ConflictSkip)
+	require.NoError(t, err)
+	assert.False(t, plan.Valid)
+	assert.Equal(t, 2, plan.Invalid)
+	assert.Equal(t, []string{
+		"time slot 8:00: time must be in HH:MM format",
+		`time slot 09:00: "Funday" is not a day of the week`,
+		"time slot 09:00: days or rrule is required",
+		`time slot 09:00: zone "Hall" not found`,
+		`time slot 09:00: ring pattern "Triple" not found`,
+		"time slot 10:00: days or rrule is required",
+	}, plan.Items[0].Errors)
+	assert.Equal(t, []string{"the file has another schedule with this name"}, plan.Items[1].Errors)
+	assert.Equal(t, ErrInvalidImport, transfer.Apply(plan, models.RevisionMeta{}))
+
+	_, err = transfer.Plan([]byte(`{"schedules": [{"name": "A", "slots": []}]}`), ScheduleFormatJSON, ConflictSkip)
+	assert.Error(t, err, "unknown fields are rejected")
+	_, err = transfer.Plan([]byte("name,time\nA,08:00\n"), ScheduleFormatCSV, ConflictSkip)
+	assert.Error(t, err, "unknown columns are rejected")
+	_, err = transfer.Export([]int64{42}, ScheduleFormatJSON)
+	assert.ErrorIs(t, err, ErrExportNotFound)
+}
@@ -1,33 +1,82 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	"bell_scheduler/internal/models"
 	"bell_scheduler/internal/store"
+	"bell_scheduler/pkg/rrule"
+)
+
+const (
+	// maxSleep bounds how long the scheduler sleeps between wake-ups so that
+	// clock steps are noticed promptly
+	maxSleep = time.Minute
+	// clockJumpThreshold is the difference between wall-clock and monotonic
+	// elapsed time above which the system clock is considered stepped
+	clockJumpThreshold = 2 * time.Second
+	// onTimeTolerance is how late a trigger may fire and still count as on time
+	onTimeTolerance = 5 * time.Second
+	// maxCatchUpWindow limits how far back triggers are considered after a stall
+	maxCatchUpWindow = 24 * time.Hour
+	// firedRetention is how long handled triggers are remembered to avoid
+	// ringing twice when the wall clock is stepped back
+	firedRetention = 48 * time.Hour
 )
 
 // SchedulerService manages the bell schedules and triggers
 type SchedulerService struct {
-	gpio         *GPIOService
-	schedules    []models.Schedule
-	logRepo      *store.LogRepository
-	scheduleRepo *store.ScheduleRepository
-	mu           sync.RWMutex
-	stopChan     chan struct{}
+	zones         *ZoneManager
+	schedules     []models.Schedule
+	rules         map[string]*rrule.Rule // Bounded rules of the time slots, by ruleKey
+	overrides     []models.CalendarOverride
+	activations   []models.ScheduleActivation
+	patterns      map[int64]models.RingPattern
+	emergency     string
+	events        *EventBus
+	announced     *int64 // Schedule last announced as activated, nil before the first check
+	location      *time.Location
+	catchUpPolicy string
+	catchUpGrace  time.Duration
+	logRepo       *store.LogRepository
+	scheduleRepo  *store.ScheduleRepository
+	clock         Clock
+	mu            sync.RWMutex
+	fired         map[string]time.Time
+	firedMu       sync.Mutex
+	stopChan      chan struct{}
+	wakeChan      chan struct{}
 }
 
 // NewSchedulerService creates a new scheduler service instance
-func NewSchedulerService(gpio *GPIOService, logRepo *store.LogRepository, scheduleRepo *store.ScheduleRepository) *SchedulerService {
+func NewSchedulerService(zones *ZoneManager, logRepo *store.LogRepository, scheduleRepo *store.ScheduleRepository, clock Clock) *SchedulerService {
+	events := NewEventBus(clock)
+	zones.SetListener(func(zone models.Zone, active bool) {
+		eventType := EventBellStopped
+		if active {
+			eventType = EventBellStarted
+		}
+		events.Publish(eventType, BellEvent{ZoneID: zone.ID, ZoneName: zone.Name})
+	})
+
 	return &SchedulerService{
-		gpio:         gpio,
-		schedules:    make([]models.Schedule, 0),
-		logRepo:      logRepo,
-		scheduleRepo: scheduleRepo,
-		stopChan:     make(chan struct{}),
+		zones:         zones,
+		schedules:     make([]models.Schedule, 0),
+		rules:         make(map[string]*rrule.Rule),
+		overrides:     make([]models.CalendarOverride, 0),
+		patterns:      make(map[int64]models.RingPattern),
+		location:      time.UTC,
+		catchUpPolicy: models.CatchUpFireLate,
+		catchUpGrace:  models.DefaultCatchUpGrace,
+		logRepo:       logRepo,
+		scheduleRepo:  scheduleRepo,
+		clock:         clock,
+		events:        events,
+		fired:         make(map[string]time.Time),
+		stopChan:      make(chan struct{}),
+		wakeChan:      make(chan struct{}, 1),
 	}
 }
 
@@ -41,103 +90,353 @@ func (s *SchedulerService) Stop() {
 	close(s.stopChan)
 }
 
-// run is the main scheduler loop
+// run is the main scheduler loop. Instead of polling on a fixed ticker it
+// sleeps until the next due trigger (or at most maxSleep), then processes
+// every trigger that fell due since the previous wake-up. This keeps bells
+// aligned to the second and lets stalls and clock steps be detected.
 func (s *SchedulerService) run() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	last := s.clock.Now()
+
+	// Changes made before the loop started are already taken into account
+	select {
+	case <-s.wakeChan:
+	default:
+	}
 
 	for {
+		timer := s.clock.NewTimer(s.untilNextWakeup(last))
 		select {
 		case <-s.stopChan:
+			timer.Stop()
 			return
-		case <-ticker.C:
-			s.checkSchedules()
-			s.checkForScheduleReset()
+		case <-s.wakeChan:
+			// Schedules or settings changed, recompute the next wake-up
+			timer.Stop()
+		case <-timer.C():
 		}
+
+		now := s.clock.Now()
+		s.tick(last, now)
+		last = now
+	}
+}
+
+// tick processes everything that fell due between two wake-ups
+func (s *SchedulerService) tick(last, now time.Time) {
+	// Wall-clock and monotonic elapsed time only differ when the system
+	// clock was stepped (NTP, manual change, RTC correction after boot)
+	wallElapsed := now.Round(0).Sub(last.Round(0))
+	if jump := wallElapsed - now.Sub(last); jump > clockJumpThreshold || jump < -clockJumpThreshold {
+		fmt.Printf("Detected wall clock jump of %v\n", jump)
+	}
+
+	from := last.Round(0)
+	if to := now.Round(0); to.Sub(from) > maxCatchUpWindow {
+		fmt.Printf("Scheduler was suspended for %v, only catching up on the last %v\n",
+			to.Sub(from), maxCatchUpWindow)
+		from = to.Add(-maxCatchUpWindow)
 	}
+
+	s.processDue(from, now.Round(0))
+	s.checkForScheduleReset(last, now)
+	s.announceSchedule()
 }
 
-// checkSchedules checks if any schedules should be triggered
-func (s *SchedulerService) checkSchedules() {
+// announceSchedule publishes schedule-activated when the schedule that runs
+// today differs from the one last announced
+func (s *SchedulerService) announceSchedule() {
+	s.mu.Lock()
+	event := s.currentSchedule()
+	changed := s.announced == nil || *s.announced != event.ScheduleID
+	s.announced = &event.ScheduleID
+	s.mu.Unlock()
+
+	if changed {
+		s.events.Publish(EventScheduleActivated, event)
+	}
+}
+
+// CurrentSchedule describes the schedule that runs today
+func (s *SchedulerService) CurrentSchedule() ScheduleEvent {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.currentSchedule()
+}
 
-	now := time.Now()
-	currentTime := now.Format("15:04")
-	currentDay := now.Weekday().String()
+// currentSchedule resolves the schedule running now. Callers must hold at
+// least a read lock.
+func (s *SchedulerService) currentSchedule() ScheduleEvent {
+	now := s.clock.Now().In(s.location)
+	if activation := s.activationAt(now); activation != nil {
+		schedule := s.findSchedule(func(schedule models.Schedule) bool { return schedule.ID == activation.ScheduleID })
+		return ScheduleEvent{ScheduleID: schedule.ID, ScheduleName: schedule.Name, ActivationID: activation.ID}
+	}
 
-	// Find the active schedule
-	var activeSchedule *models.Schedule
-	for _, schedule := range s.schedules {
-		if schedule.IsActive {
-			schedule := schedule // Create a copy to avoid pointer issues
-			activeSchedule = &schedule
-			break
+	schedule := s.resolveSchedule(now)
+	if schedule == nil {
+		return ScheduleEvent{}
+	}
+	return ScheduleEvent{ScheduleID: schedule.ID, ScheduleName: schedule.Name, IsTemporary: schedule.IsTemporary}
+}
+
+// Events returns the bus on which the scheduler publishes state changes
+func (s *SchedulerService) Events() *EventBus {
+	return s.events
+}
+
+// processDue fires or records every trigger in the window (from, to]
+func (s *SchedulerService) processDue(from, to time.Time) {
+	s.mu.RLock()
+	due := s.occurrencesBetween(from, to)
+	policy, grace := s.catchUpPolicy, s.catchUpGrace
+	emergency := s.emergency
+	s.mu.RUnlock()
+
+	for _, occ := range due {
+		if !s.markFired(occ) {
+			// Already handled, e.g. before the wall clock was stepped back
+			continue
+		}
+
+		if emergency != "" {
+			fmt.Printf("Suppressed trigger %s for schedule %d during %s\n",
+				occ.TimeSlot.TriggerTime, occ.Schedule.ID, emergency)
+			if err := s.logSkipped(occ, "suppressed"); err != nil {
+				fmt.Printf("Failed to log suppressed trigger for schedule %d: %v\n", occ.Schedule.ID, err)
+			}
+			continue
+		}
+
+		lateness := to.Sub(occ.At)
+		if lateness > onTimeTolerance && (policy != models.CatchUpFireLate || lateness > grace) {
+			fmt.Printf("Missed trigger %s for schedule %d (late by %v)\n",
+				occ.TimeSlot.TriggerTime, occ.Schedule.ID, lateness)
+			if err := s.logSkipped(occ, "missed"); err != nil {
+				fmt.Printf("Failed to log missed trigger for schedule %d: %v\n", occ.Schedule.ID, err)
+			}
+			continue
+		}
+
+		if lateness > onTimeTolerance {
+			fmt.Printf("Firing trigger %s for schedule %d late by %v\n",
+				occ.TimeSlot.TriggerTime, occ.Schedule.ID, lateness)
+		}
+		if err := s.triggerSchedule(occ.Schedule, occ.TimeSlot); err != nil {
+			fmt.Printf("Failed to trigger schedule %d: %v\n", occ.Schedule.ID, err)
 		}
 	}
+}
+
+// untilNextWakeup returns how long to sleep before the next trigger, the
+// next local midnight, or maxSleep, whichever comes first
+func (s *SchedulerService) untilNextWakeup(now time.Time) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	// If no active schedule is found, check if there's a default schedule
-	if activeSchedule == nil {
-		for _, schedule := range s.schedules {
-			if schedule.IsDefault {
-				schedule := schedule // Create a copy to avoid pointer issues
-				activeSchedule = &schedule
-				break
+	wallNow := now.Round(0)
+	next := wallNow.Add(maxSleep)
+	if midnight := nextLocalMidnight(wallNow.In(s.location)); midnight.Before(next) {
+		next = midnight
+	}
+	if due := s.occurrencesBetween(wallNow, next); len(due) > 0 {
+		next = due[0].At
+	}
+	// Wake up when an activation starts or ends to announce the change
+	for _, activation := range s.activations {
+		for _, boundary := range []time.Time{activation.StartsAt, activation.EndsAt} {
+			if boundary.After(wallNow) && boundary.Before(next) {
+				next = boundary
 			}
 		}
 	}
 
-	// If still no schedule is found, return without triggering anything
-	if activeSchedule == nil {
-		return
+	wait := next.Sub(wallNow)
+	if wait < 0 {
+		wait = 0
 	}
+	return wait
+}
 
-	// Only check timeslots for the active schedule
-	for _, timeSlot := range activeSchedule.TimeSlots {
-		if timeSlot.TriggerTime == currentTime {
-			var days []string
-			if err := json.Unmarshal([]byte(timeSlot.Days), &days); err != nil {
-				fmt.Printf("Failed to parse days for schedule %d: %v\n", activeSchedule.ID, err)
-				continue
-			}
-			for _, day := range days {
-				if day == currentDay {
-					if err := s.triggerSchedule(*activeSchedule, timeSlot); err != nil {
-						fmt.Printf("Failed to trigger schedule %d: %v\n", activeSchedule.ID, err)
-					}
-					break
+// markFired records that an occurrence was handled and reports whether it
+// was new. Entries older than firedRetention are pruned.
+func (s *SchedulerService) markFired(occ occurrence) bool {
+	s.firedMu.Lock()
+	defer s.firedMu.Unlock()
+
+	key := occ.key()
+	if _, ok := s.fired[key]; ok {
+		return false
+	}
+	for k, at := range s.fired {
+		if occ.At.Sub(at) > firedRetention {
+			delete(s.fired, k)
+		}
+	}
+	s.fired[key] = occ.At
+	return true
+}
+
+// logSkipped records a trigger that did not ring, either "missed" or
+// "suppressed" during an emergency
+func (s *SchedulerService) logSkipped(occ occurrence, trigger string) error {
+	logEntry := &models.LogEntry{
+		Timestamp:    occ.At,
+		Trigger:      trigger,
+		ScheduleID:   occ.Schedule.ID,
+		ScheduleName: occ.Schedule.Name,
+		ScheduleTime: occ.TimeSlot.TriggerTime,
+	}
+	return s.logRepo.Create(logEntry)
+}
+
+// wake interrupts the scheduler sleep so the next wake-up is recomputed
+func (s *SchedulerService) wake() {
+	select {
+	case s.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// resolveSchedule determines which schedule should run on the given date.
+// Calendar overrides take precedence over the active and default schedules.
+// Scheduled activations cover a time of day rather than a date, so callers
+// apply them on top. Callers must hold at least a read lock.
+func (s *SchedulerService) resolveSchedule(now time.Time) *models.Schedule {
+	if override := s.overrideFor(now); override != nil {
+		switch override.Action {
+		case models.CalendarActionNoBells:
+			return nil
+		case models.CalendarActionUseSchedule:
+			if override.ScheduleID != nil {
+				if schedule := s.findSchedule(func(schedule models.Schedule) bool {
+					return schedule.ID == *override.ScheduleID
+				}); schedule != nil {
+					return schedule
 				}
 			}
+			// The referenced schedule no longer exists, fall back to the usual selection
+		case models.CalendarActionUseDefault:
+			return s.findSchedule(func(schedule models.Schedule) bool { return schedule.IsDefault })
+		}
+	}
+
+	// Find the active schedule
+	if schedule := s.findSchedule(func(schedule models.Schedule) bool { return schedule.IsActive }); schedule != nil {
+		return schedule
+	}
+
+	// If no active schedule is found, fall back to the default schedule
+	return s.findSchedule(func(schedule models.Schedule) bool { return schedule.IsDefault })
+}
+
+// activationAt returns the activation in effect at the given instant. An
+// activation whose schedule was deleted is ignored. Callers must hold at
+// least a read lock.
+func (s *SchedulerService) activationAt(at time.Time) *models.ScheduleActivation {
+	for i := range s.activations {
+		activation := &s.activations[i]
+		if !activation.Covers(at) {
+			continue
+		}
+		if s.findSchedule(func(schedule models.Schedule) bool { return schedule.ID == activation.ScheduleID }) != nil {
+			return activation
 		}
 	}
+	return nil
 }
 
-// triggerSchedule triggers a specific schedule and logs the event
-func (s *SchedulerService) triggerSchedule(schedule models.Schedule, timeSlot models.TimeSlot) error {
-	if err := s.gpio.Trigger(); err != nil {
-		return fmt.Errorf("failed to trigger GPIO: %w", err)
+// overrideFor returns the calendar override that applies to the given date.
+// When several overrides cover the date the one with the shortest span wins,
+// so a single exam day inside a holiday range is still honoured.
+func (s *SchedulerService) overrideFor(now time.Time) *models.CalendarOverride {
+	var match *models.CalendarOverride
+	for i := range s.overrides {
+		override := &s.overrides[i]
+		if !override.Covers(now) {
+			continue
+		}
+		if match == nil || override.Span() < match.Span() ||
+			(override.Span() == match.Span() && override.ID > match.ID) {
+			match = override
+		}
+	}
+	return match
+}
+
+// findSchedule returns a copy of the first schedule matching the predicate
+func (s *SchedulerService) findSchedule(match func(models.Schedule) bool) *models.Schedule {
+	for _, schedule := range s.schedules {
+		if match(schedule) {
+			schedule := schedule // Create a copy to avoid pointer issues
+			return &schedule
+		}
 	}
+	return nil
+}
 
-	logEntry := &models.LogEntry{
-		Timestamp:    time.Now(),
-		Trigger:      "schedule",
-		ScheduleID:   schedule.ID,
-		ScheduleName: schedule.Name,
-		ScheduleTime: timeSlot.TriggerTime,
+// triggerSchedule rings the zones of a time slot and logs the event per zone
+func (s *SchedulerService) triggerSchedule(schedule models.Schedule, timeSlot models.TimeSlot) error {
+	zoneIDs, err := timeSlot.ZoneIDs()
+	if err != nil {
+		return fmt.Errorf("invalid zones for time slot %d: %w", timeSlot.ID, err)
 	}
 
-	if err := s.logRepo.Create(logEntry); err != nil {
-		return fmt.Errorf("failed to create log entry: %w", err)
+	patternName, pattern := s.patternFor(timeSlot.PatternID)
+	rung, triggerErr := s.zones.Trigger(zoneIDs, pattern)
+	for _, zone := range rung {
+		logEntry := &models.LogEntry{
+			Timestamp:    s.clock.Now(),
+			Trigger:      "schedule",
+			ScheduleID:   schedule.ID,
+			ScheduleName: schedule.Name,
+			ScheduleTime: timeSlot.TriggerTime,
+			ZoneID:       zone.ID,
+			ZoneName:     zone.Name,
+			PatternName:  patternName,
+		}
+		if err := s.logRepo.Create(logEntry); err != nil {
+			return fmt.Errorf("failed to create log entry: %w", err)
+		}
 	}
 
+	if triggerErr != nil {
+		return fmt.Errorf("failed to trigger bell: %w", triggerErr)
+	}
 	return nil
 }
 
+// patternFor looks up a ring pattern. A missing or invalid pattern falls back
+// to a single ring so the bell still sounds.
+func (s *SchedulerService) patternFor(patternID *int64) (string, []time.Duration) {
+	if patternID == nil {
+		return "", nil
+	}
+
+	s.mu.RLock()
+	pattern, ok := s.patterns[*patternID]
+	s.mu.RUnlock()
+	if !ok {
+		fmt.Printf("Ring pattern %d not found, ringing once\n", *patternID)
+		return "", nil
+	}
+
+	durations, err := pattern.Durations()
+	if err != nil {
+		fmt.Printf("Invalid ring pattern %s, ringing once: %v\n", pattern.Name, err)
+		return "", nil
+	}
+	return pattern.Name, durations
+}
+
 // UpdateSchedules updates the list of active schedules
 func (s *SchedulerService) UpdateSchedules(schedules []models.Schedule) {
+	rules := parseRules(schedules)
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.schedules = schedules
+	s.rules = rules
+	s.mu.Unlock()
+	s.announceSchedule()
+	s.wake()
 }
 
 // GetSchedules returns the current list of schedules
@@ -147,10 +446,54 @@ func (s *SchedulerService) GetSchedules() []models.Schedule {
 	return s.schedules
 }
 
-// TriggerNow manually triggers the bell
-func (s *SchedulerService) TriggerNow(userID int64, username string) error {
-	if err := s.gpio.Trigger(); err != nil {
-		return fmt.Errorf("failed to trigger GPIO: %w", err)
+// UpdateCalendarOverrides updates the list of calendar overrides
+func (s *SchedulerService) UpdateCalendarOverrides(overrides []models.CalendarOverride) {
+	s.mu.Lock()
+	s.overrides = overrides
+	s.mu.Unlock()
+	s.announceSchedule()
+	s.wake()
+}
+
+// UpdateActivations replaces the scheduled activations
+func (s *SchedulerService) UpdateActivations(activations []models.ScheduleActivation) {
+	s.mu.Lock()
+	s.activations = activations
+	s.mu.Unlock()
+	s.announceSchedule()
+	s.wake()
+}
+
+// UpdatePatterns replaces the ring pattern library
+func (s *SchedulerService) UpdatePatterns(patterns []models.RingPattern) {
+	byID := make(map[int64]models.RingPattern, len(patterns))
+	for _, pattern := range patterns {
+		byID[pattern.ID] = pattern
+	}
+
+	s.mu.Lock()
+	s.patterns = byID
+	s.mu.Unlock()
+}
+
+// GetCalendarOverrides returns the current list of calendar overrides
+func (s *SchedulerService) GetCalendarOverrides() []models.CalendarOverride {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overrides
+}
+
+// TriggerNow manually rings the given zones, or every zone when none are
+// given, optionally playing a ring pattern
+func (s *SchedulerService) TriggerNow(userID int64, username string, zoneIDs []int64, patternID *int64) error {
+	if s.EmergencyMode() != "" {
+		return ErrEmergencyActive
+	}
+
+	patternName, pattern := s.patternFor(patternID)
+	rung, triggerErr := s.zones.Trigger(zoneIDs, pattern)
+	if len(rung) == 0 && triggerErr != nil {
+		return fmt.Errorf("failed to trigger bell: %w", triggerErr)
 	}
 
 	// Get the default schedule or first available schedule
@@ -174,43 +517,176 @@ func (s *SchedulerService) TriggerNow(userID int64, username string) error {
 	}
 	s.mu.RUnlock()
 
-	logEntry := &models.LogEntry{
-		Timestamp: time.Now(),
-		Trigger:   "manual",
-		UserID:    userID,
-		Username:  username,
+	for _, zone := range rung {
+		logEntry := &models.LogEntry{
+			Timestamp:   s.clock.Now(),
+			Trigger:     "manual",
+			UserID:      userID,
+			Username:    username,
+			ZoneID:      zone.ID,
+			ZoneName:    zone.Name,
+			PatternName: patternName,
+		}
+
+		// Include schedule information if a schedule was found
+		if scheduleFound {
+			logEntry.ScheduleID = schedule.ID
+			logEntry.ScheduleName = schedule.Name
+			// For manual triggers, we don't have a specific time slot, so we'll use the current time
+			logEntry.ScheduleTime = s.localNow().Format("15:04")
+		}
+
+		if err := s.logRepo.Create(logEntry); err != nil {
+			return fmt.Errorf("failed to create log entry: %w", err)
+		}
 	}
 
-	// Include schedule information if a schedule was found
-	if scheduleFound {
-		logEntry.ScheduleID = schedule.ID
-		logEntry.ScheduleName = schedule.Name
-		// For manual triggers, we don't have a specific time slot, so we'll use the current time
-		logEntry.ScheduleTime = time.Now().Format("15:04")
+	if triggerErr != nil {
+		return fmt.Errorf("failed to trigger bell: %w", triggerErr)
 	}
+	return nil
+}
 
-	if err := s.logRepo.Create(logEntry); err != nil {
-		return fmt.Errorf("failed to create log entry: %w", err)
+// StopNow stops the bells ringing in the given zones, or in every zone when
+// none are given, and drops any queued rings
+func (s *SchedulerService) StopNow(userID int64, username string, zoneIDs []int64) error {
+	if s.EmergencyMode() != "" {
+		return ErrEmergencyActive
 	}
 
+	stopped, stopErr := s.zones.Cancel(zoneIDs)
+	for _, zone := range stopped {
+		logEntry := &models.LogEntry{
+			Timestamp: s.clock.Now(),
+			Trigger:   "stopped",
+			UserID:    userID,
+			Username:  username,
+			ZoneID:    zone.ID,
+			ZoneName:  zone.Name,
+		}
+		if err := s.logRepo.Create(logEntry); err != nil {
+			return fmt.Errorf("failed to create log entry: %w", err)
+		}
+	}
+
+	if stopErr != nil {
+		return fmt.Errorf("failed to stop bell: %w", stopErr)
+	}
 	return nil
 }
 
-// SetDuration updates the bell ring duration
+// StartEmergency signals the emergency mode on every zone and suppresses
+// scheduled bells until StopEmergency
+func (s *SchedulerService) StartEmergency(mode string) error {
+	pattern := models.EmergencyPattern(mode)
+	if pattern == nil {
+		return fmt.Errorf("unknown emergency mode %q", mode)
+	}
+
+	s.mu.Lock()
+	s.emergency = mode
+	s.mu.Unlock()
+	s.events.Publish(EventEmergencyStarted, EmergencyEvent{Mode: mode})
+	return s.zones.StartEmergency(pattern)
+}
+
+// StopEmergency ends the emergency signal and resumes scheduled bells
+func (s *SchedulerService) StopEmergency() error {
+	s.mu.Lock()
+	mode := s.emergency
+	s.emergency = ""
+	s.mu.Unlock()
+	if mode != "" {
+		s.events.Publish(EventEmergencyStopped, EmergencyEvent{Mode: mode})
+	}
+	return s.zones.StopEmergency()
+}
+
+// EmergencyMode returns the active emergency mode, or "" if there is none
+func (s *SchedulerService) EmergencyMode() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.emergency
+}
+
+// SetOverlapPolicy sets how a trigger is handled while a zone is ringing
+func (s *SchedulerService) SetOverlapPolicy(policy string) {
+	s.zones.SetOverlapPolicy(policy)
+}
+
+// SetDuration updates the ring duration of zones without their own duration
 func (s *SchedulerService) SetDuration(duration time.Duration) {
-	s.gpio.SetDuration(duration)
+	s.zones.SetDefaultDuration(duration)
+}
+
+// UpdateZones reloads the bell zones, opening and closing outputs as needed
+func (s *SchedulerService) UpdateZones(zones []models.Zone) error {
+	return s.zones.Load(zones)
 }
 
-// IsActive returns whether the bell is currently ringing
+// Zones returns the zone manager driving the bell outputs
+func (s *SchedulerService) Zones() *ZoneManager {
+	return s.zones
+}
+
+// IsActive returns whether the bell of any zone is currently ringing
 func (s *SchedulerService) IsActive() bool {
-	return s.gpio.IsActive()
+	return s.zones.IsActive()
+}
+
+// SetTimezone changes the timezone used to evaluate trigger times.
+// The change takes effect immediately.
+func (s *SchedulerService) SetTimezone(name string) error {
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.location = loc
+	s.mu.Unlock()
+	s.wake()
+	return nil
 }
 
-// checkForScheduleReset checks if it's midnight and resets any temporary schedules
-func (s *SchedulerService) checkForScheduleReset() {
-	now := time.Now()
-	// Check if it's midnight (00:00)
-	if now.Hour() == 0 && now.Minute() == 0 {
+// SetCatchUpPolicy configures how triggers that were skipped by a stall or
+// clock jump are handled: fired late within the grace window, or logged as missed
+func (s *SchedulerService) SetCatchUpPolicy(policy string, grace time.Duration) {
+	if policy == "" {
+		policy = models.CatchUpFireLate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catchUpPolicy = policy
+	s.catchUpGrace = grace
+}
+
+// Location returns the timezone used to evaluate trigger times
+func (s *SchedulerService) Location() *time.Location {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.location
+}
+
+// Now returns the current time according to the scheduler's clock
+func (s *SchedulerService) Now() time.Time {
+	return s.clock.Now()
+}
+
+// localNow returns the current time in the configured timezone
+func (s *SchedulerService) localNow() time.Time {
+	return s.clock.Now().In(s.Location())
+}
+
+// checkForScheduleReset resets any temporary schedules once midnight has
+// passed in the configured timezone between two wake-ups
+func (s *SchedulerService) checkForScheduleReset(last, now time.Time) {
+	loc := s.Location()
+	previous := last.Round(0).In(loc)
+	current := now.Round(0).In(loc)
+	// Check if the local date moved forward since the last wake-up
+	if current.After(previous) && previous.Format(models.CalendarDateFormat) != current.Format(models.CalendarDateFormat) {
 		// Get all schedules
 		schedules, err := s.scheduleRepo.GetAll()
 		if err != nil {
@@ -218,39 +694,47 @@ func (s *SchedulerService) checkForScheduleReset() {
 			return
 		}
 
-		// Find the default schedule
+		// Temporary schedules are set active by SetTemporary
+		var temporary []models.Schedule
 		var defaultSchedule *models.Schedule
-		for _, s := range schedules {
-			if s.IsDefault {
-				defaultSchedule = &s
-				break
+		for i := range schedules {
+			if schedules[i].IsTemporary && (schedules[i].IsActive || schedules[i].IsDefault) {
+				temporary = append(temporary, schedules[i])
+			} else if schedules[i].IsDefault && defaultSchedule == nil {
+				defaultSchedule = &schedules[i]
 			}
 		}
 
+		if len(temporary) == 0 {
+			return
+		}
 		if defaultSchedule == nil {
 			fmt.Println("No default schedule found for reset")
 			return
 		}
 
-		// Check for temporary schedules that need to be reset
-		for _, schedule := range schedules {
-			if schedule.IsTemporary && schedule.IsDefault {
-				// Reset this schedule and set the default schedule as active
-				if err := s.scheduleRepo.SetDefault(defaultSchedule.ID); err != nil {
-					fmt.Printf("Failed to reset temporary schedule %d: %v\n", schedule.ID, err)
-					continue
-				}
-
-				// Update the schedule to no longer be temporary
-				schedule.IsTemporary = false
-				schedule.IsDefault = false
-				if err := s.scheduleRepo.Update(&schedule); err != nil {
-					fmt.Printf("Failed to update temporary schedule %d: %v\n", schedule.ID, err)
-				}
+		// Reset the temporary schedules and hand control back to the default schedule
+		for _, schedule := range temporary {
+			if err := s.scheduleRepo.SetActive(defaultSchedule.ID); err != nil {
+				fmt.Printf("Failed to reset temporary schedule %d: %v\n", schedule.ID, err)
+				continue
+			}
 
-				fmt.Printf("Reset temporary schedule %s (ID: %d) to default schedule %s (ID: %d)\n",
-					schedule.Name, schedule.ID, defaultSchedule.Name, defaultSchedule.ID)
+			// Update the schedule to no longer be temporary
+			schedule.IsTemporary = false
+			schedule.IsDefault = false
+			if err := s.scheduleRepo.Update(&schedule, models.RevisionMeta{}); err != nil {
+				fmt.Printf("Failed to update temporary schedule %d: %v\n", schedule.ID, err)
 			}
+
+			fmt.Printf("Reset temporary schedule %s (ID: %d) to default schedule %s (ID: %d)\n",
+				schedule.Name, schedule.ID, defaultSchedule.Name, defaultSchedule.ID)
+			s.events.Publish(EventTemporaryReset, TemporaryResetEvent{
+				ScheduleID:          schedule.ID,
+				ScheduleName:        schedule.Name,
+				DefaultScheduleID:   defaultSchedule.ID,
+				DefaultScheduleName: defaultSchedule.Name,
+			})
 		}
 
 		// Update the scheduler with the updated schedules
@@ -0,0 +1,717 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type schedulerFixture struct {
+	db           *gorm.DB
+	scheduler    *SchedulerService
+	clock        *FakeClock
+	zones        *ZoneManager
+	output       *MockDriver
+	logRepo      *store.LogRepository
+	scheduleRepo *store.ScheduleRepository
+}
+
+func newSchedulerFixture(t *testing.T, start time.Time, timezone string) *schedulerFixture {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Schedule{}, &models.TimeSlot{}, &models.ScheduleRevision{}, &models.ScheduleActivation{}, &models.LogEntry{},
+		&models.CalendarOverride{}, &models.EmergencyState{}, &models.EmergencyLogEntry{}))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	clock := NewFakeClock(start)
+	zones := NewZoneManager(5*time.Second, clock)
+	require.NoError(t, zones.Load([]models.Zone{{BaseModel: models.BaseModel{ID: 1}, Name: "Main", OutputDriver: models.OutputDriverMock, IsDefault: true}}))
+	t.Cleanup(zones.Close)
+	output, _ := zones.Output(1)
+
+	logRepo := store.NewLogRepository(db)
+	scheduleRepo := store.NewScheduleRepository(db)
+	scheduler := NewSchedulerService(zones, logRepo, scheduleRepo, clock)
+	require.NoError(t, scheduler.SetTimezone(timezone))
+
+	return &schedulerFixture{
+		db:           db,
+		scheduler:    scheduler,
+		clock:        clock,
+		zones:        zones,
+		output:       output.(*MockDriver),
+		logRepo:      logRepo,
+		scheduleRepo: scheduleRepo,
+	}
+}
+
+// addSchedule persists a schedule and reloads the scheduler
+func (f *schedulerFixture) addSchedule(t *testing.T, schedule *models.Schedule) {
+	require.NoError(t, f.scheduleRepo.Create(schedule, models.RevisionMeta{}))
+	f.reload(t)
+}
+
+func (f *schedulerFixture) reload(t *testing.T) {
+	schedules, err := f.scheduleRepo.GetAll()
+	require.NoError(t, err)
+	f.scheduler.UpdateSchedules(schedules)
+}
+
+// simulate drives the scheduler exactly like run does, but synchronously,
+// jumping the fake clock from one wake-up to the next until the given time
+func (f *schedulerFixture) simulate(t *testing.T, until time.Time) {
+	last := f.clock.Now()
+	for last.Before(until) {
+		next := last.Add(f.scheduler.untilNextWakeup(last))
+		if next.After(until) {
+			next = until
+		}
+		// Let rings in progress end at their exact time first
+		for deadline, ok := f.clock.NextDeadline(); ok && deadline.Before(next); deadline, ok = f.clock.NextDeadline() {
+			f.clock.Set(deadline)
+			f.waitForRelaysSettled(t)
+		}
+		f.clock.Set(next)
+		f.waitForRelaysSettled(t)
+
+		f.scheduler.tick(last, next)
+		// Make sure ring goroutines are sleeping on the fake clock before
+		// the clock is moved again
+		f.waitForRelaysSettled(t)
+		last = next
+	}
+}
+
+// waitForRelaysSettled waits until every active relay is sleeping on the
+// fake clock, so that moving the clock switches them off deterministically
+func (f *schedulerFixture) waitForRelaysSettled(t *testing.T) {
+	deadline := time.Now().Add(time.Second)
+	for f.activeRelays() != f.clock.Pending() {
+		if time.Now().After(deadline) {
+			t.Fatal("relays did not settle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (f *schedulerFixture) activeRelays() int {
+	active := 0
+	for _, zone := range f.zones.Zones() {
+		f.zones.mu.RLock()
+		if f.zones.outputs[zone.ID].gpio.IsActive() {
+			active++
+		}
+		f.zones.mu.RUnlock()
+	}
+	return active
+}
+
+// logs returns the log entries of the given trigger type, oldest first
+func (f *schedulerFixture) logs(t *testing.T, trigger string) []models.LogEntry {
+	entries, err := f.logRepo.GetAll()
+	require.NoError(t, err)
+
+	var result []models.LogEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Trigger == trigger {
+			result = append(result, entries[i])
+		}
+	}
+	return result
+}
+
+func weekdaySlot(triggerTime string, days ...string) models.TimeSlot {
+	return models.TimeSlot{
+		TriggerTime: triggerTime,
+		Days:        `["` + strings.Join(days, `","`) + `"]`,
+	}
+}
+
+var schoolDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+
+func TestScheduler_WeekOfBells(t *testing.T) {
+	// Monday 2026-03-02 00:00 UTC
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.addSchedule(t, &models.Schedule{
+		Name:     "Regular",
+		IsActive: true,
+		TimeSlots: []models.TimeSlot{
+			weekdaySlot("08:00", schoolDays...),
+			weekdaySlot("12:30", schoolDays...),
+			weekdaySlot("10:00", "Saturday"),
+		},
+	})
+
+	f.simulate(t, start.AddDate(0, 0, 7))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 11)
+	assert.Equal(t, time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC), rings[0].Timestamp.UTC())
+	assert.Equal(t, time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC), rings[1].Timestamp.UTC())
+	assert.Equal(t, time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC), rings[10].Timestamp.UTC())
+	assert.Empty(t, f.logs(t, "missed"))
+
+	// Every ring switches the relay on and back off after the ring duration
+	events := f.output.Events()
+	require.Len(t, events, 22)
+	assert.Equal(t, OutputEvent{On: true, At: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)}, events[0])
+	assert.Equal(t, OutputEvent{On: false, At: time.Date(2026, 3, 2, 8, 0, 5, 0, time.UTC)}, events[1])
+}
+
+func TestScheduler_Recurrence(t *testing.T) {
+	// Monday 2026-03-02 00:00 UTC
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+
+	// A and B weeks alternate from the week of 2026-03-02
+	weekA := weekdaySlot("08:00", "Monday")
+	weekA.WeekInterval = 2
+	weekA.AnchorDate = "2026-03-02"
+	weekB := weekdaySlot("08:30", "Monday")
+	weekB.WeekInterval = 2
+	weekB.AnchorDate = "2026-03-09"
+	// Summer term timing only from 2026-03-10 to 2026-03-17
+	summer := weekdaySlot("12:00", schoolDays...)
+	summer.StartDate = "2026-03-10"
+	summer.EndDate = "2026-03-17"
+	// Last Friday of the month, whatever the days say
+	staffMeeting := weekdaySlot("15:00")
+	staffMeeting.RRule = "FREQ=MONTHLY;BYDAY=-1FR"
+	// Three induction mornings, counted from the anchor
+	induction := weekdaySlot("10:00")
+	induction.RRule = "FREQ=WEEKLY;BYDAY=TU,WE;COUNT=3"
+	induction.AnchorDate = "2026-03-02"
+	for _, slot := range []models.TimeSlot{weekA, weekB, summer, staffMeeting, induction} {
+		require.NoError(t, slot.ValidateRecurrence())
+	}
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekA, weekB, summer, staffMeeting, induction}})
+
+	var got []string
+	for _, from := range []time.Time{start, start.Add(MaxUpcomingWindow)} {
+		for _, bell := range f.scheduler.Upcoming(from, MaxUpcomingWindow) {
+			got = append(got, bell.Date+" "+bell.Time)
+		}
+	}
+	assert.Equal(t, []string{
+		"2026-03-02 08:00",
+		"2026-03-03 10:00", "2026-03-04 10:00",
+		"2026-03-09 08:30",
+		"2026-03-10 10:00", "2026-03-10 12:00", "2026-03-11 12:00", "2026-03-12 12:00", "2026-03-13 12:00",
+		"2026-03-16 08:00", "2026-03-16 12:00", "2026-03-17 12:00",
+		"2026-03-23 08:30",
+		"2026-03-27 15:00",
+	}, got)
+
+	invalid := weekdaySlot("08:00", "Monday")
+	invalid.WeekInterval = 2
+	assert.Error(t, invalid.ValidateRecurrence(), "an interval needs an anchor")
+	invalid = weekdaySlot("08:00")
+	invalid.RRule = "FREQ=WEEKLY;BYHOUR=8"
+	assert.Error(t, invalid.ValidateRecurrence())
+}
+
+func TestScheduler_CalendarOverrides(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+
+	regular := &models.Schedule{Name: "Regular", IsActive: true, IsDefault: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}
+	exams := &models.Schedule{Name: "Exams",
+		TimeSlots: []models.TimeSlot{weekdaySlot("09:15", schoolDays...)}}
+	f.addSchedule(t, regular)
+	f.addSchedule(t, exams)
+
+	f.scheduler.UpdateCalendarOverrides([]models.CalendarOverride{
+		{Name: "Holiday week", StartDate: "2026-03-03", EndDate: "2026-03-05", Action: models.CalendarActionNoBells},
+		{Name: "Exam day", StartDate: "2026-03-04", EndDate: "2026-03-04", Action: models.CalendarActionUseSchedule, ScheduleID: &exams.ID},
+	})
+
+	f.simulate(t, start.AddDate(0, 0, 5))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 3)
+	assert.Equal(t, "Regular", rings[0].ScheduleName)
+	assert.Equal(t, time.Date(2026, 3, 4, 9, 15, 0, 0, time.UTC), rings[1].Timestamp.UTC())
+	assert.Equal(t, "Exams", rings[1].ScheduleName)
+	assert.Equal(t, time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC), rings[2].Timestamp.UTC())
+}
+
+func TestScheduler_ScheduledActivation(t *testing.T) {
+	// Monday 2026-03-02 00:00 UTC
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	events, unsubscribe := f.scheduler.Events().Subscribe()
+	defer unsubscribe()
+
+	regular := &models.Schedule{Name: "Regular", IsActive: true, IsDefault: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...), weekdaySlot("16:00", schoolDays...)}}
+	assembly := &models.Schedule{Name: "Assembly",
+		TimeSlots: []models.TimeSlot{weekdaySlot("07:30", schoolDays...), weekdaySlot("09:00", schoolDays...)}}
+	f.addSchedule(t, regular)
+	f.addSchedule(t, assembly)
+
+	cancelledAt := start
+	f.scheduler.UpdateActivations([]models.ScheduleActivation{
+		{BaseModel: models.BaseModel{ID: 1}, ScheduleID: assembly.ID,
+			StartsAt: time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC), EndsAt: time.Date(2026, 3, 3, 15, 0, 0, 0, time.UTC)},
+		{BaseModel: models.BaseModel{ID: 2}, ScheduleID: assembly.ID, CancelledAt: &cancelledAt,
+			StartsAt: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+	})
+
+	bells := f.scheduler.Upcoming(time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), 24*time.Hour)
+	require.Len(t, bells, 3)
+	assert.Equal(t, int64(1), bells[0].ActivationID)
+	assert.Zero(t, bells[2].ActivationID)
+
+	f.simulate(t, start.AddDate(0, 0, 3))
+
+	var got []string
+	for _, ring := range f.logs(t, "schedule") {
+		got = append(got, ring.Timestamp.UTC().Format("Mon 15:04 ")+ring.ScheduleName)
+	}
+	// The activation replaces Regular's 08:00 bell on Tuesday, then Regular
+	// resumes. The cancelled activation changes nothing.
+	assert.Equal(t, []string{
+		"Mon 08:00 Regular", "Mon 16:00 Regular",
+		"Tue 07:30 Assembly", "Tue 09:00 Assembly", "Tue 16:00 Regular",
+		"Wed 08:00 Regular", "Wed 16:00 Regular",
+	}, got)
+
+	var activated []ScheduleEvent
+	for len(events) > 0 {
+		if event := <-events; event.Type == EventScheduleActivated {
+			activated = append(activated, event.Data.(ScheduleEvent))
+		}
+	}
+	require.Len(t, activated, 3)
+	assert.Equal(t, ScheduleEvent{ScheduleID: assembly.ID, ScheduleName: "Assembly", ActivationID: 1}, activated[1])
+	assert.Equal(t, "Regular", activated[2].ScheduleName)
+	assert.Zero(t, activated[2].ActivationID)
+}
+
+func TestScheduler_Zones(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	require.NoError(t, f.scheduler.UpdateZones([]models.Zone{
+		{BaseModel: models.BaseModel{ID: 1}, Name: "Main", OutputDriver: models.OutputDriverMock, IsDefault: true},
+		{BaseModel: models.BaseModel{ID: 2}, Name: "Gym", OutputDriver: models.OutputDriverMock, RingDuration: 10},
+	}))
+	gymOutput, ok := f.zones.Output(2)
+	require.True(t, ok)
+	gym := gymOutput.(*MockDriver)
+
+	everywhere := weekdaySlot("08:00", "Monday")
+	gymOnly := weekdaySlot("09:00", "Monday")
+	gymOnly.Zones = "[2]"
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{everywhere, gymOnly}})
+
+	f.simulate(t, start.Add(12*time.Hour))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 3)
+	// Both zones ring at 08:00, only the gym at 09:00
+	assert.ElementsMatch(t, []string{"Main", "Gym"}, []string{rings[0].ZoneName, rings[1].ZoneName})
+	assert.Equal(t, "Gym", rings[2].ZoneName)
+	assert.Len(t, f.output.Events(), 2)
+
+	// The gym rings for its own duration
+	events := gym.Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, OutputEvent{On: false, At: time.Date(2026, 3, 2, 9, 0, 10, 0, time.UTC)}, events[3])
+
+	// A manual trigger can target a single zone
+	require.NoError(t, f.scheduler.TriggerNow(1, "admin", []int64{1}, nil))
+	f.waitForRelaysSettled(t)
+	f.clock.Advance(5 * time.Second)
+	f.waitForRelaysSettled(t)
+	assert.Len(t, f.output.Events(), 4)
+	assert.Len(t, gym.Events(), 4)
+	assert.Error(t, f.scheduler.TriggerNow(1, "admin", []int64{42}, nil))
+}
+
+func TestScheduler_SameMinuteSlotsOnDifferentZones(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	require.NoError(t, f.scheduler.UpdateZones([]models.Zone{
+		{BaseModel: models.BaseModel{ID: 1}, Name: "Main", OutputDriver: models.OutputDriverMock, IsDefault: true},
+		{BaseModel: models.BaseModel{ID: 2}, Name: "Gym", OutputDriver: models.OutputDriverMock},
+	}))
+
+	main := weekdaySlot("08:00", "Monday")
+	main.Zones = "[1]"
+	gym := weekdaySlot("08:00", "Monday")
+	gym.Zones = "[2]"
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{main, gym}})
+
+	f.simulate(t, start.Add(12*time.Hour))
+
+	// Each slot rings its own zone although both are due at the same minute
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 2)
+	assert.ElementsMatch(t, []string{"Main", "Gym"}, []string{rings[0].ZoneName, rings[1].ZoneName})
+}
+
+func TestScheduler_RingPatterns(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.scheduler.UpdatePatterns([]models.RingPattern{
+		{BaseModel: models.BaseModel{ID: 7}, Name: "Fire drill", Steps: "[1000,500,1000,500,1000]"},
+	})
+
+	patternID := int64(7)
+	missingID := int64(8)
+	drill := weekdaySlot("08:00", "Monday")
+	drill.PatternID = &patternID
+	fallback := weekdaySlot("09:00", "Monday")
+	fallback.PatternID = &missingID
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{drill, fallback}})
+
+	f.simulate(t, start.Add(12*time.Hour))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 2)
+	assert.Equal(t, "Fire drill", rings[0].PatternName)
+	assert.Empty(t, rings[1].PatternName)
+
+	at := func(h, m, s, ms int) time.Time {
+		return time.Date(2026, 3, 2, h, m, s, ms*int(time.Millisecond), time.UTC)
+	}
+	assert.Equal(t, []OutputEvent{
+		{On: true, At: at(8, 0, 0, 0)},
+		{On: false, At: at(8, 0, 1, 0)},
+		{On: true, At: at(8, 0, 1, 500)},
+		{On: false, At: at(8, 0, 2, 500)},
+		{On: true, At: at(8, 0, 3, 0)},
+		{On: false, At: at(8, 0, 4, 0)},
+		// An unknown pattern falls back to a single ring
+		{On: true, At: at(9, 0, 0, 0)},
+		{On: false, At: at(9, 0, 5, 0)},
+	}, f.output.Events())
+}
+
+func TestScheduler_MidnightResetOfTemporarySchedule(t *testing.T) {
+	start := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+
+	regular := &models.Schedule{Name: "Regular", IsDefault: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}
+	assembly := &models.Schedule{Name: "Assembly", IsActive: true, IsTemporary: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("09:00", schoolDays...)}}
+	f.addSchedule(t, regular)
+	f.addSchedule(t, assembly)
+
+	f.simulate(t, start.Add(30*time.Hour))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 2)
+	assert.Equal(t, "Assembly", rings[0].ScheduleName)
+	assert.Equal(t, "Regular", rings[1].ScheduleName)
+	assert.Equal(t, time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC), rings[1].Timestamp.UTC())
+
+	reset, err := f.scheduleRepo.Get(assembly.ID)
+	require.NoError(t, err)
+	assert.False(t, reset.IsTemporary)
+	assert.False(t, reset.IsActive)
+
+	restored, err := f.scheduleRepo.Get(regular.ID)
+	require.NoError(t, err)
+	assert.True(t, restored.IsActive)
+}
+
+func TestScheduler_Upcoming(t *testing.T) {
+	start := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	require.NoError(t, f.scheduler.UpdateZones([]models.Zone{
+		{BaseModel: models.BaseModel{ID: 1}, Name: "Main", OutputDriver: models.OutputDriverMock, IsDefault: true},
+		{BaseModel: models.BaseModel{ID: 2}, Name: "Gym", OutputDriver: models.OutputDriverMock},
+	}))
+	f.scheduler.UpdatePatterns([]models.RingPattern{
+		{BaseModel: models.BaseModel{ID: 7}, Name: "Double", Steps: "[1000,500,1000]"},
+	})
+
+	patternID := int64(7)
+	assemblySlot := weekdaySlot("09:00", schoolDays...)
+	assemblySlot.Zones = "[2]"
+	assemblySlot.PatternID = &patternID
+	regular := &models.Schedule{Name: "Regular", IsDefault: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}
+	assembly := &models.Schedule{Name: "Assembly", IsActive: true, IsTemporary: true,
+		TimeSlots: []models.TimeSlot{assemblySlot}}
+	exams := &models.Schedule{Name: "Exams",
+		TimeSlots: []models.TimeSlot{weekdaySlot("10:30", schoolDays...)}}
+	f.addSchedule(t, regular)
+	f.addSchedule(t, assembly)
+	f.addSchedule(t, exams)
+	f.scheduler.UpdateCalendarOverrides([]models.CalendarOverride{
+		{Name: "Open day", StartDate: "2026-03-04", EndDate: "2026-03-04", Action: models.CalendarActionNoBells},
+		{Name: "Exam day", StartDate: "2026-03-05", EndDate: "2026-03-05", Action: models.CalendarActionUseSchedule, ScheduleID: &exams.ID},
+	})
+
+	bells := f.scheduler.Upcoming(start, 4*24*time.Hour)
+	require.Len(t, bells, 3)
+
+	// The temporary schedule only covers today
+	assert.Equal(t, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), bells[0].At.UTC())
+	assert.Equal(t, "Assembly", bells[0].ScheduleName)
+	assert.True(t, bells[0].IsTemporary)
+	assert.Equal(t, []string{"Gym"}, bells[0].Zones)
+	assert.Equal(t, "Double", bells[0].PatternName)
+
+	assert.Equal(t, "2026-03-03", bells[1].Date)
+	assert.Equal(t, "08:00", bells[1].Time)
+	assert.Equal(t, "Regular", bells[1].ScheduleName)
+	assert.Equal(t, regular.TimeSlots[0].ID, bells[1].TimeSlotID)
+	assert.Equal(t, []string{"Main", "Gym"}, bells[1].Zones)
+	assert.Empty(t, bells[1].OverrideName)
+
+	// No bells on the open day, the exam schedule the day after
+	assert.Equal(t, time.Date(2026, 3, 5, 10, 30, 0, 0, time.UTC), bells[2].At.UTC())
+	assert.Equal(t, "Exams", bells[2].ScheduleName)
+	assert.Equal(t, "Exam day", bells[2].OverrideName)
+
+	// Predicting does not ring or log anything
+	assert.Empty(t, f.logs(t, "schedule"))
+	assert.Empty(t, f.output.Events())
+}
+
+func TestScheduler_CalendarFeed(t *testing.T) {
+	// Midday, so the feed's first day has already started
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	require.NoError(t, f.scheduler.UpdateZones([]models.Zone{
+		{BaseModel: models.BaseModel{ID: 1}, Name: "Main", OutputDriver: models.OutputDriverMock, IsDefault: true},
+		{BaseModel: models.BaseModel{ID: 2}, Name: "Gym", OutputDriver: models.OutputDriverMock},
+	}))
+
+	gymOnly := weekdaySlot("09:00", schoolDays...)
+	gymOnly.Zones = "[2]"
+	gymOnly.Description = "Gym"
+	// Rings at the same time as the gym bell, in the main building
+	assembly := weekdaySlot("09:00", schoolDays...)
+	assembly.Zones = "[1]"
+	assembly.Description = "Assembly"
+	regular := &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...), gymOnly, assembly}}
+	f.addSchedule(t, regular)
+	f.scheduler.UpdateCalendarOverrides([]models.CalendarOverride{
+		{BaseModel: models.BaseModel{ID: 4}, Name: "Open day", StartDate: "2026-03-03", EndDate: "2026-03-03", Action: models.CalendarActionNoBells},
+		{BaseModel: models.BaseModel{ID: 5}, Name: "Next term", StartDate: "2026-04-13", EndDate: "2026-04-17", Action: models.CalendarActionNoBells},
+	})
+
+	feed := &models.CalendarFeed{Name: "Bells", Days: 3}
+	out := string(f.scheduler.CalendarFeed(feed))
+	assert.Equal(t, 7, strings.Count(out, "BEGIN:VEVENT"))
+	// Today's bells before noon are included, the open day has none
+	bellUID := func(at string) *regexp.Regexp {
+		return regexp.MustCompile(`UID:bell-` + at + `-` + fmt.Sprint(regular.ID) + `-[0-9a-f]{8}@bell-scheduler\r\n`)
+	}
+	assert.Len(t, bellUID("20260302T080000Z").FindAllString(out, -1), 1)
+	assert.Len(t, bellUID("20260304T090000Z").FindAllString(out, -1), 2)
+	assert.NotContains(t, out, "20260303T080000Z")
+	assert.Contains(t, out, "UID:override-4@bell-scheduler\r\nDTSTAMP:20260302T120000Z\r\n"+
+		"DTSTART;VALUE=DATE:20260303\r\nDTEND;VALUE=DATE:20260304\r\nSUMMARY:Open day\r\n")
+	assert.NotContains(t, out, "Next term")
+
+	// Every event has its own UID, including bells of one schedule that
+	// ring at the same time in different zones
+	uids := make(map[string]bool)
+	for _, uid := range regexp.MustCompile(`UID:[^\r]+`).FindAllString(out, -1) {
+		assert.False(t, uids[uid], "duplicate %s", uid)
+		uids[uid] = true
+	}
+	assert.Len(t, uids, 7)
+
+	// Saving the schedule again replaces its time slots, but not the UIDs
+	regular.TimeSlots = []models.TimeSlot{weekdaySlot("08:00", schoolDays...), gymOnly, assembly}
+	require.NoError(t, f.scheduleRepo.Update(regular, models.RevisionMeta{}))
+	f.reload(t)
+	assert.Equal(t, out, string(f.scheduler.CalendarFeed(feed)))
+
+	// A zone feed leaves out bells that only ring elsewhere
+	mainZone := int64(1)
+	feed.ZoneID = &mainZone
+	out = string(f.scheduler.CalendarFeed(feed))
+	assert.Equal(t, 5, strings.Count(out, "BEGIN:VEVENT"))
+	assert.NotContains(t, out, "SUMMARY:Gym")
+}
+
+func TestScheduler_PublishesEvents(t *testing.T) {
+	start := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	events, unsubscribe := f.scheduler.Events().Subscribe()
+	defer unsubscribe()
+
+	regular := &models.Schedule{Name: "Regular", IsDefault: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}}
+	assembly := &models.Schedule{Name: "Assembly", IsActive: true, IsTemporary: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("09:00", schoolDays...)}}
+	f.addSchedule(t, regular)
+	f.addSchedule(t, assembly)
+	f.simulate(t, start.Add(20*time.Hour))
+
+	var got []string
+	for len(events) > 0 {
+		event := <-events
+		got = append(got, event.Type)
+		switch data := event.Data.(type) {
+		case BellEvent:
+			assert.Equal(t, "Main", data.ZoneName)
+		case TemporaryResetEvent:
+			assert.Equal(t, "Assembly", data.ScheduleName)
+			assert.Equal(t, "Regular", data.DefaultScheduleName)
+		}
+	}
+
+	assert.Equal(t, []string{
+		EventScheduleActivated, // Regular as the default
+		EventScheduleActivated, // Assembly once it is loaded
+		EventBellStarted,
+		EventBellStopped,
+		EventTemporaryReset,
+		EventScheduleActivated, // Back to Regular after midnight
+	}, got)
+}
+
+func TestScheduler_TimezoneChangeAppliesLive(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}})
+
+	f.simulate(t, start.Add(12*time.Hour))
+	require.NoError(t, f.scheduler.SetTimezone("America/New_York"))
+	f.simulate(t, start.Add(36*time.Hour))
+
+	rings := f.logs(t, "schedule")
+	require.Len(t, rings, 2)
+	assert.Equal(t, time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC), rings[0].Timestamp.UTC())
+	// 08:00 EDT is 12:00 UTC
+	assert.Equal(t, time.Date(2026, 6, 2, 12, 0, 0, 0, time.UTC), rings[1].Timestamp.UTC())
+}
+
+func TestScheduler_DaylightSavingTransitions(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	t.Run("spring forward rings skipped slot after the gap", func(t *testing.T) {
+		start := time.Date(2026, 3, 7, 12, 0, 0, 0, loc)
+		f := newSchedulerFixture(t, start, "America/New_York")
+		f.addSchedule(t, &models.Schedule{Name: "Sunday", IsActive: true,
+			TimeSlots: []models.TimeSlot{weekdaySlot("02:30", "Sunday"), weekdaySlot("03:30", "Sunday")}})
+
+		f.simulate(t, start.Add(24*time.Hour))
+
+		rings := f.logs(t, "schedule")
+		require.Len(t, rings, 2)
+		assert.Equal(t, time.Date(2026, 3, 8, 3, 0, 0, 0, loc), rings[0].Timestamp.In(loc))
+		assert.Equal(t, time.Date(2026, 3, 8, 3, 30, 0, 0, loc), rings[1].Timestamp.In(loc))
+	})
+
+	t.Run("fall back rings repeated slot once", func(t *testing.T) {
+		start := time.Date(2026, 10, 31, 12, 0, 0, 0, loc)
+		f := newSchedulerFixture(t, start, "America/New_York")
+		f.addSchedule(t, &models.Schedule{Name: "Sunday", IsActive: true,
+			TimeSlots: []models.TimeSlot{weekdaySlot("01:30", "Sunday")}})
+
+		f.simulate(t, start.Add(24*time.Hour))
+
+		rings := f.logs(t, "schedule")
+		require.Len(t, rings, 1)
+		_, offset := rings[0].Timestamp.In(loc).Zone()
+		assert.Equal(t, -4*3600, offset, "expected the first (EDT) occurrence")
+	})
+}
+
+func TestScheduler_CatchUpPolicy(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 55, 0, 0, time.UTC)
+	stallEnd := start.Add(10 * time.Minute)
+
+	tests := []struct {
+		name       string
+		policy     string
+		grace      time.Duration
+		wantRings  int
+		wantMissed int
+	}{
+		{name: "fire late within grace", policy: models.CatchUpFireLate, grace: 10 * time.Minute, wantRings: 1},
+		{name: "fire late outside grace", policy: models.CatchUpFireLate, grace: 2 * time.Minute, wantMissed: 1},
+		{name: "log missed", policy: models.CatchUpLogMissed, grace: time.Hour, wantMissed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSchedulerFixture(t, start, "UTC")
+			f.scheduler.SetCatchUpPolicy(tt.policy, tt.grace)
+			f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+				TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}})
+
+			// The process stalls across the 08:00 trigger
+			f.clock.Set(stallEnd)
+			f.scheduler.tick(start, stallEnd)
+
+			assert.Len(t, f.logs(t, "schedule"), tt.wantRings)
+			assert.Len(t, f.logs(t, "missed"), tt.wantMissed)
+		})
+	}
+}
+
+func TestScheduler_ClockStepBackDoesNotRingTwice(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 59, 0, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}})
+
+	f.simulate(t, start.Add(2*time.Minute))
+	f.waitForRelaysSettled(t)
+
+	// NTP steps the clock back across the trigger that already rang
+	stepped := start.Add(-3 * time.Minute)
+	f.clock.Set(stepped)
+	f.simulate(t, start.Add(5*time.Minute))
+
+	assert.Len(t, f.logs(t, "schedule"), 1)
+}
+
+func TestScheduler_RunLoopUsesClock(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 59, 30, 0, time.UTC)
+	f := newSchedulerFixture(t, start, "UTC")
+	f.addSchedule(t, &models.Schedule{Name: "Regular", IsActive: true,
+		TimeSlots: []models.TimeSlot{weekdaySlot("08:00", schoolDays...)}})
+
+	f.scheduler.Start()
+	defer f.scheduler.Stop()
+
+	// The loop should sleep exactly until the trigger
+	f.clock.BlockUntil(1)
+	next, ok := f.clock.NextDeadline()
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC), next)
+
+	f.clock.Set(next)
+	assert.Eventually(t, func() bool {
+		return len(f.logs(t, "schedule")) == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSessionInvalid is returned for unknown, expired or revoked sessions
+	ErrSessionInvalid = errors.New("session is invalid or has expired")
+	// ErrRefreshTokenReused is returned when a rotated refresh token is
+	// presented again. The session is revoked, since the token was copied.
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+	// ErrUserInactive is returned when the session's user was deactivated,
+	// deleted or has not been approved
+	ErrUserInactive = errors.New("user account is not active")
+)
+
+// SessionService issues, rotates and revokes login sessions
+type SessionService struct {
+	sessions *store.SessionRepository
+	users    store.UserRepository
+	clock    Clock
+}
+
+// NewSessionService creates a new session service instance
+func NewSessionService(sessions *store.SessionRepository, users store.UserRepository, clock Clock) *SessionService {
+	return &SessionService{
+		sessions: sessions,
+		users:    users,
+		clock:    clock,
+	}
+}
+
+// Create starts a session for the user and returns its refresh token
+func (s *SessionService) Create(user *models.User, userAgent, ipAddress string) (string, *models.Session, error) {
+	token, hash, err := models.NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.clock.Now()
+	session := &models.Session{
+		UserID:     user.ID,
+		TokenHash:  hash,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(models.RefreshTokenLifetime),
+	}
+	if err := s.sessions.Create(session); err != nil {
+		return "", nil, err
+	}
+
+	// Sessions are only created at login, which is often enough to prune
+	if _, err := s.sessions.DeleteExpired(now); err != nil {
+		fmt.Printf("Failed to delete expired sessions: %v\n", err)
+	}
+	return token, session, nil
+}
+
+// Refresh exchanges a refresh token for a new one. The old token stops
+// working; presenting it again revokes the session.
+func (s *SessionService) Refresh(token string) (string, *models.Session, *models.User, error) {
+	hash := models.HashRefreshToken(token)
+	session, err := s.sessions.GetByTokenHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil, nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	now := s.clock.Now()
+	if session.TokenHash != hash {
+		if session.RevokedAt == nil {
+			fmt.Printf("Refresh token reuse detected, revoking session %d of user %d\n", session.ID, session.UserID)
+			if err := s.sessions.Revoke(session.ID, now); err != nil {
+				return "", nil, nil, err
+			}
+		}
+		return "", nil, nil, ErrRefreshTokenReused
+	}
+	if !session.IsActive(now) {
+		return "", nil, nil, ErrSessionInvalid
+	}
+
+	user, err := s.activeUser(session.UserID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	newToken, newHash, err := models.NewRefreshToken()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	rotated, err := s.sessions.Rotate(session, newHash, now)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if !rotated {
+		// Another request rotated or revoked the session in the meantime
+		return "", nil, nil, ErrSessionInvalid
+	}
+	return newToken, session, user, nil
+}
+
+// Validate checks that the session is still active and belongs to the user,
+// and returns the user as currently stored
+func (s *SessionService) Validate(sessionID, userID int64) (*models.User, error) {
+	session, err := s.sessions.Get(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID || !session.IsActive(s.clock.Now()) {
+		return nil, ErrSessionInvalid
+	}
+	return s.activeUser(userID)
+}
+
+// List returns the active sessions of the user
+func (s *SessionService) List(userID int64) ([]models.Session, error) {
+	return s.sessions.GetActiveForUser(userID, s.clock.Now())
+}
+
+// Revoke ends one of the user's sessions
+func (s *SessionService) Revoke(userID, sessionID int64) error {
+	session, err := s.sessions.Get(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionInvalid
+	}
+	return s.sessions.Revoke(session.ID, s.clock.Now())
+}
+
+// RevokeToken ends the session the refresh token belongs to. Unknown
+// tokens are ignored, so logging out twice is harmless.
+func (s *SessionService) RevokeToken(token string) error {
+	session, err := s.sessions.GetByTokenHash(models.HashRefreshToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.sessions.Revoke(session.ID, s.clock.Now())
+}
+
+// RevokeAll ends every session of the user except keep, and returns how
+// many were ended. Pass 0 to end them all.
+func (s *SessionService) RevokeAll(userID, keep int64) (int64, error) {
+	return s.sessions.RevokeAllForUser(userID, keep, s.clock.Now())
+}
+
+// DeleteAll removes every session of the user, for when the user is deleted
+func (s *SessionService) DeleteAll(userID int64) error {
+	return s.sessions.DeleteForUser(userID)
+}
+
+func (s *SessionService) activeUser(userID int64) (*models.User, error) {
+	user, err := s.users.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserInactive
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive || user.PendingApproval {
+		return nil, ErrUserInactive
+	}
+	return user, nil
+}
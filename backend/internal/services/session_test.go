@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestUser opens an in-memory database with the user tables and one user
+func newTestUser(t *testing.T) (*gorm.DB, *store.GormUserRepository, *models.User) {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Session{}))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	users := store.NewUserRepository(db)
+	user := &models.User{Username: "teacher", Email: "teacher@example.com", Password: "password123", Role: models.RoleUser, IsActive: true}
+	require.NoError(t, users.Create(user))
+	return db, users, user
+}
+
+func newSessionFixture(t *testing.T) (*SessionService, *store.GormUserRepository, *FakeClock, *models.User) {
+	db, users, user := newTestUser(t)
+	clock := NewFakeClock(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	return NewSessionService(store.NewSessionRepository(db), users, clock), users, clock, user
+}
+
+func TestSession_RefreshRotatesToken(t *testing.T) {
+	sessions, _, clock, user := newSessionFixture(t)
+
+	first, session, err := sessions.Create(user, "Firefox", "10.0.0.5")
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	second, refreshed, refreshedUser, err := sessions.Refresh(first)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, session.ID, refreshed.ID)
+	assert.Equal(t, user.ID, refreshedUser.ID)
+
+	_, err = sessions.Validate(session.ID, user.ID)
+	require.NoError(t, err)
+
+	// The replaced token is reuse, which revokes the session for everyone
+	_, _, _, err = sessions.Refresh(first)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, _, _, err = sessions.Refresh(second)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+	_, err = sessions.Validate(session.ID, user.ID)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+}
+
+func TestSession_Expires(t *testing.T) {
+	sessions, _, clock, user := newSessionFixture(t)
+
+	token, session, err := sessions.Create(user, "", "")
+	require.NoError(t, err)
+
+	clock.Advance(models.RefreshTokenLifetime + time.Minute)
+	_, _, _, err = sessions.Refresh(token)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+	_, err = sessions.Validate(session.ID, user.ID)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+}
+
+func TestSession_Revocation(t *testing.T) {
+	sessions, _, _, user := newSessionFixture(t)
+
+	laptop, laptopSession, err := sessions.Create(user, "laptop", "")
+	require.NoError(t, err)
+	_, phoneSession, err := sessions.Create(user, "phone", "")
+	require.NoError(t, err)
+	_, tabletSession, err := sessions.Create(user, "tablet", "")
+	require.NoError(t, err)
+
+	// Sessions are only visible to their own user
+	assert.ErrorIs(t, sessions.Revoke(user.ID+1, phoneSession.ID), ErrSessionInvalid)
+	require.NoError(t, sessions.Revoke(user.ID, phoneSession.ID))
+	_, err = sessions.Validate(phoneSession.ID, user.ID)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+
+	revoked, err := sessions.RevokeAll(user.ID, laptopSession.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+	_, err = sessions.Validate(tabletSession.ID, user.ID)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+
+	active, err := sessions.List(user.ID)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "laptop", active[0].UserAgent)
+
+	// Logging out twice is harmless
+	require.NoError(t, sessions.RevokeToken(laptop))
+	require.NoError(t, sessions.RevokeToken(laptop))
+	_, err = sessions.Validate(laptopSession.ID, user.ID)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+}
+
+func TestSession_RejectsInactiveUsers(t *testing.T) {
+	sessions, users, _, user := newSessionFixture(t)
+
+	token, session, err := sessions.Create(user, "", "")
+	require.NoError(t, err)
+
+	user.IsActive = false
+	user.Password = ""
+	require.NoError(t, users.Update(user))
+	_, err = sessions.Validate(session.ID, user.ID)
+	assert.ErrorIs(t, err, ErrUserInactive)
+	_, _, _, err = sessions.Refresh(token)
+	assert.ErrorIs(t, err, ErrUserInactive)
+
+	require.NoError(t, users.Delete(user.ID))
+	_, err = sessions.Validate(session.ID, user.ID)
+	assert.ErrorIs(t, err, ErrUserInactive)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxDSTShift bounds the search for a zone offset change. No zone in the tz
+// database shifts its clocks by more than this.
+const maxDSTShift = 3 * time.Hour
+
+// LoadTimezone resolves an IANA timezone name, treating an empty name as UTC
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// wallClockInstant returns the instant at which the wall clock in loc first
+// shows hour:minute on the given date. DST transitions are resolved as:
+//   - a time skipped when clocks spring forward maps to the end of the gap,
+//     so the bell rings at the first minute after the jump
+//   - a time repeated when clocks fall back maps to its first occurrence,
+//     so the bell rings only once
+func wallClockInstant(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, loc)
+
+	// Inside a spring-forward gap the requested wall time does not exist and
+	// time.Date normalizes it to either side of the transition.
+	if t.Hour() != hour || t.Minute() != minute {
+		// Compare wall clocks in UTC, which is only a neutral container here
+		want := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+		got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+		if got.After(want) {
+			return zoneTransition(t.Add(-maxDSTShift), t)
+		}
+		return zoneTransition(t, t.Add(maxDSTShift))
+	}
+
+	// Inside a fall-back overlap prefer the earlier of the two instants
+	_, offsetNow := t.Zone()
+	_, offsetBefore := t.Add(-maxDSTShift).Zone()
+	if offsetBefore > offsetNow {
+		earlier := t.Add(-time.Duration(offsetBefore-offsetNow) * time.Second)
+		if earlier.Hour() == hour && earlier.Minute() == minute && earlier.Day() == day {
+			return earlier
+		}
+	}
+	return t
+}
+
+// zoneTransition returns the first instant in (from, to] whose zone offset
+// differs from the offset at from, or to when there is no transition
+func zoneTransition(from, to time.Time) time.Time {
+	_, offsetFrom := from.Zone()
+	for to.Sub(from) > time.Second {
+		mid := from.Add(to.Sub(from) / 2)
+		if _, offset := mid.Zone(); offset == offsetFrom {
+			from = mid
+		} else {
+			to = mid
+		}
+	}
+	return to.Truncate(time.Second)
+}
+
+// nextLocalMidnight returns the start of the day following t in t's location
+func nextLocalMidnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
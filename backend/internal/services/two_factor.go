@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"bell_scheduler/internal/models"
+	"bell_scheduler/internal/store"
+	"bell_scheduler/pkg/totp"
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when enrolling a user who already
+	// uses two-factor authentication
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when a user without two-factor
+	// authentication is asked for a code
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotSetUp is returned when confirming enrolment that was
+	// never started
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or reused codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// TwoFactorService enrols users in TOTP two-factor authentication and
+// checks their codes. Each code is accepted once, and each recovery code
+// replaces a code once.
+type TwoFactorService struct {
+	users *store.GormUserRepository
+	clock Clock
+}
+
+// NewTwoFactorService creates a new two-factor service instance
+func NewTwoFactorService(users *store.GormUserRepository, clock Clock) *TwoFactorService {
+	return &TwoFactorService{
+		users: users,
+		clock: clock,
+	}
+}
+
+// Setup starts enrolment with a new secret, and returns it with the URI
+// authenticator apps scan. Enrolment completes once Enable confirms a code.
+func (s *TwoFactorService) Setup(user *models.User) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.users.UpdateTwoFactor(user.ID, secret, false, ""); err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret = secret
+	return secret, totp.ProvisioningURI(secret, models.TwoFactorIssuer, user.Username), nil
+}
+
+// Enable completes enrolment with a code from the authenticator app and
+// returns the recovery codes, which are not shown again
+func (s *TwoFactorService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, s.clock.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := models.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.UpdateTwoFactor(user.ID, user.TOTPSecret, true, hashes); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.ClaimTOTPStep(user.ID, step); err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	user.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// Verify checks a code from the authenticator app or a recovery code
+func (s *TwoFactorService) Verify(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := totp.Validate(user.TOTPSecret, code, s.clock.Now()); ok {
+		claimed, err := s.users.ClaimTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	return s.useRecoveryCode(user, code)
+}
+
+// useRecoveryCode accepts an unused recovery code and crosses it off
+func (s *TwoFactorService) useRecoveryCode(user *models.User, code string) error {
+	hash := models.HashRecoveryCode(code)
+	hashes := user.RecoveryCodeHashes()
+	remaining := make([]string, 0, len(hashes))
+	found := false
+	for _, h := range hashes {
+		if h == hash && !found {
+			found = true
+			continue
+		}
+		remaining = append(remaining, h)
+	}
+	if !found {
+		return ErrInvalidTwoFactorCode
+	}
+
+	data, err := json.Marshal(remaining)
+	if err != nil {
+		return err
+	}
+	replaced, err := s.users.ReplaceRecoveryCodes(user.ID, user.RecoveryCodes, string(data))
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		// Another request used a code in the meantime
+		return ErrInvalidTwoFactorCode
+	}
+	user.RecoveryCodes = string(data)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	codes, hashes, err := models.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := s.users.ReplaceRecoveryCodes(user.ID, user.RecoveryCodes, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !replaced {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	user.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off and forgets the secret
+func (s *TwoFactorService) Disable(user *models.User) error {
+	if err := s.users.UpdateTwoFactor(user.ID, "", false, ""); err != nil {
+		return err
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.RecoveryCodes = ""
+	return nil
+}
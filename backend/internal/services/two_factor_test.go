@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bell_scheduler/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactor_EnrolAndVerify(t *testing.T) {
+	_, users, user := newTestUser(t)
+	clock := NewFakeClock(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	twoFactor := NewTwoFactorService(users, clock)
+
+	assert.ErrorIs(t, twoFactor.Verify(user, "123456"), ErrTwoFactorNotEnabled)
+	_, err := twoFactor.Enable(user, "123456")
+	assert.ErrorIs(t, err, ErrTwoFactorNotSetUp)
+
+	secret, uri, err := twoFactor.Setup(user)
+	require.NoError(t, err)
+	assert.Contains(t, uri, "secret="+secret)
+
+	code := func() string {
+		code, err := totp.Code(secret, totp.Step(clock.Now()))
+		require.NoError(t, err)
+		return code
+	}
+
+	_, err = twoFactor.Enable(user, "000000")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	recovery, err := twoFactor.Enable(user, code())
+	require.NoError(t, err)
+	assert.Len(t, recovery, 10)
+
+	// The code confirming enrolment cannot be used again to log in
+	user, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, user.TOTPEnabled)
+	assert.ErrorIs(t, twoFactor.Verify(user, code()), ErrInvalidTwoFactorCode)
+
+	clock.Advance(totp.Period)
+	require.NoError(t, twoFactor.Verify(user, code()))
+	assert.ErrorIs(t, twoFactor.Verify(user, code()), ErrInvalidTwoFactorCode)
+
+	// Recovery codes work once each, in any case
+	require.NoError(t, twoFactor.Verify(user, recovery[0]))
+	assert.ErrorIs(t, twoFactor.Verify(user, recovery[0]), ErrInvalidTwoFactorCode)
+	require.NoError(t, twoFactor.Verify(user, strings.ToUpper(recovery[1])))
+	assert.Len(t, user.RecoveryCodeHashes(), 8)
+
+	fresh, err := twoFactor.RegenerateRecoveryCodes(user)
+	require.NoError(t, err)
+	assert.ErrorIs(t, twoFactor.Verify(user, recovery[2]), ErrInvalidTwoFactorCode)
+	require.NoError(t, twoFactor.Verify(user, fresh[0]))
+
+	require.NoError(t, twoFactor.Disable(user))
+	user, err = users.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.False(t, user.TOTPEnabled)
+	assert.Empty(t, user.TOTPSecret)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"bell_scheduler/internal/models"
+)
+
+// MaxUpcomingWindow bounds how far ahead the timeline can be expanded
+const MaxUpcomingWindow = 14 * 24 * time.Hour
+
+// UpcomingBell is a predicted bell on the timeline returned by Upcoming
+type UpcomingBell struct {
+	At           time.Time `json:"at"`
+	Date         string    `json:"date"` // Local date, YYYY-MM-DD
+	Time         string    `json:"time"` // Local wall-clock time, HH:MM
+	ScheduleID   int64     `json:"scheduleId"`
+	ScheduleName string    `json:"scheduleName"`
+	IsTemporary  bool      `json:"isTemporary"`
+	TimeSlotID   int64     `json:"timeSlotId"`
+	TriggerTime  string    `json:"triggerTime"`
+	Description  string    `json:"description,omitempty"`
+	OverrideName string    `json:"overrideName,omitempty"` // Calendar override that selected the schedule
+	ActivationID int64     `json:"activationId,omitempty"` // Scheduled activation that selected the schedule
+	Zones        []string  `json:"zones"`
+	ZoneIDs      []int64   `json:"zoneIds,omitempty"` // Zones the time slot is limited to, in order; none for every zone
+	PatternName  string    `json:"patternName,omitempty"`
+}
+
+// BellFilter limits bells to those of one schedule or ringing in one zone.
+// Zero fields match every bell.
+type BellFilter struct {
+	ScheduleID int64
+	ZoneID     int64
+}
+
+// matches reports whether an occurrence passes the filter
+func (f BellFilter) matches(occ occurrence) bool {
+	if f.ScheduleID != 0 && occ.Schedule.ID != f.ScheduleID {
+		return false
+	}
+	if f.ZoneID != 0 {
+		ids, err := occ.TimeSlot.ZoneIDs()
+		if err != nil {
+			return false
+		}
+		if len(ids) == 0 {
+			return true
+		}
+		for _, id := range ids {
+			if id == f.ZoneID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Upcoming expands the schedules, temporary schedules, calendar overrides and
+// scheduled activations into the bells due in (from, from+window], in the
+// order they will ring
+func (s *SchedulerService) Upcoming(from time.Time, window time.Duration) []UpcomingBell {
+	if window > MaxUpcomingWindow {
+		window = MaxUpcomingWindow
+	}
+	return s.Bells(from, from.Add(window), BellFilter{})
+}
+
+// Bells returns the bells due in (from, to] that pass the filter, like
+// Upcoming but without limiting the window
+func (s *SchedulerService) Bells(from, to time.Time, filter BellFilter) []UpcomingBell {
+	zoneNames := make(map[int64]string)
+	var allZones []string
+	for _, zone := range s.zones.Zones() {
+		zoneNames[zone.ID] = zone.Name
+		allZones = append(allZones, zone.Name)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []occurrence
+	for _, occ := range s.occurrencesBetween(from, to) {
+		if filter.matches(occ) {
+			due = append(due, occ)
+		}
+	}
+	bells := make([]UpcomingBell, 0, len(due))
+	for _, occ := range due {
+		local := occ.At.In(s.location)
+		bell := UpcomingBell{
+			At:           occ.At,
+			Date:         local.Format(models.CalendarDateFormat),
+			Time:         local.Format("15:04"),
+			ScheduleID:   occ.Schedule.ID,
+			ScheduleName: occ.Schedule.Name,
+			IsTemporary:  occ.Schedule.IsTemporary,
+			TimeSlotID:   occ.TimeSlot.ID,
+			TriggerTime:  occ.TimeSlot.TriggerTime,
+			Description:  occ.TimeSlot.Description,
+			Zones:        allZones,
+		}
+		if occ.Activation != nil {
+			bell.ActivationID = occ.Activation.ID
+		} else if override := s.overrideFor(local); override != nil {
+			bell.OverrideName = override.Name
+		}
+		if ids, err := occ.TimeSlot.ZoneIDs(); err == nil && len(ids) > 0 {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			bell.ZoneIDs = ids
+			bell.Zones = nil
+			for _, id := range ids {
+				if name, ok := zoneNames[id]; ok {
+					bell.Zones = append(bell.Zones, name)
+				}
+			}
+		}
+		if occ.TimeSlot.PatternID != nil {
+			if pattern, ok := s.patterns[*occ.TimeSlot.PatternID]; ok {
+				bell.PatternName = pattern.Name
+			}
+		}
+		bells = append(bells, bell)
+	}
+	return bells
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bell_scheduler/internal/models"
+)
+
+// ZoneManager owns one GPIOService per bell zone
+type ZoneManager struct {
+	mu              sync.RWMutex
+	outputs         map[int64]*zoneOutput
+	defaultDuration time.Duration
+	overlapPolicy   string
+	emergency       []time.Duration
+	listener        func(zone models.Zone, active bool)
+	clock           Clock
+}
+
+type zoneOutput struct {
+	zone models.Zone
+	gpio *GPIOService
+}
+
+// NewZoneManager creates a zone manager. Zones without their own ring
+// duration use the default duration.
+func NewZoneManager(defaultDuration time.Duration, clock Clock) *ZoneManager {
+	return &ZoneManager{
+		outputs:         make(map[int64]*zoneOutput),
+		defaultDuration: defaultDuration,
+		overlapPolicy:   models.OverlapReject,
+		clock:           clock,
+	}
+}
+
+// Load reconciles the running outputs with the zone configuration: new zones
+// are opened, changed zones reconfigured and removed zones closed. Zones whose
+// driver fails to open keep running with the no-op driver.
+func (m *ZoneManager) Load(zones []models.Zone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failures []string
+	seen := make(map[int64]bool)
+	for _, zone := range zones {
+		seen[zone.ID] = true
+
+		output, ok := m.outputs[zone.ID]
+		if !ok {
+			driver, err := NewOutputDriver(zone.OutputDriver, zone.GPIOChip, zone.GPIOPin, m.clock)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("zone %s: %v", zone.Name, err))
+				driver = NewNoopDriver()
+			}
+			gpio := NewGPIOService(driver, m.durationFor(zone), m.clock)
+			gpio.SetOverlapPolicy(m.overlapPolicy)
+			m.listen(gpio, zone)
+			if m.emergency != nil {
+				if err := gpio.StartEmergency(m.emergency); err != nil {
+					failures = append(failures, fmt.Sprintf("zone %s: %v", zone.Name, err))
+				}
+			}
+			m.outputs[zone.ID] = &zoneOutput{zone: zone, gpio: gpio}
+			continue
+		}
+
+		if output.zone.OutputDriver != zone.OutputDriver || output.zone.GPIOChip != zone.GPIOChip || output.zone.GPIOPin != zone.GPIOPin {
+			if err := output.gpio.Reconfigure(zone.OutputDriver, zone.GPIOChip, zone.GPIOPin); err != nil {
+				failures = append(failures, fmt.Sprintf("zone %s: %v", zone.Name, err))
+			}
+		}
+		output.zone = zone
+		output.gpio.SetDuration(m.durationFor(zone))
+		m.listen(output.gpio, zone)
+	}
+
+	for id, output := range m.outputs {
+		if !seen[id] {
+			output.gpio.Close()
+			delete(m.outputs, id)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to open outputs: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Trigger rings the given zones, or every zone when none are given, playing
+// the pattern or a single ring of each zone's duration when it is empty. It
+// returns the zones that rang; zones that failed are reported in the error.
+func (m *ZoneManager) Trigger(zoneIDs []int64, pattern []time.Duration) ([]models.Zone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	targets, failures := m.resolve(zoneIDs)
+	if len(targets) == 0 && len(failures) == 0 {
+		return nil, fmt.Errorf("no bell zones configured")
+	}
+
+	var rung []models.Zone
+	for _, output := range targets {
+		if err := output.gpio.Play(pattern); err != nil {
+			failures = append(failures, fmt.Sprintf("zone %s: %v", output.zone.Name, err))
+			continue
+		}
+		rung = append(rung, output.zone)
+	}
+
+	if len(failures) > 0 {
+		return rung, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return rung, nil
+}
+
+// Cancel stops the ring in progress in the given zones, or in every zone when
+// none are given. It returns the zones that were ringing.
+func (m *ZoneManager) Cancel(zoneIDs []int64) ([]models.Zone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	targets, failures := m.resolve(zoneIDs)
+
+	var stopped []models.Zone
+	for _, output := range targets {
+		wasActive := output.gpio.IsActive()
+		if err := output.gpio.Cancel(); err != nil {
+			failures = append(failures, fmt.Sprintf("zone %s: %v", output.zone.Name, err))
+			continue
+		}
+		if wasActive {
+			stopped = append(stopped, output.zone)
+		}
+	}
+
+	if len(failures) > 0 {
+		return stopped, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return stopped, nil
+}
+
+// resolve looks up the outputs for the zone IDs, ordered by zone ID.
+// Callers must hold at least a read lock.
+func (m *ZoneManager) resolve(zoneIDs []int64) ([]*zoneOutput, []string) {
+	var targets []*zoneOutput
+	var failures []string
+	if len(zoneIDs) == 0 {
+		for _, output := range m.outputs {
+			targets = append(targets, output)
+		}
+	} else {
+		for _, id := range zoneIDs {
+			output, ok := m.outputs[id]
+			if !ok {
+				failures = append(failures, fmt.Sprintf("zone %d not found", id))
+				continue
+			}
+			targets = append(targets, output)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].zone.ID < targets[j].zone.ID
+	})
+	return targets, failures
+}
+
+// Zones returns the configured zones ordered by ID
+func (m *ZoneManager) Zones() []models.Zone {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	targets, _ := m.resolve(nil)
+	zones := make([]models.Zone, 0, len(targets))
+	for _, output := range targets {
+		zones = append(zones, output.zone)
+	}
+	return zones
+}
+
+// Output returns the output driver of a zone
+func (m *ZoneManager) Output(zoneID int64) (OutputDriver, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	output, ok := m.outputs[zoneID]
+	if !ok {
+		return nil, false
+	}
+	return output.gpio.Driver(), true
+}
+
+// IsActive returns whether the bell of any zone is ringing
+func (m *ZoneManager) IsActive() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, output := range m.outputs {
+		if output.gpio.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDefaultDuration updates the ring duration of zones without their own
+func (m *ZoneManager) SetDefaultDuration(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.defaultDuration = duration
+	for _, output := range m.outputs {
+		output.gpio.SetDuration(m.durationFor(output.zone))
+	}
+}
+
+// StartEmergency plays the emergency pattern in a loop on every zone,
+// including zones added later, until StopEmergency
+func (m *ZoneManager) StartEmergency(pattern []time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emergency = pattern
+	var failures []string
+	for _, output := range m.outputs {
+		if err := output.gpio.StartEmergency(pattern); err != nil {
+			failures = append(failures, fmt.Sprintf("zone %s: %v", output.zone.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// StopEmergency ends the emergency signal on every zone
+func (m *ZoneManager) StopEmergency() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emergency = nil
+	var failures []string
+	for _, output := range m.outputs {
+		if err := output.gpio.StopEmergency(); err != nil {
+			failures = append(failures, fmt.Sprintf("zone %s: %v", output.zone.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// SetOverlapPolicy sets how every zone handles a trigger while it is ringing
+func (m *ZoneManager) SetOverlapPolicy(policy string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.overlapPolicy = policy
+	for _, output := range m.outputs {
+		output.gpio.SetOverlapPolicy(policy)
+	}
+}
+
+// SetListener registers a function called whenever the bell of a zone starts
+// or stops ringing. It runs on the zone's output goroutine and must not block.
+func (m *ZoneManager) SetListener(listener func(zone models.Zone, active bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listener = listener
+	for _, output := range m.outputs {
+		m.listen(output.gpio, output.zone)
+	}
+}
+
+// listen forwards the state changes of an output to the listener.
+// Callers must hold the write lock.
+func (m *ZoneManager) listen(gpio *GPIOService, zone models.Zone) {
+	listener := m.listener
+	if listener == nil {
+		gpio.SetStateListener(nil)
+		return
+	}
+	gpio.SetStateListener(func(active bool) {
+		listener(zone, active)
+	})
+}
+
+// Close releases the outputs of every zone
+func (m *ZoneManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, output := range m.outputs {
+		output.gpio.Close()
+		delete(m.outputs, id)
+	}
+}
+
+func (m *ZoneManager) durationFor(zone models.Zone) time.Duration {
+	if zone.RingDuration > 0 {
+		return time.Duration(zone.RingDuration) * time.Second
+	}
+	return m.defaultDuration
+}
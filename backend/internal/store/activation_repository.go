@@ -0,0 +1,65 @@
+package store
+
+import (
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ActivationRepository handles database operations for scheduled activations
+type ActivationRepository struct {
+	db *gorm.DB
+}
+
+// NewActivationRepository creates a new activation repository instance
+func NewActivationRepository(db *gorm.DB) *ActivationRepository {
+	return &ActivationRepository{
+		db: db,
+	}
+}
+
+// Create adds a new activation
+func (r *ActivationRepository) Create(activation *models.ScheduleActivation) error {
+	return r.db.Create(activation).Error
+}
+
+// Get retrieves an activation by ID
+func (r *ActivationRepository) Get(id int64) (*models.ScheduleActivation, error) {
+	var activation models.ScheduleActivation
+	if err := r.db.First(&activation, id).Error; err != nil {
+		return nil, err
+	}
+	return &activation, nil
+}
+
+// GetAll retrieves all activations ordered by start, optionally only those
+// of one schedule
+func (r *ActivationRepository) GetAll(scheduleID int64) ([]models.ScheduleActivation, error) {
+	query := r.db.Order("starts_at ASC")
+	if scheduleID != 0 {
+		query = query.Where("schedule_id = ?", scheduleID)
+	}
+	var activations []models.ScheduleActivation
+	if err := query.Find(&activations).Error; err != nil {
+		return nil, err
+	}
+	return activations, nil
+}
+
+// GetUnfinished retrieves the activations that are not cancelled and end
+// after the given time
+func (r *ActivationRepository) GetUnfinished(now time.Time) ([]models.ScheduleActivation, error) {
+	var activations []models.ScheduleActivation
+	err := r.db.Where("cancelled_at IS NULL AND ends_at > ?", now.UTC()).
+		Order("starts_at ASC").
+		Find(&activations).Error
+	return activations, err
+}
+
+// Cancel marks an activation as cancelled
+func (r *ActivationRepository) Cancel(id int64, at time.Time, by string) error {
+	return r.db.Model(&models.ScheduleActivation{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"cancelled_at": at.UTC(), "cancelled_by": by}).Error
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"strings"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles database operations for audit entries
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository instance
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create adds a new audit entry
+func (r *AuditRepository) Create(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// GetByAction retrieves the audit entries with the action, newest first
+func (r *AuditRepository) GetByAction(action string) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.db.Where("action = ?", action).Order("timestamp DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// Find retrieves the entries matching the filter, newest first, with the
+// total number of matches. A limit of zero returns every match.
+func (r *AuditRepository) Find(filter models.AuditFilter, offset, limit int) ([]models.AuditEntry, int64, error) {
+	query := r.db.Model(&models.AuditEntry{})
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, ".") {
+			query = query.Where(`action LIKE ? ESCAPE '\'`, escapeLike(filter.Action)+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor_name = ?", filter.Actor)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("timestamp DESC, id DESC")
+	if limit > 0 {
+		query = query.Offset(offset).Limit(limit)
+	}
+	var entries []models.AuditEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bell_scheduler/internal/models"
+)
+
+func TestAuditRepository_Find(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.AuditEntry{}))
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	repo := NewAuditRepository(db)
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	for i, e := range []models.AuditEntry{
+		{Action: models.AuditScheduleCreated, ActorName: "head", TargetType: "schedule", TargetID: 1},
+		{Action: models.AuditScheduleUpdated, ActorName: "head", TargetType: "schedule", TargetID: 1},
+		{Action: models.AuditSettingsUpdated, ActorName: "deputy", TargetType: "settings", TargetID: 1},
+		{Action: models.AuditUserSessionsRevoked, ActorName: "head", TargetType: "user", TargetID: 2},
+	} {
+		e.Timestamp = start.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, repo.Create(&e))
+	}
+
+	entries, total, err := repo.Find(models.AuditFilter{Action: "schedule."}, 0, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.AuditScheduleUpdated, entries[0].Action, "newest first")
+
+	_, total, err = repo.Find(models.AuditFilter{Action: "schedule.created"}, 0, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	_, total, err = repo.Find(models.AuditFilter{Actor: "head", From: start.Add(time.Hour), To: start.Add(3 * time.Hour)}, 0, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	entries, total, err = repo.Find(models.AuditFilter{}, 1, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.AuditScheduleUpdated, entries[1].Action)
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CalendarRepository handles database operations for calendar overrides
+type CalendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository creates a new calendar repository instance
+func NewCalendarRepository(db *gorm.DB) *CalendarRepository {
+	return &CalendarRepository{
+		db: db,
+	}
+}
+
+// Transaction runs fn with a repository whose changes are committed
+// together, or not at all if fn returns an error
+func (r *CalendarRepository) Transaction(fn func(repo *CalendarRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&CalendarRepository{db: tx})
+	})
+}
+
+// Create adds a new calendar override
+func (r *CalendarRepository) Create(override *models.CalendarOverride) error {
+	return r.db.Create(override).Error
+}
+
+// Get retrieves a calendar override by ID
+func (r *CalendarRepository) Get(id int64) (*models.CalendarOverride, error) {
+	var override models.CalendarOverride
+	if err := r.db.First(&override, id).Error; err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// GetAll retrieves all calendar overrides ordered by start date
+func (r *CalendarRepository) GetAll() ([]models.CalendarOverride, error) {
+	var overrides []models.CalendarOverride
+	if err := r.db.Order("start_date ASC").Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// GetByDateRange retrieves calendar overrides that overlap the given date range
+func (r *CalendarRepository) GetByDateRange(start, end time.Time) ([]models.CalendarOverride, error) {
+	var overrides []models.CalendarOverride
+	err := r.db.Where("start_date <= ? AND end_date >= ?",
+		end.Format(models.CalendarDateFormat), start.Format(models.CalendarDateFormat)).
+		Order("start_date ASC").
+		Find(&overrides).Error
+	return overrides, err
+}
+
+// Update saves changes to an existing calendar override
+func (r *CalendarRepository) Update(override *models.CalendarOverride) error {
+	return r.db.Save(override).Error
+}
+
+// Delete removes a calendar override
+func (r *CalendarRepository) Delete(id int64) error {
+	return r.db.Delete(&models.CalendarOverride{}, id).Error
+}
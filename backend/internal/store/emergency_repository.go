@@ -0,0 +1,64 @@
+package store
+
+import (
+	"errors"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EmergencyRepository handles database operations for the emergency state
+// and its tamper-evident log
+type EmergencyRepository struct {
+	db *gorm.DB
+}
+
+// NewEmergencyRepository creates a new emergency repository instance
+func NewEmergencyRepository(db *gorm.DB) *EmergencyRepository {
+	return &EmergencyRepository{
+		db: db,
+	}
+}
+
+// GetState retrieves the emergency state, which is inactive if none was saved
+func (r *EmergencyRepository) GetState() (*models.EmergencyState, error) {
+	var state models.EmergencyState
+	err := r.db.First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveTransition appends the log entry to the hash chain and saves the new
+// state in a single transaction
+func (r *EmergencyRepository) SaveTransition(state *models.EmergencyState, entry *models.EmergencyLogEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last models.EmergencyLogEntry
+		err := tx.Order("id DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		entry.ID = last.ID + 1
+		entry.PrevHash = last.Hash
+		entry.Hash = entry.ComputeHash()
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		state.LastHash = entry.Hash
+		return tx.Save(state).Error
+	})
+}
+
+// GetLog retrieves the emergency log in chain order
+func (r *EmergencyRepository) GetLog() ([]models.EmergencyLogEntry, error) {
+	var entries []models.EmergencyLogEntry
+	err := r.db.Order("id ASC").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// FeedRepository handles database operations for calendar feeds
+type FeedRepository struct {
+	db *gorm.DB
+}
+
+// NewFeedRepository creates a new feed repository instance
+func NewFeedRepository(db *gorm.DB) *FeedRepository {
+	return &FeedRepository{
+		db: db,
+	}
+}
+
+// Create adds a new calendar feed
+func (r *FeedRepository) Create(feed *models.CalendarFeed) error {
+	return r.db.Create(feed).Error
+}
+
+// Get retrieves a calendar feed by ID
+func (r *FeedRepository) Get(id int64) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := r.db.First(&feed, id).Error; err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetByTokenHash retrieves the calendar feed with the given token hash
+func (r *FeedRepository) GetByTokenHash(hash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := r.db.Where("token_hash = ?", hash).First(&feed).Error; err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetByUser retrieves the calendar feeds of a user
+func (r *FeedRepository) GetByUser(userID int64) ([]models.CalendarFeed, error) {
+	var feeds []models.CalendarFeed
+	if err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+// Touch records when a feed was last fetched
+func (r *FeedRepository) Touch(id int64, at time.Time) error {
+	return r.db.Model(&models.CalendarFeed{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// Delete removes a calendar feed
+func (r *FeedRepository) Delete(id int64) error {
+	return r.db.Delete(&models.CalendarFeed{}, id).Error
+}
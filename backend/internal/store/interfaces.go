@@ -12,5 +12,6 @@ type UserRepository interface {
 	Update(user *models.User) error
 	Delete(id int64) error
 	GetAll() ([]models.User, error)
+	GetPending() ([]models.User, error)
 	GetAllWithPagination(page, limit int, sortBy string, sortDesc bool, search string) ([]models.User, int64, error)
 }
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles database operations for invitations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository instance
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{
+		db: db,
+	}
+}
+
+// Create adds a new invitation
+func (r *InvitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// Get retrieves an invitation by ID
+func (r *InvitationRepository) Get(id int64) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.First(&invitation, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetByToken retrieves the invitation issued with the token
+func (r *InvitationRepository) GetByToken(token string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.Where("token_hash = ?", models.HashInvitationToken(token)).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetAll retrieves all invitations, newest first
+func (r *InvitationRepository) GetAll() ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	if err := r.db.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Update saves changes to an existing invitation
+func (r *InvitationRepository) Update(invitation *models.Invitation) error {
+	return r.db.Save(invitation).Error
+}
+
+// Accept marks the invitation accepted unless it already was, and reports
+// whether this call claimed it
+func (r *InvitationRepository) Accept(id int64, at time.Time) (bool, error) {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL", id).
+		Update("accepted_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Release makes an accepted invitation usable again
+func (r *InvitationRepository) Release(id int64) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ?", id).Update("accepted_at", nil).Error
+}
+
+// Delete removes an invitation
+func (r *InvitationRepository) Delete(id int64) error {
+	return r.db.Delete(&models.Invitation{}, id).Error
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bell_scheduler/internal/models"
+)
+
+func TestInvitationRepository_AcceptOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Invitation{}))
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	repo := NewInvitationRepository(db)
+	token, hash, err := models.NewInvitationToken()
+	require.NoError(t, err)
+	now := time.Now()
+	invitation := &models.Invitation{Email: "new@example.com", Role: models.RoleUser, TokenHash: hash, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(invitation))
+
+	found, err := repo.GetByToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, invitation.ID, found.ID)
+	assert.True(t, found.IsUsable(now))
+	assert.False(t, found.IsUsable(now.Add(2*time.Hour)), "expired")
+	_, err = repo.GetByToken(hash)
+	assert.Error(t, err, "the stored hash is not a token")
+
+	claimed, err := repo.Accept(invitation.ID, now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.Accept(invitation.ID, now)
+	require.NoError(t, err)
+	assert.False(t, claimed, "an invitation can only be accepted once")
+
+	found, err = repo.GetByToken(token)
+	require.NoError(t, err)
+	assert.False(t, found.IsUsable(now))
+
+	require.NoError(t, repo.Release(invitation.ID))
+	claimed, err = repo.Accept(invitation.ID, now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
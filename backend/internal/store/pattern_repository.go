@@ -0,0 +1,59 @@
+package store
+
+import (
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PatternRepository handles database operations for ring patterns
+type PatternRepository struct {
+	db *gorm.DB
+}
+
+// NewPatternRepository creates a new ring pattern repository instance
+func NewPatternRepository(db *gorm.DB) *PatternRepository {
+	return &PatternRepository{
+		db: db,
+	}
+}
+
+// Create adds a new ring pattern
+func (r *PatternRepository) Create(pattern *models.RingPattern) error {
+	return r.db.Create(pattern).Error
+}
+
+// Get retrieves a ring pattern by ID
+func (r *PatternRepository) Get(id int64) (*models.RingPattern, error) {
+	var pattern models.RingPattern
+	if err := r.db.First(&pattern, id).Error; err != nil {
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+// GetAll retrieves all ring patterns ordered by name
+func (r *PatternRepository) GetAll() ([]models.RingPattern, error) {
+	var patterns []models.RingPattern
+	if err := r.db.Order("name ASC").Find(&patterns).Error; err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// Update saves changes to an existing ring pattern
+func (r *PatternRepository) Update(pattern *models.RingPattern) error {
+	return r.db.Save(pattern).Error
+}
+
+// CountUsage returns the number of time slots that play the pattern
+func (r *PatternRepository) CountUsage(id int64) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TimeSlot{}).Where("pattern_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// Delete removes a ring pattern
+func (r *PatternRepository) Delete(id int64) error {
+	return r.db.Delete(&models.RingPattern{}, id).Error
+}
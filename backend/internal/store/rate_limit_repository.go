@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitRepository stores the attempts of rate limiters, and implements
+// ratelimiter.Store
+type RateLimitRepository struct {
+	db *gorm.DB
+}
+
+// NewRateLimitRepository creates a new rate limit repository instance
+func NewRateLimitRepository(db *gorm.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Load returns the recorded attempts of a key
+func (r *RateLimitRepository) Load(key string) ([]time.Time, error) {
+	var entry models.RateLimitEntry
+	if err := r.db.Where("bucket_key = ?", key).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var attempts []time.Time
+	if err := json.Unmarshal([]byte(entry.Attempts), &attempts); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// Save replaces the attempts of a key, deleting it once there are none
+func (r *RateLimitRepository) Save(key string, attempts []time.Time) error {
+	if len(attempts) == 0 {
+		return r.Delete(key)
+	}
+	data, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"attempts", "updated_at"}),
+	}).Create(&models.RateLimitEntry{Key: key, Attempts: string(data)}).Error
+}
+
+// Delete forgets a key
+func (r *RateLimitRepository) Delete(key string) error {
+	return r.db.Where("bucket_key = ?", key).Delete(&models.RateLimitEntry{}).Error
+}
+
+// DeleteOlderThan removes the keys that have not been used since the cutoff
+func (r *RateLimitRepository) DeleteOlderThan(cutoff time.Time) error {
+	return r.db.Where("updated_at < ?", cutoff).Delete(&models.RateLimitEntry{}).Error
+}
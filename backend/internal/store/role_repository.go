@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RoleRepository handles database operations for roles
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new role repository instance
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{
+		db: db,
+	}
+}
+
+// Create adds a new role
+func (r *RoleRepository) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Get retrieves a role by ID
+func (r *RoleRepository) Get(id int64) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetByName retrieves a role by name
+func (r *RoleRepository) GetByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetAll retrieves all roles ordered by name
+func (r *RoleRepository) GetAll() ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Update saves changes to an existing role. Users refer to roles by name, so
+// when the role was renamed their role is renamed with it.
+func (r *RoleRepository) Update(role *models.Role, previousName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+		if previousName == role.Name {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("role = ?", previousName).Update("role", role.Name).Error
+	})
+}
+
+// CountUsers returns the number of users holding the role
+func (r *RoleRepository) CountUsers(name string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("role = ?", name).Count(&count).Error
+	return count, err
+}
+
+// Delete removes a role
+func (r *RoleRepository) Delete(id int64) error {
+	return r.db.Delete(&models.Role{}, id).Error
+}
+
+// HasPermission reports whether the named role grants the permission. An
+// unknown role grants nothing.
+func (r *RoleRepository) HasPermission(roleName, permission string) (bool, error) {
+	role, err := r.GetByName(roleName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role.HasPermission(permission), nil
+}
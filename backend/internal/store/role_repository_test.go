@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bell_scheduler/internal/models"
+)
+
+func TestRoleRepository_RenameMovesUsers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Role{}))
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	roles := NewRoleRepository(db)
+	role := &models.Role{Name: "office", Permissions: "[]"}
+	require.NoError(t, roles.Create(role))
+	require.NoError(t, db.Create(&models.User{Username: "clerk", Email: "clerk@example.com", Password: "x", Role: "office"}).Error)
+
+	role.Name = "front office"
+	require.NoError(t, roles.Update(role, "office"))
+
+	count, err := roles.CountUsers("front office")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = roles.CountUsers("office")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	allowed, err := roles.HasPermission("front office", models.PermBellTrigger)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
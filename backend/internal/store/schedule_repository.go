@@ -1,6 +1,8 @@
 package store
 
 import (
+	"errors"
+
 	"bell_scheduler/internal/models"
 
 	"gorm.io/gorm"
@@ -16,8 +18,22 @@ func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
 	}
 }
 
-func (r *ScheduleRepository) Create(schedule *models.Schedule) error {
-	return r.db.Create(schedule).Error
+// Transaction runs fn with a repository whose changes are committed
+// together, or not at all if fn returns an error
+func (r *ScheduleRepository) Transaction(fn func(repo *ScheduleRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&ScheduleRepository{db: tx})
+	})
+}
+
+// Create adds a schedule with its first revision
+func (r *ScheduleRepository) Create(schedule *models.Schedule, meta models.RevisionMeta) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(schedule).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, schedule, meta)
+	})
 }
 
 func (r *ScheduleRepository) Get(id int64) (*models.Schedule, error) {
@@ -36,7 +52,9 @@ func (r *ScheduleRepository) GetAll() ([]models.Schedule, error) {
 	return schedules, nil
 }
 
-func (r *ScheduleRepository) Update(schedule *models.Schedule) error {
+// Update saves a schedule and its time slots, recording a revision if its
+// content changed
+func (r *ScheduleRepository) Update(schedule *models.Schedule, meta models.RevisionMeta) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		println("Starting update for schedule:", schedule.ID)
 		println("Number of time slots:", len(schedule.TimeSlots))
@@ -97,12 +115,81 @@ func (r *ScheduleRepository) Update(schedule *models.Schedule) error {
 		for _, slot := range schedule.TimeSlots {
 			println("Time slot triggerTime:", slot.TriggerTime)
 		}
-		return nil
+		return recordRevision(tx, schedule, meta)
 	})
 }
 
+// Delete deletes a schedule with its activations. Its revisions are kept,
+// so a deleted schedule can still be audited and restored; schedule IDs
+// are never reused.
 func (r *ScheduleRepository) Delete(id int64) error {
-	return r.db.Delete(&models.Schedule{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&models.ScheduleActivation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Schedule{}, id).Error
+	})
+}
+
+// GetRevisions retrieves the revisions of a schedule, newest first
+func (r *ScheduleRepository) GetRevisions(scheduleID int64) ([]models.ScheduleRevision, error) {
+	var revisions []models.ScheduleRevision
+	err := r.db.Where("schedule_id = ?", scheduleID).Order("number DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision retrieves a revision of a schedule by number
+func (r *ScheduleRepository) GetRevision(scheduleID int64, number int) (*models.ScheduleRevision, error) {
+	var revision models.ScheduleRevision
+	if err := r.db.Where("schedule_id = ? AND number = ?", scheduleID, number).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// RecordMissingRevisions gives schedules saved before revisions were kept
+// their first revision
+func (r *ScheduleRepository) RecordMissingRevisions() error {
+	var schedules []models.Schedule
+	err := r.db.Preload("TimeSlots").
+		Where("id NOT IN (?)", r.db.Model(&models.ScheduleRevision{}).Select("schedule_id")).
+		Find(&schedules).Error
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		if err := recordRevision(r.db, &schedules[i], models.RevisionMeta{Note: "Initial version"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordRevision stores the content of a schedule as its next revision,
+// unless it is the same as the latest one
+func recordRevision(tx *gorm.DB, schedule *models.Schedule, meta models.RevisionMeta) error {
+	revision, err := models.NewScheduleRevision(schedule)
+	if err != nil {
+		return err
+	}
+
+	var latest models.ScheduleRevision
+	err = tx.Where("schedule_id = ?", schedule.ID).Order("number DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		if latest.SameContent(revision) {
+			return nil
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	default:
+		return err
+	}
+
+	revision.Number = latest.Number + 1
+	revision.AuthorID = meta.AuthorID
+	revision.AuthorName = meta.AuthorName
+	revision.Note = meta.Note
+	return tx.Create(revision).Error
 }
 
 func (r *ScheduleRepository) FindByID(id int64) (*models.Schedule, error) {
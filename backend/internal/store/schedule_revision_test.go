@@ -0,0 +1,84 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bell_scheduler/internal/models"
+)
+
+func TestScheduleRepository_Revisions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Schedule{}, &models.TimeSlot{}, &models.ScheduleRevision{}, &models.ScheduleActivation{}))
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	repo := NewScheduleRepository(db)
+	schedule := &models.Schedule{Name: "Normal day", TimeSlots: []models.TimeSlot{{TriggerTime: "08:30"}}}
+	require.NoError(t, repo.Create(schedule, models.RevisionMeta{AuthorID: 1, AuthorName: "head"}))
+
+	schedule.TimeSlots = append(schedule.TimeSlots, models.TimeSlot{TriggerTime: "15:00"})
+	require.NoError(t, repo.Update(schedule, models.RevisionMeta{AuthorID: 2, AuthorName: "deputy"}))
+
+	// Saving without changing the content, as marking a schedule temporary
+	// does, adds no revision
+	schedule.IsTemporary = true
+	require.NoError(t, repo.Update(schedule, models.RevisionMeta{}))
+
+	revisions, err := repo.GetRevisions(schedule.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.Equal(t, "deputy", revisions[0].AuthorName)
+	slots, err := revisions[1].Slots()
+	require.NoError(t, err)
+	assert.Len(t, slots, 1)
+
+	// The revisions outlive the schedule, and a new schedule does not take
+	// over its ID
+	require.NoError(t, repo.Delete(schedule.ID))
+	revisions, err = repo.GetRevisions(schedule.ID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	next := &models.Schedule{Name: "Exam day"}
+	require.NoError(t, repo.Create(next, models.RevisionMeta{}))
+	assert.NotEqual(t, schedule.ID, next.ID)
+}
+
+func TestScheduleRepository_RecordMissingRevisions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Schedule{}, &models.TimeSlot{}, &models.ScheduleRevision{}, &models.ScheduleActivation{}))
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	// A schedule saved before revisions were kept
+	legacy := &models.Schedule{Name: "Old", TimeSlots: []models.TimeSlot{{TriggerTime: "09:00"}}}
+	require.NoError(t, db.Create(legacy).Error)
+
+	repo := NewScheduleRepository(db)
+	require.NoError(t, repo.RecordMissingRevisions())
+	require.NoError(t, repo.RecordMissingRevisions())
+
+	revisions, err := repo.GetRevisions(legacy.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "Initial version", revisions[0].Note)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"time"
+
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository handles database operations for sessions
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository instance
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+// Create adds a new session
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// Get retrieves a session by ID
+func (r *SessionRepository) Get(id int64) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByTokenHash retrieves the session whose current or previous refresh
+// token has the hash
+func (r *SessionRepository) GetByTokenHash(hash string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("token_hash = ? OR previous_hash = ?", hash, hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate replaces the refresh token of a session, unless another request
+// rotated it first, and reports whether this call did
+func (r *SessionRepository) Rotate(session *models.Session, newHash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, session.TokenHash).
+		Updates(map[string]interface{}{
+			"previous_hash": session.TokenHash,
+			"token_hash":    newHash,
+			"last_used_at":  at,
+		})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false, result.Error
+	}
+	session.PreviousHash = session.TokenHash
+	session.TokenHash = newHash
+	session.LastUsedAt = at
+	return true, nil
+}
+
+// GetActiveForUser retrieves the unrevoked, unexpired sessions of a user,
+// most recently used first
+func (r *SessionRepository) GetActiveForUser(userID int64, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Revoke ends a session
+func (r *SessionRepository) Revoke(id int64, at time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RevokeAllForUser ends every session of a user except the one with ID
+// keep, and returns how many were ended. Pass 0 to end them all.
+func (r *SessionRepository) RevokeAllForUser(userID, keep int64, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keep).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteForUser removes all sessions of a user
+func (r *SessionRepository) DeleteForUser(userID int64) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
+
+// DeleteExpired removes sessions that expired or were revoked before the cutoff
+func (r *SessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ? OR revoked_at < ?", before, before).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -28,6 +28,19 @@ func (r *SettingsRepository) Update(settings *models.Settings) error {
 	return r.db.Save(settings).Error
 }
 
+// RequiresTwoFactor reports whether users of the role must use two-factor
+// authentication
+func (r *SettingsRepository) RequiresTwoFactor(role string) (bool, error) {
+	if role != models.RoleAdmin {
+		return false, nil
+	}
+	settings, err := r.Get()
+	if err != nil {
+		return false, err
+	}
+	return settings.RequireAdminTwoFactor, nil
+}
+
 func (r *SettingsRepository) UpdateBellRingDuration(duration int) error {
 	return r.db.Model(&models.Settings{}).Update("bell_ring_duration", duration).Error
 }
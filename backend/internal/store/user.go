@@ -170,6 +170,96 @@ func (r *GormUserRepository) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
+// GetPending retrieves the self-registered users awaiting approval, oldest first
+func (r *GormUserRepository) GetPending() ([]models.User, error) {
+	var users []models.User
+	if err := r.db.Where("pending_approval = ?", true).Order("created_at ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *GormUserRepository) Count() (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountActiveAdmins returns the number of active, approved administrators
+func (r *GormUserRepository) CountActiveAdmins() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("role = ? AND is_active = ? AND pending_approval = ?", models.RoleAdmin, true, false).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateTwoFactor saves the two-factor state of a user, resetting the
+// last used code
+func (r *GormUserRepository) UpdateTwoFactor(userID int64, secret string, enabled bool, recoveryCodes string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_enabled":   enabled,
+		"totp_last_step": 0,
+		"recovery_codes": recoveryCodes,
+	}).Error
+}
+
+// ClaimTOTPStep records the time step of a code, unless that step or a
+// later one was already used, and reports whether this call recorded it
+func (r *GormUserRepository) ClaimTOTPStep(userID, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReplaceRecoveryCodes swaps the recovery codes of a user if they are still
+// the expected ones, and reports whether they were
+func (r *GormUserRepository) ReplaceRecoveryCodes(userID int64, expected, recoveryCodes string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND recovery_codes = ?", userID, expected).
+		Update("recovery_codes", recoveryCodes)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordFailedLogin counts a failed login of a user and returns the
+// number of consecutive failures
+func (r *GormUserRepository) RecordFailedLogin(userID int64) (int, error) {
+	if err := r.db.Model(&models.User{}).Where("id = ?", userID).
+		Update("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+		return 0, err
+	}
+	var user models.User
+	if err := r.db.Select("failed_logins").First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLogins, nil
+}
+
+// Lock locks a user out until the given time, starting a new count of
+// failed logins and counting the lockout
+func (r *GormUserRepository) Lock(userID int64, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"lockouts":      gorm.Expr("lockouts + 1"),
+		"locked_until":  until,
+	}).Error
+}
+
+// ResetFailedLogins clears the failed logins, lockouts and lock of a user
+func (r *GormUserRepository) ResetFailedLogins(userID int64) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"lockouts":      0,
+		"locked_until":  nil,
+	}).Error
+}
+
 // Delete deletes a user
 func (r *GormUserRepository) Delete(id int64) error {
 	if err := r.db.Delete(&models.User{}, id).Error; err != nil {
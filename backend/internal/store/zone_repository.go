@@ -0,0 +1,93 @@
+package store
+
+import (
+	"bell_scheduler/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ZoneRepository handles database operations for bell zones
+type ZoneRepository struct {
+	db *gorm.DB
+}
+
+// NewZoneRepository creates a new zone repository instance
+func NewZoneRepository(db *gorm.DB) *ZoneRepository {
+	return &ZoneRepository{
+		db: db,
+	}
+}
+
+// Create adds a new zone
+func (r *ZoneRepository) Create(zone *models.Zone) error {
+	return r.db.Create(zone).Error
+}
+
+// Get retrieves a zone by ID
+func (r *ZoneRepository) Get(id int64) (*models.Zone, error) {
+	var zone models.Zone
+	if err := r.db.First(&zone, id).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// GetAll retrieves all zones ordered by name
+func (r *ZoneRepository) GetAll() ([]models.Zone, error) {
+	var zones []models.Zone
+	if err := r.db.Order("name ASC").Find(&zones).Error; err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// GetDefault retrieves the default zone
+func (r *ZoneRepository) GetDefault() (*models.Zone, error) {
+	var zone models.Zone
+	if err := r.db.Where("is_default = ?", true).First(&zone).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// Update saves changes to an existing zone
+func (r *ZoneRepository) Update(zone *models.Zone) error {
+	return r.db.Save(zone).Error
+}
+
+// SetDefault makes the zone the default zone
+func (r *ZoneRepository) SetDefault(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Zone{}).Where("id != ?", id).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Zone{}).Where("id = ?", id).Update("is_default", true).Error
+	})
+}
+
+// CountUsage returns the number of time slots that ring the zone
+func (r *ZoneRepository) CountUsage(id int64) (int64, error) {
+	var slots []models.TimeSlot
+	if err := r.db.Select("id", "zones").Where("zones != '' AND zones != '[]'").Find(&slots).Error; err != nil {
+		return 0, err
+	}
+	var count int64
+	for i := range slots {
+		ids, err := slots[i].ZoneIDs()
+		if err != nil {
+			return 0, err
+		}
+		for _, zoneID := range ids {
+			if zoneID == id {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+// Delete removes a zone
+func (r *ZoneRepository) Delete(id int64) error {
+	return r.db.Delete(&models.Zone{}, id).Error
+}
@@ -0,0 +1,168 @@
+// Package ical reads and writes iCalendar (RFC 5545) documents. Times are
+// written in UTC and all-day events as dates, so no VTIMEZONE is needed.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	// maxLineLength is the longest content line in octets before folding
+	maxLineLength = 75
+)
+
+// Event is a VEVENT. All-day events use the dates of Start and End, End
+// being the day after the last day.
+type Event struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Summary      string
+	Description  string
+	Location     string
+	Categories   []string
+	LastModified time.Time
+	Status       string // TENTATIVE, CONFIRMED or CANCELLED
+	RRule        string
+	// RecurrenceID names the instance of a recurring event this event
+	// replaces, as a UTC date-time or a date. It is empty for other events.
+	RecurrenceID string
+}
+
+// Key identifies the event within its calendar. Instances of a recurring
+// event share the UID of the series and differ by their RECURRENCE-ID.
+func (e *Event) Key() string {
+	if e.RecurrenceID == "" {
+		return e.UID
+	}
+	return e.UID + ";RECURRENCE-ID=" + e.RecurrenceID
+}
+
+// Calendar is a VCALENDAR with its events
+type Calendar struct {
+	ProdID string
+	Name   string
+	// RefreshInterval suggests how often clients fetch the calendar again
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Marshal renders the calendar, stamping every event with the given time
+func (c *Calendar) Marshal(stamp time.Time) []byte {
+	var buf bytes.Buffer
+	w := &writer{buf: &buf}
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", c.ProdID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		duration := formatDuration(c.RefreshInterval)
+		w.line("REFRESH-INTERVAL;VALUE=DURATION", duration)
+		w.line("X-PUBLISHED-TTL", duration)
+	}
+
+	for _, event := range c.Events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", event.UID)
+		w.line("DTSTAMP", stamp.UTC().Format(dateTimeFormat))
+		if event.AllDay {
+			w.line("DTSTART;VALUE=DATE", event.Start.Format(dateFormat))
+			w.line("DTEND;VALUE=DATE", event.End.Format(dateFormat))
+		} else {
+			w.line("DTSTART", event.Start.UTC().Format(dateTimeFormat))
+			w.line("DTEND", event.End.UTC().Format(dateTimeFormat))
+		}
+		w.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			w.line("LOCATION", escape(event.Location))
+		}
+		if len(event.Categories) > 0 {
+			categories := make([]string, len(event.Categories))
+			for i, category := range event.Categories {
+				categories[i] = escape(category)
+			}
+			w.line("CATEGORIES", strings.Join(categories, ","))
+		}
+		if event.Status != "" {
+			w.line("STATUS", event.Status)
+		}
+		if event.RRule != "" {
+			w.line("RRULE", event.RRule)
+		}
+		if !event.LastModified.IsZero() {
+			w.line("LAST-MODIFIED", event.LastModified.UTC().Format(dateTimeFormat))
+		}
+		w.line("TRANSP", "TRANSPARENT")
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// writer writes content lines, folding them at 75 octets without splitting
+// UTF-8 characters
+type writer struct {
+	buf *bytes.Buffer
+}
+
+func (w *writer) line(name, value string) {
+	line := name + ":" + value
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for !startsRune(line[cut]) {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space that counts toward their length
+		limit = maxLineLength - 1
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+// startsRune reports whether the byte begins a UTF-8 character
+func startsRune(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// escape escapes a TEXT value
+func escape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// formatDuration writes a duration of whole minutes as PTnHnM
+func formatDuration(d time.Duration) string {
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+	switch {
+	case minutes == 0:
+		return fmt.Sprintf("PT%dH", hours)
+	case hours == 0:
+		return fmt.Sprintf("PT%dM", minutes)
+	}
+	return fmt.Sprintf("PT%dH%dM", hours, minutes)
+}
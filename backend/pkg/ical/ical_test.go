@@ -0,0 +1,164 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	start := time.Date(2026, 11, 3, 8, 30, 0, 0, time.FixedZone("CET", 3600))
+	calendar := &Calendar{
+		ProdID:          "-//Test//EN",
+		Name:            "Bells, main building",
+		RefreshInterval: time.Hour,
+		Events: []Event{
+			{UID: "bell-1@test", Start: start, End: start.Add(time.Minute), Summary: "Period 1; Maths",
+				Description: "Line one\nLine two", Categories: []string{"Bell", "A,B"}},
+			{UID: "day-1@test", AllDay: true, Summary: "Holiday",
+				Start: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	out := string(calendar.Marshal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "X-WR-CALNAME:Bells\\, main building\r\n")
+	assert.Contains(t, out, "REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n")
+	assert.Contains(t, out, "DTSTART:20261103T073000Z\r\nDTEND:20261103T073100Z\r\n")
+	assert.Contains(t, out, "SUMMARY:Period 1\\; Maths\r\n")
+	assert.Contains(t, out, "DESCRIPTION:Line one\\nLine two\r\n")
+	assert.Contains(t, out, "CATEGORIES:Bell,A\\,B\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20261224\r\nDTEND;VALUE=DATE:20261225\r\n")
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT"))
+}
+
+func TestMarshal_FoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("Glocke ä ", 30)
+	calendar := &Calendar{ProdID: "-//Test//EN", Events: []Event{{UID: "x", Summary: summary}}}
+	out := string(calendar.Marshal(time.Now()))
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+		assert.True(t, utf8.ValidString(line), "folding must not split characters")
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, "SUMMARY:"+summary+"\r\n")
+}
+
+func TestParse(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//District//EN",
+		"X-WR-CALNAME:District 2026\\, 27",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T030000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:winter@district",
+		"DTSTART;VALUE=DATE:20261221",
+		"DTEND;VALUE=DATE:20270102",
+		"SUMMARY:Winter break",
+		"CATEGORIES:Holiday,School\\, closed",
+		"CATEGORIES:Break",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Reminder",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:early@district",
+		"DTSTART;TZID=Europe/Berlin:20261120T120000",
+		"DURATION:PT3H",
+		"SUMMARY:Early release for parent-teacher confere",
+		" nces",
+		"DESCRIPTION:Pupils leave at 12:00\\nBuses run early",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:open@district",
+		"DTSTART;VALUE=DATE:20261009",
+		"SUMMARY:Open day",
+		"STATUS:CANCELLED",
+		"RRULE:FREQ=YEARLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:open@district",
+		"RECURRENCE-ID;VALUE=DATE:20271009",
+		"DTSTART;VALUE=DATE:20271008",
+		"SUMMARY:Open day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:early@district",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20271119T120000",
+		"DTSTART;TZID=Europe/Berlin:20271119T110000",
+		"SUMMARY:Early release",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	calendar, err := Parse([]byte(data), time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, "District 2026, 27", calendar.Name)
+	require.Len(t, calendar.Events, 5)
+
+	winter := calendar.Events[0]
+	assert.True(t, winter.AllDay)
+	assert.Equal(t, time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC), winter.Start)
+	assert.Equal(t, time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), winter.End)
+	assert.Equal(t, []string{"Holiday", "School, closed", "Break"}, winter.Categories)
+	assert.Empty(t, winter.Description, "alarm properties belong to the alarm")
+
+	early := calendar.Events[1]
+	assert.False(t, early.AllDay)
+	assert.Equal(t, time.Date(2026, 11, 20, 11, 0, 0, 0, time.UTC), early.Start.UTC())
+	assert.Equal(t, 3*time.Hour, early.End.Sub(early.Start))
+	assert.Equal(t, "Early release for parent-teacher conferences", early.Summary)
+	assert.Equal(t, "Pupils leave at 12:00\nBuses run early", early.Description)
+
+	open := calendar.Events[2]
+	assert.Equal(t, time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), open.End, "a date event lasts a day by default")
+	assert.Equal(t, "CANCELLED", open.Status)
+	assert.Equal(t, "FREQ=YEARLY", open.RRule)
+	assert.Equal(t, "open@district", open.Key())
+
+	// Changed instances of a recurring event are told apart by RECURRENCE-ID
+	assert.Equal(t, "open@district;RECURRENCE-ID=20271009", calendar.Events[3].Key())
+	assert.Equal(t, "20271119T110000Z", calendar.Events[4].RecurrenceID)
+	assert.Equal(t, "early@district;RECURRENCE-ID=20271119T110000Z", calendar.Events[4].Key())
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	start := time.Date(2026, 11, 3, 8, 30, 0, 0, time.UTC)
+	calendar := &Calendar{ProdID: "-//Test//EN", Name: "Bells", Events: []Event{
+		{UID: "a", Start: start, End: start.Add(time.Minute), Summary: strings.Repeat("Période; 1, ", 10),
+			Categories: []string{"Bell", "A,B"}},
+	}}
+
+	parsed, err := Parse(calendar.Marshal(start), time.UTC)
+	require.NoError(t, err)
+	require.Len(t, parsed.Events, 1)
+	assert.Equal(t, calendar.Events[0].Summary, parsed.Events[0].Summary)
+	assert.Equal(t, calendar.Events[0].Categories, parsed.Events[0].Categories)
+	assert.True(t, start.Equal(parsed.Events[0].Start))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":        "",
+		"not calendar": "BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"unterminated": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\n",
+		"no start":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"bad date":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nDTSTART:2026-11-03\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		_, err := Parse([]byte(data), time.UTC)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,256 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern matches a DURATION value such as P1D, PT1H30M or P2W
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Parse reads the events of an iCalendar document. Times with a TZID that
+// cannot be loaded, and floating times, are read in loc. Recurring events
+// are returned once with their RRULE, and changed instances of them as
+// events with a RecurrenceID. Components other than VEVENT are ignored.
+func Parse(data []byte, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty calendar")
+	}
+
+	calendar := &Calendar{}
+	var stack []string
+	var event *Event
+	hasEnd, duration := false, time.Duration(0)
+	for i, line := range lines {
+		name, params, value, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch name {
+		case "BEGIN":
+			component := strings.ToUpper(value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", i+1)
+			}
+			stack = append(stack, component)
+			if component == "VEVENT" && len(stack) == 2 {
+				event = &Event{}
+				hasEnd, duration = false, 0
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, value)
+			}
+			if event != nil && len(stack) == 2 {
+				if event.UID == "" {
+					return nil, fmt.Errorf("line %d: event without UID", i+1)
+				}
+				if event.Start.IsZero() {
+					return nil, fmt.Errorf("event %s has no DTSTART", event.UID)
+				}
+				if !hasEnd {
+					switch {
+					case duration != 0:
+						event.End = event.Start.Add(duration)
+					case event.AllDay:
+						event.End = event.Start.AddDate(0, 0, 1)
+					default:
+						event.End = event.Start
+					}
+				}
+				calendar.Events = append(calendar.Events, *event)
+				event = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if len(stack) == 1 {
+			switch name {
+			case "PRODID":
+				calendar.ProdID = value
+			case "X-WR-CALNAME":
+				calendar.Name = unescape(value)
+			}
+			continue
+		}
+		// Properties of alarms and other nested components are skipped
+		if event == nil || len(stack) != 2 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescape(value)
+		case "DESCRIPTION":
+			event.Description = unescape(value)
+		case "LOCATION":
+			event.Location = unescape(value)
+		case "STATUS":
+			event.Status = strings.ToUpper(value)
+		case "RRULE":
+			event.RRule = value
+		case "RECURRENCE-ID":
+			var id time.Time
+			var allDay bool
+			id, allDay, err = parseTime(value, params, loc)
+			if allDay {
+				event.RecurrenceID = id.Format(dateFormat)
+			} else {
+				event.RecurrenceID = id.UTC().Format(dateTimeFormat)
+			}
+		case "CATEGORIES":
+			for _, category := range splitList(value) {
+				if category = strings.TrimSpace(unescape(category)); category != "" {
+					event.Categories = append(event.Categories, category)
+				}
+			}
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(value, params, loc)
+		case "DTEND":
+			event.End, _, err = parseTime(value, params, loc)
+			hasEnd = true
+		case "DURATION":
+			duration, err = parseDuration(value)
+		case "LAST-MODIFIED":
+			event.LastModified, _, err = parseTime(value, params, loc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s: %w", i+1, name, err)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+	return calendar, nil
+}
+
+// unfold joins folded content lines
+func unfold(data []byte) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine splits a content line into its upper-case name, parameters and
+// value
+func splitLine(line string) (string, map[string]string, string, error) {
+	// The value starts at the first colon outside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("no value in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+// parseTime reads a DATE or DATE-TIME value, reporting whether it is a date
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, value)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration reads a DURATION value
+func parseDuration(value string) (time.Duration, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if match[i+2] != "" {
+			n, _ := strconv.Atoi(match[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if match[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// splitList splits a list value on commas that are not escaped
+func splitList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+// unescape reverses escape
+func unescape(value string) string {
+	return strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	).Replace(value)
+}
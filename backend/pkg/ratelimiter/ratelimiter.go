@@ -1,57 +1,162 @@
 package ratelimiter
 
 import (
-    "sync"
-    "time"
+	"fmt"
+	"sync"
+	"time"
 )
 
+// Store persists the attempts of each key, so limits survive a restart
+type Store interface {
+	// Load returns the recorded attempts of a key, or none if it is unknown
+	Load(key string) ([]time.Time, error)
+	// Save replaces the attempts of a key
+	Save(key string, attempts []time.Time) error
+	// Delete forgets a key
+	Delete(key string) error
+}
+
+// RateLimiter allows at most max attempts per key within a sliding window
 type RateLimiter struct {
-    mu       sync.RWMutex
-    attempts map[string][]time.Time
-    window   time.Duration
-    max      int
+	mu       sync.RWMutex
+	attempts map[string][]time.Time
+	window   time.Duration
+	max      int
+	store    Store
+	now      func() time.Time
+	pruned   time.Time // When idle keys were last forgotten
 }
 
+// NewRateLimiter creates a rate limiter that keeps attempts in memory
 func NewRateLimiter(window time.Duration, max int) *RateLimiter {
-    return &RateLimiter{
-        attempts: make(map[string][]time.Time),
-        window:   window,
-        max:      max,
-    }
+	return &RateLimiter{
+		attempts: make(map[string][]time.Time),
+		window:   window,
+		max:      max,
+		now:      time.Now,
+	}
+}
+
+// NewPersistentRateLimiter creates a rate limiter that also writes attempts
+// to the store. Keys are loaded from the store the first time they are seen.
+func NewPersistentRateLimiter(window time.Duration, max int, store Store) *RateLimiter {
+	rl := NewRateLimiter(window, max)
+	rl.store = store
+	return rl
+}
+
+// WithClock makes the rate limiter read the time from now, for tests
+func (rl *RateLimiter) WithClock(now func() time.Time) *RateLimiter {
+	rl.now = now
+	return rl
 }
 
+// IsAllowed records an attempt for the key if the limit allows it. When it
+// does not, it returns how long until the next attempt is allowed.
 func (rl *RateLimiter) IsAllowed(key string) (bool, time.Duration) {
-    rl.mu.Lock()
-    defer rl.mu.Unlock()
-
-    now := time.Now()
-    windowStart := now.Add(-rl.window)
-
-    // Clean up old attempts
-    attempts := rl.attempts[key]
-    valid := attempts[:0]
-    for _, t := range attempts {
-        if t.After(windowStart) {
-            valid = append(valid, t)
-        }
-    }
-    rl.attempts[key] = valid
-
-    // Check if rate limit is exceeded
-    if len(valid) >= rl.max {
-        // Calculate time until next allowed attempt
-        oldestValid := valid[0]
-        waitTime := rl.window - now.Sub(oldestValid)
-        return false, waitTime
-    }
-
-    // Add new attempt
-    rl.attempts[key] = append(rl.attempts[key], now)
-    return true, 0
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.prune()
+
+	if allowed, waitTime := rl.check(key); !allowed {
+		return false, waitTime
+	}
+	rl.add(key)
+	return true, 0
+}
+
+// Check reports whether an attempt for the key would be allowed, without
+// recording one
+func (rl *RateLimiter) Check(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.prune()
+	return rl.check(key)
 }
 
+// Add records an attempt for the key and reports whether the limit is now
+// reached. Use it with Check to count only failed attempts.
+func (rl *RateLimiter) Add(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.prune()
+	rl.valid(key)
+	return rl.add(key) >= rl.max
+}
+
+// Clear forgets the attempts of the key
 func (rl *RateLimiter) Clear(key string) {
-    rl.mu.Lock()
-    defer rl.mu.Unlock()
-    delete(rl.attempts, key)
-} 
\ No newline at end of file
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.attempts, key)
+	if rl.store != nil {
+		if err := rl.store.Delete(key); err != nil {
+			fmt.Printf("Failed to clear rate limit for %s: %v\n", key, err)
+		}
+	}
+}
+
+// prune forgets the keys without attempts in the window, at most once per
+// window, so keys that are not seen again do not pile up in memory. The
+// store keeps them until it is cleaned up itself.
+func (rl *RateLimiter) prune() {
+	now := rl.now()
+	if now.Sub(rl.pruned) < rl.window {
+		return
+	}
+	rl.pruned = now
+
+	windowStart := now.Add(-rl.window)
+	for key, attempts := range rl.attempts {
+		if len(attempts) == 0 || !attempts[len(attempts)-1].After(windowStart) {
+			delete(rl.attempts, key)
+		}
+	}
+}
+
+func (rl *RateLimiter) check(key string) (bool, time.Duration) {
+	valid := rl.valid(key)
+
+	// Check if rate limit is exceeded
+	if len(valid) >= rl.max {
+		// Calculate time until next allowed attempt
+		oldestValid := valid[len(valid)-rl.max]
+		waitTime := rl.window - rl.now().Sub(oldestValid)
+		return false, waitTime
+	}
+	return true, 0
+}
+
+// valid drops the attempts of the key that fell out of the window and
+// returns the rest
+func (rl *RateLimiter) valid(key string) []time.Time {
+	attempts, ok := rl.attempts[key]
+	if !ok && rl.store != nil {
+		loaded, err := rl.store.Load(key)
+		if err != nil {
+			fmt.Printf("Failed to load rate limit for %s: %v\n", key, err)
+		}
+		attempts = loaded
+	}
+
+	windowStart := rl.now().Add(-rl.window)
+	valid := attempts[:0]
+	for _, t := range attempts {
+		if t.After(windowStart) {
+			valid = append(valid, t)
+		}
+	}
+	rl.attempts[key] = valid
+	return valid
+}
+
+// add records an attempt and returns how many are in the window
+func (rl *RateLimiter) add(key string) int {
+	rl.attempts[key] = append(rl.attempts[key], rl.now())
+	if rl.store != nil {
+		if err := rl.store.Save(key, rl.attempts[key]); err != nil {
+			fmt.Printf("Failed to save rate limit for %s: %v\n", key, err)
+		}
+	}
+	return len(rl.attempts[key])
+}
@@ -0,0 +1,30 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_ForgetsIdleKeys(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(time.Minute, 2).WithClock(func() time.Time { return now })
+
+	for i := 0; i < 100; i++ {
+		rl.IsAllowed(fmt.Sprintf("10.0.0.%d", i))
+	}
+	allowed, _ := rl.IsAllowed("busy")
+	assert.True(t, allowed)
+	assert.Len(t, rl.attempts, 101)
+
+	// Keys whose attempts left the window are dropped on the next call
+	now = now.Add(50 * time.Second)
+	rl.IsAllowed("busy")
+	now = now.Add(20 * time.Second)
+	allowed, _ = rl.Check("busy")
+	assert.True(t, allowed)
+	assert.Len(t, rl.attempts, 1)
+	assert.Len(t, rl.attempts["busy"], 1)
+}
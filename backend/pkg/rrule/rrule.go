@@ -0,0 +1,368 @@
+// Package rrule evaluates the subset of RFC 5545 recurrence rules that
+// selects dates: FREQ of DAILY, WEEKLY, MONTHLY or YEARLY with INTERVAL,
+// COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST. Rules that pick times
+// of day or positions in a set (BYHOUR, BYSETPOS, ...) are rejected.
+package rrule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ of a rule
+type Frequency int
+
+// Supported frequencies
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+// maxCountScan bounds how many days are walked to find the end of COUNT
+const maxCountScan = 366 * 100
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month or year,
+// e.g. 1 for the first or -1 for the last, and 0 for every such weekday.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time // Last date included, zero for none
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE". An
+// "RRULE:" prefix is accepted.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		name, val := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		var err error
+		switch name {
+		case "FREQ":
+			hasFreq = true
+			rule.Freq, err = parseFreq(val)
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(val)
+		case "COUNT":
+			rule.Count, err = parsePositive(val)
+		case "UNTIL":
+			rule.Until, err = parseUntil(val)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(val, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(val, 12)
+			for _, month := range months {
+				if month < 0 {
+					return nil, fmt.Errorf("invalid BYMONTH %d", month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			day, ok := weekdays[val]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.WeekStart = day
+		default:
+			return nil, fmt.Errorf("%s is not supported", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be used")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	return rule, nil
+}
+
+// NeedsStart reports whether the rule depends on its start date beyond
+// bounding it: an interval, a count, or a day, month or weekday taken from
+// the start because the rule does not give one
+func (r *Rule) NeedsStart() bool {
+	if r.Interval > 1 || r.Count > 0 {
+		return true
+	}
+	switch r.Freq {
+	case Weekly:
+		return len(r.ByDay) == 0
+	case Monthly:
+		return len(r.ByDay) == 0 && len(r.ByMonthDay) == 0
+	case Yearly:
+		return len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0
+	}
+	return false
+}
+
+// Bound returns the rule for the start date with COUNT replaced by the
+// date of the last occurrence, so Occurs compares dates instead of counting
+// them. Callers that check many dates should bound the rule once. If the
+// last occurrence is more than maxCountScan days away the rule is left
+// open. A rule without COUNT is returned as it is.
+func (r *Rule) Bound(start time.Time) *Rule {
+	if r.Count == 0 {
+		return r
+	}
+	start = dateOf(start)
+	bound := *r
+	bound.Count = 0
+	n := 0
+	for d, i := start, 0; i < maxCountScan; d, i = d.AddDate(0, 0, 1), i+1 {
+		if r.matches(start, d) {
+			n++
+			if n == r.Count {
+				bound.Until = d
+				break
+			}
+		}
+	}
+	return &bound
+}
+
+// Occurs reports whether the rule selects the date. Only the dates of start
+// and day are used. A zero start places no lower bound, which is only
+// meaningful when NeedsStart is false.
+func (r *Rule) Occurs(start, day time.Time) bool {
+	if r.Count > 0 {
+		return r.Bound(start).Occurs(start, day)
+	}
+	start = dateOf(start)
+	day = dateOf(day)
+	if !start.IsZero() && day.Before(start) {
+		return false
+	}
+	if !r.Until.IsZero() && day.After(r.Until) {
+		return false
+	}
+	return r.matches(start, day)
+}
+
+// matches applies the frequency, interval and BY* parts to a date
+func (r *Rule) matches(start, day time.Time) bool {
+	if !r.inInterval(start, day) {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, day.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !matchesMonthDay(r.ByMonthDay, day) {
+		return false
+	}
+	if len(r.ByDay) > 0 {
+		return r.matchesByDay(day)
+	}
+
+	// Parts the rule leaves out are taken from the start
+	switch r.Freq {
+	case Weekly:
+		return day.Weekday() == start.Weekday()
+	case Monthly:
+		return len(r.ByMonthDay) > 0 || day.Day() == start.Day()
+	case Yearly:
+		if len(r.ByMonthDay) > 0 {
+			return true
+		}
+		if len(r.ByMonth) > 0 {
+			return day.Day() == start.Day()
+		}
+		return day.Month() == start.Month() && day.Day() == start.Day()
+	}
+	return true
+}
+
+// inInterval reports whether the date falls in a period selected by INTERVAL
+func (r *Rule) inInterval(start, day time.Time) bool {
+	if r.Interval <= 1 || start.IsZero() {
+		return true
+	}
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = daysBetween(start, day)
+	case Weekly:
+		periods = daysBetween(r.weekOf(start), r.weekOf(day)) / 7
+	case Monthly:
+		periods = (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+	case Yearly:
+		periods = day.Year() - start.Year()
+	}
+	return periods%r.Interval == 0
+}
+
+// weekOf returns the first day of the week containing the date
+func (r *Rule) weekOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) - int(r.WeekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func (r *Rule) matchesByDay(day time.Time) bool {
+	for _, byDay := range r.ByDay {
+		if byDay.Weekday != day.Weekday() {
+			continue
+		}
+		if byDay.N == 0 {
+			return true
+		}
+
+		// Ordinals count within the month, or within the year for a
+		// yearly rule without BYMONTH
+		var index, total int
+		if r.Freq == Yearly && len(r.ByMonth) == 0 {
+			index = day.YearDay()
+			total = time.Date(day.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		} else {
+			index = day.Day()
+			total = daysIn(day)
+		}
+		nth := (index-1)/7 + 1
+		nthFromEnd := -((total-index)/7 + 1)
+		if byDay.N == nth || byDay.N == nthFromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMonthDay(monthDays []int, day time.Time) bool {
+	for _, monthDay := range monthDays {
+		if monthDay == day.Day() || (monthDay < 0 && daysIn(day)+monthDay+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFreq(value string) (Frequency, error) {
+	switch value {
+	case "DAILY":
+		return Daily, nil
+	case "WEEKLY":
+		return Weekly, nil
+	case "MONTHLY":
+		return Monthly, nil
+	case "YEARLY":
+		return Yearly, nil
+	}
+	return 0, fmt.Errorf("%q is not supported", value)
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive number", value)
+	}
+	return n, nil
+}
+
+// parseUntil reads a DATE or DATE-TIME value and keeps its date
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return dateOf(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("%q is not a weekday", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%q is not a weekday", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("%q has an invalid ordinal", item)
+			}
+		}
+		days = append(days, WeekdayNum{Weekday: day, N: n})
+	}
+	return days, nil
+}
+
+// parseInts reads a list of numbers from 1 to max, or -max to -1
+func parseInts(value string, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, fmt.Errorf("%q is out of range", item)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// dateOf returns midnight UTC of the date, keeping the date as written
+func dateOf(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func daysIn(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,112 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// occurrences lists the dates in [from, to] selected by the rule
+func occurrences(t *testing.T, value, start, from, to string) []string {
+	rule, err := Parse(value)
+	require.NoError(t, err)
+	var result []string
+	for day := date(from); !day.After(date(to)); day = day.AddDate(0, 0, 1) {
+		if rule.Occurs(date(start), day) {
+			result = append(result, day.Format("2006-01-02"))
+		}
+	}
+	return result
+}
+
+func TestOccurs(t *testing.T) {
+	tests := []struct {
+		name, rule, start, from, to string
+		want                        []string
+	}{
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2026-03-02", "2026-03-01", "2026-03-31",
+			[]string{"2026-03-02", "2026-03-06", "2026-03-16", "2026-03-20", "2026-03-30"}},
+		{"weekday of the start", "FREQ=WEEKLY", "2026-03-04", "2026-03-01", "2026-03-20",
+			[]string{"2026-03-04", "2026-03-11", "2026-03-18"}},
+		{"first Monday", "FREQ=MONTHLY;BYDAY=1MO", "2026-01-01", "2026-03-01", "2026-04-30",
+			[]string{"2026-03-02", "2026-04-06"}},
+		{"last Friday", "FREQ=MONTHLY;BYDAY=-1FR", "2026-01-01", "2026-03-01", "2026-04-30",
+			[]string{"2026-03-27", "2026-04-24"}},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-01", "2026-02-01", "2026-03-31",
+			[]string{"2026-02-28", "2026-03-31"}},
+		{"every third day", "FREQ=DAILY;INTERVAL=3", "2026-03-01", "2026-03-01", "2026-03-10",
+			[]string{"2026-03-01", "2026-03-04", "2026-03-07", "2026-03-10"}},
+		{"until", "FREQ=DAILY;UNTIL=20260303T120000Z", "2026-03-01", "2026-03-01", "2026-03-10",
+			[]string{"2026-03-01", "2026-03-02", "2026-03-03"}},
+		{"count", "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=3", "2026-03-01", "2026-03-01", "2026-03-31",
+			[]string{"2026-03-03", "2026-03-05", "2026-03-10"}},
+		{"yearly in June", "RRULE:FREQ=YEARLY;BYMONTH=6;BYDAY=MO", "2026-01-01", "2026-05-30", "2026-06-16",
+			[]string{"2026-06-01", "2026-06-08", "2026-06-15"}},
+		{"before the start", "FREQ=DAILY", "2026-03-05", "2026-03-01", "2026-03-06",
+			[]string{"2026-03-05", "2026-03-06"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, occurrences(t, tt.rule, tt.start, tt.from, tt.to))
+		})
+	}
+}
+
+func TestBound(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;COUNT=3")
+	require.NoError(t, err)
+	bound := rule.Bound(date("2026-03-02"))
+	assert.Zero(t, bound.Count)
+	assert.Equal(t, date("2026-03-30"), bound.Until)
+	assert.Equal(t, 3, rule.Count, "the parsed rule is left as it is")
+
+	// A count that ends beyond the scanned days leaves the rule open
+	rule, err = Parse("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=30")
+	require.NoError(t, err)
+	assert.True(t, rule.Bound(date("2026-01-01")).Until.IsZero())
+
+	rule, err = Parse("FREQ=DAILY")
+	require.NoError(t, err)
+	assert.Same(t, rule, rule.Bound(date("2026-03-02")))
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;BYHOUR=9",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260301",
+		"FREQ=DAILY;INTERVAL=0",
+	} {
+		_, err := Parse(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestNeedsStart(t *testing.T) {
+	for value, want := range map[string]bool{
+		"FREQ=WEEKLY;BYDAY=MO":            false,
+		"FREQ=MONTHLY;BYDAY=1MO":          false,
+		"FREQ=WEEKLY":                     true,
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO": true,
+		"FREQ=DAILY;COUNT=5":              true,
+	} {
+		rule, err := Parse(value)
+		require.NoError(t, err)
+		assert.Equal(t, want, rule.NeedsStart(), value)
+	}
+}
@@ -1,6 +1,8 @@
 import Vue from 'vue'
 import axios from 'axios'
 import store from '../store'
+import { retryAfterRefresh } from '../utils/tokenRefresh'
+import { handleAccountRequirement } from '../utils/accountRequirements'
 
 // Create axios instance with base URL
 const instance = axios.create({
@@ -30,8 +32,19 @@ instance.interceptors.response.use(
   response => response,
   error => {
     if (error.response && error.response.status === 401) {
+      // Access tokens are short-lived; refresh once before giving up
+      const retry = retryAfterRefresh(instance, error)
+      if (retry) {
+        return retry.catch(() => {
+          store.dispatch('auth/logout')
+          return Promise.reject(error)
+        })
+      }
       store.dispatch('auth/logout')
     }
+    if (error.response && error.response.status === 403) {
+      handleAccountRequirement(error)
+    }
     return Promise.reject(error)
   }
 )
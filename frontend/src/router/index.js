@@ -13,6 +13,12 @@ const routes = [
     component: () => import('../views/Login.vue'),
     meta: { guest: true }
   },
+  {
+    path: '/setup',
+    name: 'Setup',
+    component: () => import('../views/Setup.vue'),
+    meta: { guest: true }
+  },
   {
     path: '/register',
     name: 'Register',
@@ -37,6 +43,12 @@ const routes = [
     component: () => import('../components/auth/ForcePasswordChange.vue'),
     meta: { requiresAuth: true }
   },
+  {
+    path: '/two-factor-setup',
+    name: 'TwoFactorSetup',
+    component: () => import('../views/TwoFactorSetup.vue'),
+    meta: { requiresAuth: true }
+  },
   {
     path: '/',
     name: 'Dashboard',
@@ -49,11 +61,17 @@ const routes = [
     component: () => import('../views/Schedules.vue'),
     meta: { requiresAuth: true }
   },
+  {
+    path: '/activations',
+    name: 'Activations',
+    component: () => import('../views/Activations.vue'),
+    meta: { requiresAuth: true }
+  },
   {
     path: '/users',
     name: 'Users',
     component: () => import('../views/Users.vue'),
-    meta: { requiresAuth: true }
+    meta: { requiresAuth: true, permission: 'users:manage' }
   },
   {
     path: '/settings',
@@ -71,7 +89,19 @@ const routes = [
     path: '/logs',
     name: 'Logs',
     component: Logs,
-    meta: { requiresAuth: true }
+    meta: { requiresAuth: true, permission: 'logs:read' }
+  },
+  {
+    path: '/audit',
+    name: 'Audit',
+    component: () => import('../views/Audit.vue'),
+    meta: { requiresAuth: true, permission: 'audit:read' }
+  },
+  {
+    path: '/backups',
+    name: 'Backups',
+    component: () => import('../views/Backups.vue'),
+    meta: { requiresAuth: true, permission: 'backups:manage' }
   }
 ]
 
@@ -105,6 +135,13 @@ router.beforeEach((to, from, next) => {
     return
   }
 
+  // If the user's role lacks the permission the route needs
+  const permission = to.matched.map(record => record.meta.permission).find(p => p)
+  if (isAuthenticated && permission && !store.getters['auth/can'](permission)) {
+    next('/')
+    return
+  }
+
   next()
 })
 
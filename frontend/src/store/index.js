@@ -3,6 +3,7 @@ import Vuex from 'vuex'
 import auth from './modules/auth'
 import schedules from './modules/schedules'
 import settings from './modules/settings'
+import status from './modules/status'
 import users from './modules/users'
 
 Vue.use(Vuex)
@@ -12,6 +13,7 @@ export default new Vuex.Store({
     auth,
     schedules,
     settings,
+    status,
     users
   }
 }) 
\ No newline at end of file
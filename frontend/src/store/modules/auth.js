@@ -1,8 +1,14 @@
 import axios from '@/utils/axios'
 
+// A refresh in flight, shared by every request that failed with 401 so the
+// refresh token is only used once
+let refreshing = null
+
 const state = {
   token: localStorage.getItem('token') || null,
+  refreshToken: localStorage.getItem('refreshToken') || null,
   user: JSON.parse(localStorage.getItem('user')) || null,
+  permissions: JSON.parse(localStorage.getItem('permissions')) || [],
   loading: false,
   error: null
 }
@@ -16,6 +22,14 @@ const mutations = {
       localStorage.removeItem('token')
     }
   },
+  SET_REFRESH_TOKEN(state, refreshToken) {
+    state.refreshToken = refreshToken
+    if (refreshToken) {
+      localStorage.setItem('refreshToken', refreshToken)
+    } else {
+      localStorage.removeItem('refreshToken')
+    }
+  },
   SET_USER(state, user) {
     state.user = user
     if (user) {
@@ -24,6 +38,14 @@ const mutations = {
       localStorage.removeItem('user')
     }
   },
+  SET_PERMISSIONS(state, permissions) {
+    state.permissions = permissions || []
+    if (permissions) {
+      localStorage.setItem('permissions', JSON.stringify(permissions))
+    } else {
+      localStorage.removeItem('permissions')
+    }
+  },
   SET_LOADING(state, loading) {
     state.loading = loading
   },
@@ -38,8 +60,31 @@ const actions = {
     commit('SET_ERROR', null)
     try {
       const response = await axios.post('/auth/login', credentials)
+      // With two-factor authentication the caller continues with loginTwoFactor
+      if (!response.data.twoFactorRequired) {
+        commit('SET_TOKEN', response.data.token)
+        commit('SET_REFRESH_TOKEN', response.data.refreshToken)
+        commit('SET_USER', response.data.user)
+        commit('SET_PERMISSIONS', response.data.permissions)
+      }
+      return response.data
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Login failed')
+      throw error
+    } finally {
+      commit('SET_LOADING', false)
+    }
+  },
+
+  async loginTwoFactor({ commit }, { challengeToken, code }) {
+    commit('SET_LOADING', true)
+    commit('SET_ERROR', null)
+    try {
+      const response = await axios.post('/auth/login/2fa', { challengeToken, code })
       commit('SET_TOKEN', response.data.token)
+      commit('SET_REFRESH_TOKEN', response.data.refreshToken)
       commit('SET_USER', response.data.user)
+      commit('SET_PERMISSIONS', response.data.permissions)
       return response.data
     } catch (error) {
       commit('SET_ERROR', error.response?.data?.error || 'Login failed')
@@ -91,9 +136,76 @@ const actions = {
     }
   },
 
-  logout({ commit }) {
+  refresh({ commit, state }) {
+    if (!refreshing) {
+      refreshing = axios.post('/auth/refresh', { refreshToken: state.refreshToken })
+        .then(response => {
+          commit('SET_TOKEN', response.data.token)
+          commit('SET_REFRESH_TOKEN', response.data.refreshToken)
+          commit('SET_USER', response.data.user)
+          commit('SET_PERMISSIONS', response.data.permissions)
+          return response.data
+        })
+        .finally(() => {
+          refreshing = null
+        })
+    }
+    return refreshing
+  },
+
+  async logout({ commit, state }) {
+    const refreshToken = state.refreshToken
     commit('SET_TOKEN', null)
+    commit('SET_REFRESH_TOKEN', null)
     commit('SET_USER', null)
+    commit('SET_PERMISSIONS', null)
+    if (refreshToken) {
+      // End the session on the server; signing out locally is enough if it fails
+      try {
+        await axios.post('/auth/logout', { refreshToken })
+      } catch (error) {
+        // ignore
+      }
+    }
+  },
+
+  async fetchSessions({ commit }) {
+    try {
+      const response = await axios.get('/auth/sessions')
+      return response.data
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to fetch sessions')
+      throw error
+    }
+  },
+
+  async revokeSession({ commit }, id) {
+    try {
+      await axios.delete(`/auth/sessions/${id}`)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to sign out device')
+      throw error
+    }
+  },
+
+  async revokeAllSessions({ commit, dispatch }) {
+    try {
+      await axios.post('/auth/sessions/revoke-all')
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to sign out devices')
+      throw error
+    }
+    await dispatch('logout')
+  },
+
+  // Permissions of a role can change while the user is logged in
+  async fetchPermissions({ commit }) {
+    try {
+      const response = await axios.get('/auth/permissions')
+      commit('SET_PERMISSIONS', response.data.permissions)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to fetch permissions')
+    }
   },
 
   async checkAuth({ commit, state }) {
@@ -135,7 +247,10 @@ const getters = {
   loading: state => state.loading,
   error: state => state.error,
   isAdmin: state => state.user?.role === 'admin',
+  permissions: state => state.permissions,
+  can: state => permission => state.permissions.includes(permission),
   token: state => state.token,
+  refreshToken: state => state.refreshToken,
   forcePasswordChange: state => state.user?.ForcePasswordChange || false
 }
 
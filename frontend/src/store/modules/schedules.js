@@ -3,6 +3,7 @@ import axios from '@/utils/axios'
 const state = {
   schedules: [],
   currentSchedule: null,
+  upcoming: [],
   loading: false,
   error: null
 }
@@ -14,6 +15,9 @@ const mutations = {
   SET_CURRENT_SCHEDULE(state, schedule) {
     state.currentSchedule = schedule
   },
+  SET_UPCOMING(state, bells) {
+    state.upcoming = bells
+  },
   SET_LOADING(state, loading) {
     state.loading = loading
   },
@@ -153,6 +157,45 @@ const actions = {
     }
   },
 
+  async fetchRevisions(_, scheduleId) {
+    const response = await axios.get(`/schedules/${scheduleId}/revisions`)
+    return response.data
+  },
+
+  async restoreRevision({ commit }, { scheduleId, number }) {
+    commit('SET_ERROR', null)
+    try {
+      const response = await axios.post(`/schedules/${scheduleId}/revisions/${number}/restore`)
+      commit('UPDATE_SCHEDULE', response.data)
+      return response.data
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to restore revision')
+      throw error
+    }
+  },
+
+  async exportSchedules(_, { format, ids }) {
+    const response = await axios.get('/schedules/export', {
+      params: { format, ids: ids && ids.length ? ids.join(',') : undefined },
+      responseType: 'blob'
+    })
+    return response.data
+  },
+
+  async importSchedules(_, request) {
+    const response = await axios.post('/schedules/import', request)
+    return response.data
+  },
+
+  async fetchUpcoming({ commit }, hours = 24) {
+    try {
+      const response = await axios.get('/schedule/upcoming', { params: { hours } })
+      commit('SET_UPCOMING', response.data.bells)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to fetch upcoming bells')
+    }
+  },
+
   async triggerBell({ commit }) {
     commit('SET_LOADING', true)
     commit('SET_ERROR', null)
@@ -184,6 +227,8 @@ const getters = {
   currentSchedule: state => state.currentSchedule,
   defaultSchedule: state => state.schedules.find(s => s.isDefault),
   activeSchedule: state => state.schedules.find(s => s.isActive),
+  upcoming: state => state.upcoming,
+  nextBell: state => state.upcoming[0] || null,
   loading: state => state.loading,
   error: state => state.error
 }
@@ -45,7 +45,9 @@ const actions = {
       const response = await axios.put('/settings', {
         ringDuration: settings.ringDuration,
         timezone: settings.timezone,
-        gpioPin: settings.gpioPin
+        gpioPin: settings.gpioPin,
+        registrationPolicy: settings.registrationPolicy,
+        requireAdminTwoFactor: settings.requireAdminTwoFactor
       })
       commit('SET_SETTINGS', response.data)
       return response.data
@@ -0,0 +1,91 @@
+// Live bell and schedule status pushed by the backend over /api/events
+
+const baseURL = process.env.VUE_APP_API_URL || 'http://localhost:8080/api'
+
+// The EventSource is kept outside the state so Vue does not observe it
+let source = null
+
+const state = {
+  connected: false,
+  activeZones: [],
+  emergency: ''
+}
+
+const mutations = {
+  SET_CONNECTED(state, connected) {
+    state.connected = connected
+  },
+  SET_STATUS(state, status) {
+    state.activeZones = status.bellActive ? ['all'] : []
+    state.emergency = status.emergency || ''
+  },
+  SET_ZONE_ACTIVE(state, { zoneId, active }) {
+    // A snapshot only says whether any zone rings; forget it once zones report
+    const zones = state.activeZones.filter(id => id !== zoneId && id !== 'all')
+    state.activeZones = active ? [...zones, zoneId] : zones
+  },
+  SET_EMERGENCY(state, mode) {
+    state.emergency = mode
+  }
+}
+
+const actions = {
+  connect({ commit, dispatch, rootGetters }) {
+    const token = rootGetters['auth/token']
+    if (source || !token || typeof EventSource === 'undefined') return
+
+    source = new EventSource(`${baseURL}/events?token=${encodeURIComponent(token)}`)
+    source.onopen = () => commit('SET_CONNECTED', true)
+    // EventSource reconnects on its own after a network error, but gives up
+    // when the server refuses it, usually because the access token in the
+    // URL has expired. Reconnect with a refreshed token after a pause.
+    source.onerror = () => {
+      commit('SET_CONNECTED', false)
+      if (source.readyState !== EventSource.CLOSED) return
+      source = null
+      setTimeout(() => {
+        dispatch('auth/refresh', null, { root: true })
+          .then(() => dispatch('connect'))
+          .catch(() => {})
+      }, 5000)
+    }
+
+    const on = (type, handler) => {
+      source.addEventListener(type, e => handler(JSON.parse(e.data)))
+    }
+    on('status', status => {
+      commit('SET_STATUS', status)
+      // Catch up on anything missed while disconnected
+      dispatch('schedules/fetchSchedules', null, { root: true })
+    })
+    on('bell-started', event => commit('SET_ZONE_ACTIVE', { zoneId: event.data.zoneId, active: true }))
+    on('bell-stopped', event => commit('SET_ZONE_ACTIVE', { zoneId: event.data.zoneId, active: false }))
+    on('schedule-activated', () => dispatch('schedules/fetchSchedules', null, { root: true }))
+    on('temporary-reset', () => dispatch('schedules/fetchSchedules', null, { root: true }))
+    on('settings-changed', event => commit('settings/SET_SETTINGS', event.data, { root: true }))
+    on('emergency-started', event => commit('SET_EMERGENCY', event.data.mode))
+    on('emergency-stopped', () => commit('SET_EMERGENCY', ''))
+  },
+
+  disconnect({ commit }) {
+    if (source) {
+      source.close()
+      source = null
+    }
+    commit('SET_CONNECTED', false)
+  }
+}
+
+const getters = {
+  connected: state => state.connected,
+  bellActive: state => state.activeZones.length > 0,
+  emergency: state => state.emergency
+}
+
+export default {
+  namespaced: true,
+  state,
+  mutations,
+  actions,
+  getters
+}
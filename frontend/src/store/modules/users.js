@@ -2,6 +2,8 @@ import axios from '@/utils/axios'
 
 const state = {
   users: [],
+  roles: [],
+  pending: [],
   loading: false,
   error: null,
   pagination: {
@@ -23,6 +25,12 @@ const mutations = {
     state.users = users
     state.pagination.totalItems = total
   },
+  SET_ROLES(state, roles) {
+    state.roles = roles
+  },
+  SET_PENDING(state, users) {
+    state.pending = users
+  },
   SET_LOADING(state, loading) {
     state.loading = loading
   },
@@ -43,6 +51,15 @@ const mutations = {
 }
 
 const actions = {
+  async fetchRoles({ commit }) {
+    try {
+      const response = await axios.get('/roles')
+      commit('SET_ROLES', response.data)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to fetch roles')
+    }
+  },
+
   async fetchUsers({ commit, state }) {
     commit('SET_LOADING', true)
     commit('SET_ERROR', null)
@@ -114,6 +131,79 @@ const actions = {
     }
   },
 
+  async fetchPending({ commit }) {
+    try {
+      const response = await axios.get('/users/pending')
+      commit('SET_PENDING', response.data)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to fetch pending users')
+    }
+  },
+
+  async approveUser({ commit, dispatch }, { id, role }) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post(`/users/${id}/approve`, role ? { role } : undefined)
+      await Promise.all([dispatch('fetchPending'), dispatch('fetchUsers')])
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to approve user')
+      throw error
+    }
+  },
+
+  async rejectUser({ commit, dispatch }, id) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post(`/users/${id}/reject`)
+      await Promise.all([dispatch('fetchPending'), dispatch('fetchUsers')])
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to reject user')
+      throw error
+    }
+  },
+
+  async revokeSessions({ commit }, id) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post(`/users/${id}/revoke-sessions`)
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to sign out user')
+      throw error
+    }
+  },
+
+  async resetTwoFactor({ commit, dispatch }, id) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post(`/users/${id}/reset-2fa`)
+      await dispatch('fetchUsers')
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to reset two-factor authentication')
+      throw error
+    }
+  },
+
+  async unlockUser({ commit, dispatch }, id) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post(`/users/${id}/unlock`)
+      await dispatch('fetchUsers')
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to unlock user')
+      throw error
+    }
+  },
+
+  async inviteUser({ commit }, { email, role }) {
+    commit('SET_ERROR', null)
+    try {
+      await axios.post('/invitations', { email, role })
+    } catch (error) {
+      commit('SET_ERROR', error.response?.data?.error || 'Failed to send invitation')
+      throw error
+    }
+  },
+
   updatePagination({ commit }, { page, itemsPerPage }) {
     commit('SET_PAGINATION', { page, itemsPerPage })
     // Removed automatic fetchUsers call to prevent duplicate API calls
@@ -132,6 +222,8 @@ const actions = {
 
 const getters = {
   users: state => state.users,
+  roles: state => state.roles,
+  pending: state => state.pending,
   loading: state => state.loading,
   error: state => state.error,
   pagination: state => state.pagination,
@@ -0,0 +1,22 @@
+import store from '@/store'
+import router from '@/router'
+
+// handleAccountRequirement sends the user to the page that fixes a
+// requirement the server refused a request for: a password that must be
+// changed, or two-factor authentication their role requires
+export function handleAccountRequirement(error) {
+  const code = error.response?.data?.code
+  if (code === 'password_change_required') {
+    const user = store.getters['auth/user']
+    if (user && !user.ForcePasswordChange) {
+      store.commit('auth/SET_USER', { ...user, ForcePasswordChange: true })
+    }
+    if (router.currentRoute.name !== 'ForcePasswordChange') {
+      router.push({ name: 'ForcePasswordChange' })
+    }
+  } else if (code === 'two_factor_setup_required') {
+    if (router.currentRoute.name !== 'TwoFactorSetup') {
+      router.push({ name: 'TwoFactorSetup' })
+    }
+  }
+}
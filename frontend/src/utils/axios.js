@@ -1,6 +1,8 @@
 import axios from 'axios'
 import store from '@/store'
 import router from '@/router'
+import { retryAfterRefresh } from '@/utils/tokenRefresh'
+import { handleAccountRequirement } from '@/utils/accountRequirements'
 
 const instance = axios.create({
   baseURL: process.env.VUE_APP_API_URL || 'http://localhost:8080/api',
@@ -28,6 +30,19 @@ instance.interceptors.request.use(
 instance.interceptors.response.use(
   response => response,
   error => {
+    if (error.response && error.response.status === 401) {
+      // Access tokens are short-lived; refresh once before giving up
+      const retry = retryAfterRefresh(instance, error)
+      if (retry) {
+        return retry.catch(() => {
+          store.dispatch('auth/logout')
+          if (router.currentRoute.name !== 'Login') {
+            router.push({ name: 'Login' })
+          }
+          return Promise.reject(error)
+        })
+      }
+    }
     if (error.response) {
       switch (error.response.status) {
         case 401:
@@ -38,7 +53,7 @@ instance.interceptors.response.use(
           }
           break
         case 403:
-          // Handle forbidden access
+          handleAccountRequirement(error)
           break
         case 404:
           // Handle not found
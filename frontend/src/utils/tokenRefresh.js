@@ -0,0 +1,16 @@
+import store from '@/store'
+
+// Requests that must not trigger a refresh themselves
+const authPaths = ['/auth/login', '/auth/login/2fa', '/auth/refresh', '/auth/logout']
+
+// retryAfterRefresh replays a request that failed with 401 once the access
+// token has been refreshed. It returns null when the request should not be
+// retried, so the caller falls back to logging out.
+export function retryAfterRefresh(instance, error) {
+  const config = error.config
+  if (!config || config._retried || !store.getters['auth/refreshToken']) return null
+  if (authPaths.some(path => (config.url || '').endsWith(path))) return null
+
+  config._retried = true
+  return store.dispatch('auth/refresh').then(() => instance(config))
+}